@@ -0,0 +1,381 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/zclconf/go-cty-diff/ctydiff"
+	"github.com/zclconf/go-cty-diff/ctydiff/render/hcl"
+	"github.com/zclconf/go-cty-diff/ctydiff/render/plan"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// cli holds the input and output streams for a command.
+type cli struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+}
+
+// errorf prints an error message and returns exitTrouble.
+func (c *cli) errorf(format string, args ...interface{}) int {
+	fmt.Fprintf(c.stderr, "ctydiff: "+format+"\n", args...)
+	return exitTrouble
+}
+
+// flags returns a new flag set for the given command, along with a pointer
+// to the value of the -type flag that all commands accept.
+func (c *cli) flags(name, args string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "usage: ctydiff %s [flags] %s\n\nflags:\n", name, args)
+		fs.PrintDefaults()
+	}
+	typeExpr := fs.String("type", "", "cty JSON type expression for the documents; inferred if not set")
+	return fs, typeExpr
+}
+
+// parse parses the given arguments using the given flag set and checks that
+// the expected number of file names remain. If the command should not
+// continue, parse returns no file names and the exit code, which is exitOK
+// if help was requested.
+func (c *cli) parse(fs *flag.FlagSet, args []string, n int) ([]string, int) {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil, exitOK
+		}
+		return nil, exitTrouble
+	}
+	if fs.NArg() != n {
+		fs.Usage()
+		return nil, exitTrouble
+	}
+	return fs.Args(), exitOK
+}
+
+func runDiff(c *cli, args []string) int {
+	fs, typeExpr := c.flags("diff", "OLD NEW")
+	format := fs.String("format", "plan", `output format: "plan", "hcl", "json", "jsonpatch" or "mergepatch"`)
+	color := fs.Bool("color", false, `use terminal colors for the "plan" format`)
+	width := fs.Int("width", 0, `maximum line width for the "plan" format`)
+	var ignore pathsFlag
+	fs.Var(&ignore, "ignore", "`path` of a value not to compare, such as .tags[\"env\"]; may be repeated")
+	files, code := c.parse(fs, args, 2)
+	if files == nil {
+		return code
+	}
+	switch *format {
+	case "plan", "hcl", "json", "jsonpatch", "mergepatch":
+	default:
+		return c.errorf("unknown format %q", *format)
+	}
+
+	vals, err := c.readValues(*typeExpr, files...)
+	if err != nil {
+		return c.errorf("%s", err)
+	}
+	old, new := vals[0], vals[1]
+	diff := ctydiff.NewDiffWithOptions(old, new, ctydiff.DiffOptions{Ignore: ignore.patterns()})
+	if diff.Stats().Changes() == 0 {
+		return exitOK
+	}
+
+	var out []byte
+	switch *format {
+	case "plan":
+		out = []byte(plan.Render(diff, old, plan.Options{Color: *color, Width: *width}))
+	case "hcl":
+		out = []byte(hcl.Render(diff, old))
+	case "json":
+		out, err = marshalDiff(diff)
+	case "jsonpatch":
+		out, err = diff.ToJSONPatch()
+	case "mergepatch":
+		out, err = diff.ToMergePatch(old)
+	}
+	if err != nil {
+		return c.errorf("%s", err)
+	}
+	if err := c.write(out); err != nil {
+		return c.errorf("%s", err)
+	}
+	return exitChanged
+}
+
+func runApply(c *cli, args []string) int {
+	fs, typeExpr := c.flags("apply", "VALUE PATCH")
+	format := fs.String("format", "jsonpatch", `patch format: "json", "jsonpatch" or "mergepatch"`)
+	files, code := c.parse(fs, args, 2)
+	if files == nil {
+		return code
+	}
+
+	vals, err := c.readValues(*typeExpr, files[0])
+	if err != nil {
+		return c.errorf("%s", err)
+	}
+	val := vals[0]
+	diff, code := c.readPatch(*format, val, files[1])
+	if code != exitOK {
+		return code
+	}
+	result, err := diff.Apply(val)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "ctydiff: patch does not apply: %s\n", err)
+		return exitChanged
+	}
+	if err := c.writeValue(result); err != nil {
+		return c.errorf("%s", err)
+	}
+	return exitOK
+}
+
+func runInvert(c *cli, args []string) int {
+	fs, typeExpr := c.flags("invert", "VALUE PATCH")
+	format := fs.String("format", "jsonpatch", `patch format: "json", "jsonpatch" or "mergepatch"`)
+	files, code := c.parse(fs, args, 2)
+	if files == nil {
+		return code
+	}
+
+	vals, err := c.readValues(*typeExpr, files[0])
+	if err != nil {
+		return c.errorf("%s", err)
+	}
+	val := vals[0]
+	diff, code := c.readPatch(*format, val, files[1])
+	if code != exitOK {
+		return code
+	}
+	result, err := diff.Apply(val)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "ctydiff: patch does not apply: %s\n", err)
+		return exitChanged
+	}
+
+	// Patches do not record the values they replace, so we diff the
+	// result against the original value to recover them.
+	inverse, err := ctydiff.NewDiff(val, result).Invert()
+	if err != nil {
+		return c.errorf("%s", err)
+	}
+	var out []byte
+	switch *format {
+	case "json":
+		out, err = marshalDiff(inverse)
+	case "jsonpatch":
+		out, err = inverse.ToJSONPatch()
+	default:
+		out, err = inverse.ToMergePatch(result)
+	}
+	if err != nil {
+		return c.errorf("%s", err)
+	}
+	if err := c.write(out); err != nil {
+		return c.errorf("%s", err)
+	}
+	return exitOK
+}
+
+func runMerge(c *cli, args []string) int {
+	fs, typeExpr := c.flags("merge", "BASE OURS THEIRS")
+	files, code := c.parse(fs, args, 3)
+	if files == nil {
+		return code
+	}
+
+	vals, err := c.readValues(*typeExpr, files...)
+	if err != nil {
+		return c.errorf("%s", err)
+	}
+	merged, err := ctydiff.Merge(vals[0], vals[1], vals[2])
+	var mergeErr *ctydiff.MergeError
+	if errors.As(err, &mergeErr) {
+		for _, conflict := range mergeErr.Conflicts {
+			fmt.Fprintf(c.stderr, "ctydiff: conflict at %s\n", formatPath(conflict.Path))
+		}
+	} else if err != nil {
+		return c.errorf("%s", err)
+	}
+	if err := c.writeValue(merged); err != nil {
+		return c.errorf("%s", err)
+	}
+	if mergeErr != nil {
+		return exitChanged
+	}
+	return exitOK
+}
+
+// readValues reads the JSON documents in the given files and decodes them
+// using the given type expression, or using a type inferred from all of the
+// documents together if the type expression is empty.
+func (c *cli) readValues(typeExpr string, files ...string) ([]cty.Value, error) {
+	docs := make([][]byte, len(files))
+	for i, name := range files {
+		doc, err := c.readFile(name)
+		if err != nil {
+			return nil, err
+		}
+		docs[i] = doc
+	}
+
+	var ty cty.Type
+	if typeExpr != "" {
+		var err error
+		ty, err = ctyjson.UnmarshalType([]byte(typeExpr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid type: %s", err)
+		}
+	} else {
+		types := make([]cty.Type, len(docs))
+		for i, doc := range docs {
+			implied, err := ctyjson.ImpliedType(doc)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", files[i], err)
+			}
+			types[i] = implied
+		}
+		ty = unifyTypes(types)
+	}
+
+	vals := make([]cty.Value, len(docs))
+	for i, doc := range docs {
+		if typeExpr == "" {
+			// The document's implied type is generally narrower than the
+			// unified type, so we decode it with its own type and then
+			// conform it to the unified type.
+			implied, _ := ctyjson.ImpliedType(doc)
+			val, err := ctyjson.Unmarshal(doc, implied)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", files[i], err)
+			}
+			vals[i] = conform(val, ty)
+			continue
+		}
+		val, err := ctyjson.Unmarshal(doc, ty)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", files[i], err)
+		}
+		vals[i] = val
+	}
+	return vals, nil
+}
+
+// readPatch reads the patch in the given file, in the given format, and
+// converts it to a diff for the given value. It returns exitOK on success,
+// or an exit code after printing an error message.
+func (c *cli) readPatch(format string, val cty.Value, name string) (ctydiff.Diff, int) {
+	patch, err := c.readFile(name)
+	if err != nil {
+		return nil, c.errorf("%s", err)
+	}
+	var diff ctydiff.Diff
+	switch format {
+	case "json":
+		diff, err = unmarshalDiff(patch)
+	case "jsonpatch":
+		diff, err = ctydiff.FromJSONPatch(val.Type(), patch)
+	case "mergepatch":
+		diff, err = ctydiff.FromMergePatch(val, patch)
+	default:
+		return nil, c.errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return nil, c.errorf("%s: %s", name, err)
+	}
+	return diff, exitOK
+}
+
+// marshalDiff returns the JSON serialization of the given diff used by the
+// "json" format, which is the JSON serialization of its cty representation
+// along with the type of that representation.
+func marshalDiff(diff ctydiff.Diff) ([]byte, error) {
+	return ctyjson.Marshal(diff.ToValue(), cty.DynamicPseudoType)
+}
+
+// unmarshalDiff is the inverse of marshalDiff.
+func unmarshalDiff(raw []byte) (ctydiff.Diff, error) {
+	val, err := ctyjson.Unmarshal(raw, cty.DynamicPseudoType)
+	if err != nil {
+		return nil, err
+	}
+	return ctydiff.DiffFromValue(val)
+}
+
+func (c *cli) readFile(name string) ([]byte, error) {
+	if name == "-" {
+		return ioutil.ReadAll(c.stdin)
+	}
+	return ioutil.ReadFile(name)
+}
+
+// writeValue writes the given value as an indented JSON document.
+func (c *cli) writeValue(val cty.Value) error {
+	raw, err := ctyjson.Marshal(val, val.Type())
+	if err != nil {
+		return err
+	}
+	return c.write(raw)
+}
+
+// write writes the given output, indenting it first if it is JSON and
+// ensuring that it ends with a newline.
+func (c *cli) write(out []byte) error {
+	if json.Valid(out) {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, out, "", "  "); err == nil {
+			out = buf.Bytes()
+		}
+	}
+	if len(out) > 0 && out[len(out)-1] != '\n' {
+		out = append(out, '\n')
+	}
+	_, err := c.stdout.Write(out)
+	return err
+}
+
+// pathsFlag is a flag.Value for a flag that may be repeated to give a
+// number of paths, in the syntax of ctydiff.ParsePath.
+type pathsFlag []cty.Path
+
+func (f *pathsFlag) String() string {
+	strs := make([]string, len(*f))
+	for i, path := range *f {
+		strs[i] = ctydiff.FormatPath(path)
+	}
+	return strings.Join(strs, " ")
+}
+
+func (f *pathsFlag) Set(s string) error {
+	path, err := ctydiff.ParsePath(s)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, path)
+	return nil
+}
+
+// patterns returns patterns that match each of the paths exactly.
+func (f pathsFlag) patterns() []ctydiff.PathPattern {
+	ret := make([]ctydiff.PathPattern, len(f))
+	for i, path := range f {
+		ret[i] = ctydiff.PathPatternFromPath(path)
+	}
+	return ret
+}
+
+// formatPath returns a readable presentation of the given path for
+// messages.
+func formatPath(path cty.Path) string {
+	if len(path) == 0 {
+		return "the root value"
+	}
+	return ctydiff.FormatPath(path)
+}
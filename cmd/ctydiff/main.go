@@ -0,0 +1,100 @@
+// Command ctydiff compares, patches and merges JSON documents using the
+// structural diffs of the ctydiff package.
+//
+// Usage:
+//
+//	ctydiff diff [flags] OLD NEW
+//	ctydiff apply [flags] VALUE PATCH
+//	ctydiff invert [flags] VALUE PATCH
+//	ctydiff merge [flags] BASE OURS THEIRS
+//
+// Each argument is the name of a file containing a JSON document, or "-" to
+// read the document from standard input.
+//
+// The documents are decoded using the type given by the -type flag, as a
+// cty JSON type expression such as '["map","string"]'. Without -type, the
+// type is inferred from the documents themselves: JSON arrays whose
+// elements are all of the same type are treated as lists, and JSON objects
+// as objects with the union of the attributes of all of the documents.
+// The apply and invert commands infer the type from the VALUE document
+// alone, so a patch that adds a property to a JSON object can be applied
+// only with a -type that gives the object a map type.
+//
+// The diff command prints the differences between two documents, either
+// for humans ("plan" or "hcl" format) or as a patch ("json", "jsonpatch"
+// or "mergepatch" format). The "json" format is the cty representation of
+// the diff, including the types of its values, and unlike the other patch
+// formats it can represent any difference between two documents exactly.
+// The -ignore flag of the diff command gives the path of a value not to
+// compare, in the syntax of ctydiff.FormatPath, such as .tags["env"].
+//
+// The apply command applies a patch to a document and prints the result,
+// and the invert command prints a patch that undoes the given patch. The
+// merge command performs a three-way merge and prints the merged document.
+//
+// As with diff(1), the exit status is 0 for success, or when diff finds no
+// differences, 1 when diff finds differences, a patch does not apply or a
+// merge has conflicts, and 2 for any other error.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Exit codes, following the conventions of diff(1).
+const (
+	exitOK      = 0
+	exitChanged = 1
+	exitTrouble = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// command is a subcommand of ctydiff.
+type command struct {
+	name  string
+	args  string
+	usage string
+	run   func(c *cli, args []string) int
+}
+
+var commands = []command{
+	{"diff", "OLD NEW", "print the differences between two documents", runDiff},
+	{"apply", "VALUE PATCH", "apply a patch to a document", runApply},
+	{"invert", "VALUE PATCH", "print a patch that undoes a patch to a document", runInvert},
+	{"merge", "BASE OURS THEIRS", "merge the changes made to a document in two others", runMerge},
+}
+
+// run runs the command given by the arguments, excluding the program name,
+// and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitTrouble
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			c := &cli{stdin: stdin, stdout: stdout, stderr: stderr}
+			return cmd.run(c, args[1:])
+		}
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "-help" {
+		usage(stdout)
+		return exitOK
+	}
+	fmt.Fprintf(stderr, "ctydiff: unknown command %q\n", args[0])
+	usage(stderr)
+	return exitTrouble
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: ctydiff <command> [flags] <files>\n\ncommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-7s %-17s %s\n", cmd.name, cmd.args, cmd.usage)
+	}
+	fmt.Fprintf(w, "\nRun 'ctydiff <command> -h' for the flags of a command.\n")
+}
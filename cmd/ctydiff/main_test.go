@@ -0,0 +1,374 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"old.json":    `{"name":"a","tags":{"env":"dev"},"list":["x","y"]}`,
+		"new.json":    `{"name":"b","tags":{"env":"dev"},"list":["x","z"]}`,
+		"patch.json":  `[{"op":"replace","path":"/name","value":"b"}]`,
+		"merge.json":  `{"name":"b"}`,
+		"diff.json":   `{"value":[{"kind":"replace","path":[{"type":"attr","name":"name"}],"old":"a","new":"b"}],"type":["tuple",[["object",{"kind":"string","path":["tuple",[["object",{"type":"string","name":"string"}]]],"old":"string","new":"string"}]]]}`,
+		"bad.json":    `[{"op":"test","path":"/name","value":"z"}]`,
+		"ours.json":   `{"name":"b","tags":{"env":"dev"},"list":["x","y"]}`,
+		"theirs.json": `{"name":"a","tags":{"env":"prod"},"list":["x","y"]}`,
+		"clash.json":  `{"name":"c","tags":{"env":"dev"},"list":["x","y"]}`,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		args       []string
+		stdin      string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			"DiffSame",
+			[]string{"diff", "old.json", "old.json"},
+			"",
+			exitOK,
+			"",
+			"",
+		},
+		{
+			"DiffPlan",
+			[]string{"diff", "old.json", "new.json"},
+			"",
+			exitChanged,
+			`~ list = [
+    # (1 unchanged element hidden)
+    ~ "y" -> "z"
+  ]
+~ name = "a" -> "b"
+`,
+			"",
+		},
+		{
+			"DiffIgnore",
+			[]string{"diff", "-ignore", ".name", "-ignore", ".list[1]", "old.json", "new.json"},
+			"",
+			exitOK,
+			"",
+			"",
+		},
+		{
+			"DiffBadIgnore",
+			[]string{"diff", "-ignore", "name", "old.json", "new.json"},
+			"",
+			exitTrouble,
+			"",
+			`invalid value "name" for flag -ignore: unexpected 'n' at offset 0`,
+		},
+		{
+			"DiffJSONPatch",
+			[]string{"diff", "-format", "jsonpatch", "old.json", "new.json"},
+			"",
+			exitChanged,
+			`[
+  {
+    "op": "test",
+    "path": "/list/0",
+    "value": "x"
+  },
+  {
+    "op": "replace",
+    "path": "/list/1",
+    "value": "z"
+  },
+  {
+    "op": "replace",
+    "path": "/name",
+    "value": "b"
+  }
+]
+`,
+			"",
+		},
+		{
+			"DiffStdin",
+			[]string{"diff", "-format", "mergepatch", "old.json", "-"},
+			files["new.json"],
+			exitChanged,
+			`{
+  "list": [
+    "x",
+    "z"
+  ],
+  "name": "b"
+}
+`,
+			"",
+		},
+		{
+			"Apply",
+			[]string{"apply", "old.json", "patch.json"},
+			"",
+			exitOK,
+			`{
+  "list": [
+    "x",
+    "y"
+  ],
+  "name": "b",
+  "tags": {
+    "env": "dev"
+  }
+}
+`,
+			"",
+		},
+		{
+			"ApplyMergePatch",
+			[]string{"apply", "-format", "mergepatch", "old.json", "merge.json"},
+			"",
+			exitOK,
+			`{
+  "list": [
+    "x",
+    "y"
+  ],
+  "name": "b",
+  "tags": {
+    "env": "dev"
+  }
+}
+`,
+			"",
+		},
+		{
+			"ApplyJSON",
+			[]string{"apply", "-format", "json", "old.json", "diff.json"},
+			"",
+			exitOK,
+			`{
+  "list": [
+    "x",
+    "y"
+  ],
+  "name": "b",
+  "tags": {
+    "env": "dev"
+  }
+}
+`,
+			"",
+		},
+		{
+			"ApplyConflict",
+			[]string{"apply", "old.json", "bad.json"},
+			"",
+			exitChanged,
+			"",
+			"ctydiff: patch does not apply: ",
+		},
+		{
+			"Invert",
+			[]string{"invert", "old.json", "patch.json"},
+			"",
+			exitOK,
+			`[
+  {
+    "op": "replace",
+    "path": "/name",
+    "value": "a"
+  },
+  {
+    "op": "test",
+    "path": "/list/1",
+    "value": "y"
+  },
+  {
+    "op": "test",
+    "path": "/list/0",
+    "value": "x"
+  }
+]
+`,
+			"",
+		},
+		{
+			"Merge",
+			[]string{"merge", "old.json", "ours.json", "theirs.json"},
+			"",
+			exitOK,
+			`{
+  "list": [
+    "x",
+    "y"
+  ],
+  "name": "b",
+  "tags": {
+    "env": "prod"
+  }
+}
+`,
+			"",
+		},
+		{
+			"MergeConflict",
+			[]string{"merge", "old.json", "ours.json", "clash.json"},
+			"",
+			exitChanged,
+			`{
+  "list": [
+    "x",
+    "y"
+  ],
+  "name": "b",
+  "tags": {
+    "env": "dev"
+  }
+}
+`,
+			"ctydiff: conflict at .name\n",
+		},
+		{
+			"MissingFile",
+			[]string{"diff", "old.json", "missing.json"},
+			"",
+			exitTrouble,
+			"",
+			"ctydiff: open ",
+		},
+		{
+			"BadType",
+			[]string{"diff", "-type", `"nope"`, "old.json", "new.json"},
+			"",
+			exitTrouble,
+			"",
+			"ctydiff: invalid type: ",
+		},
+		{
+			"WrongArgs",
+			[]string{"diff", "old.json"},
+			"",
+			exitTrouble,
+			"",
+			"usage: ctydiff diff [flags] OLD NEW\n",
+		},
+		{
+			"DiffBadFormat",
+			[]string{"diff", "-format", "yaml", "old.json", "old.json"},
+			"",
+			exitTrouble,
+			"",
+			"ctydiff: unknown format \"yaml\"\n",
+		},
+		{
+			"CommandHelp",
+			[]string{"diff", "-h"},
+			"",
+			exitOK,
+			"",
+			"usage: ctydiff diff [flags] OLD NEW\n",
+		},
+		{
+			"UnknownCommand",
+			[]string{"frob"},
+			"",
+			exitTrouble,
+			"",
+			"ctydiff: unknown command \"frob\"\n",
+		},
+		{
+			"NoCommand",
+			nil,
+			"",
+			exitTrouble,
+			"",
+			"usage: ctydiff <command> [flags] <files>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := make([]string, len(tt.args))
+			for i, arg := range tt.args {
+				if strings.HasSuffix(arg, ".json") {
+					arg = filepath.Join(dir, arg)
+				}
+				args[i] = arg
+			}
+			var stdout, stderr bytes.Buffer
+			code := run(args, strings.NewReader(tt.stdin), &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Errorf("wrong exit code %d; want %d\nstderr: %s", code, tt.wantCode, stderr.String())
+			}
+			if got := stdout.String(); got != tt.wantStdout {
+				t.Errorf("wrong output\ngot:\n%s\nwant:\n%s", got, tt.wantStdout)
+			}
+			if got := stderr.String(); !strings.HasPrefix(got, tt.wantStderr) {
+				t.Errorf("wrong error output\ngot:  %q\nwant prefix: %q", got, tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestUnifyTypes(t *testing.T) {
+	tests := []struct {
+		name  string
+		types []cty.Type
+		want  cty.Type
+	}{
+		{
+			"Same",
+			[]cty.Type{cty.String, cty.String},
+			cty.String,
+		},
+		{
+			"Different",
+			[]cty.Type{cty.String, cty.Number},
+			cty.DynamicPseudoType,
+		},
+		{
+			"Objects",
+			[]cty.Type{
+				cty.Object(map[string]cty.Type{"a": cty.String}),
+				cty.Object(map[string]cty.Type{"a": cty.String, "b": cty.Bool}),
+			},
+			cty.Object(map[string]cty.Type{"a": cty.String, "b": cty.Bool}),
+		},
+		{
+			"Tuples",
+			[]cty.Type{
+				cty.Tuple([]cty.Type{cty.String}),
+				cty.Tuple([]cty.Type{cty.String, cty.String}),
+				cty.EmptyTuple,
+			},
+			cty.List(cty.String),
+		},
+		{
+			"EmptyTuples",
+			[]cty.Type{cty.EmptyTuple, cty.EmptyTuple},
+			cty.EmptyTuple,
+		},
+		{
+			"MixedTuple",
+			[]cty.Type{cty.Tuple([]cty.Type{cty.String, cty.Number})},
+			cty.DynamicPseudoType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unifyTypes(tt.types); !got.Equals(tt.want) {
+				t.Errorf("wrong type %#v; want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"github.com/zclconf/go-cty/cty"
+)
+
+// unifyTypes returns a type that can represent values of all of the given
+// types, which are the types implied by a set of JSON documents.
+//
+// ctyjson.ImpliedType infers a tuple type for each JSON array and an object
+// type with exactly the attributes present for each JSON object, so two
+// versions of a document usually have different implied types. Comparing
+// them as-is would show every changed array or object as replaced
+// entirely, so instead we generalize arrays to lists where possible and
+// objects to the union of their attributes.
+//
+// Where the types cannot be unified the result is cty.DynamicPseudoType,
+// and values are left with their own types.
+func unifyTypes(types []cty.Type) cty.Type {
+	first := types[0]
+	switch {
+	case allTypes(types, cty.Type.IsObjectType):
+		atys := make(map[string][]cty.Type)
+		for _, ty := range types {
+			for name, aty := range ty.AttributeTypes() {
+				atys[name] = append(atys[name], aty)
+			}
+		}
+		ret := make(map[string]cty.Type, len(atys))
+		for name, types := range atys {
+			ret[name] = unifyTypes(types)
+		}
+		return cty.Object(ret)
+	case allTypes(types, isSequenceType):
+		var etys []cty.Type
+		for _, ty := range types {
+			if ty.IsListType() {
+				etys = append(etys, ty.ElementType())
+			} else {
+				etys = append(etys, ty.TupleElementTypes()...)
+			}
+		}
+		if len(etys) == 0 {
+			return first
+		}
+		if ety := unifyTypes(etys); !ety.Equals(cty.DynamicPseudoType) {
+			return cty.List(ety)
+		}
+		return cty.DynamicPseudoType
+	}
+	for _, ty := range types[1:] {
+		if !ty.Equals(first) {
+			return cty.DynamicPseudoType
+		}
+	}
+	return first
+}
+
+func allTypes(types []cty.Type, fn func(cty.Type) bool) bool {
+	for _, ty := range types {
+		if !fn(ty) {
+			return false
+		}
+	}
+	return true
+}
+
+func isSequenceType(ty cty.Type) bool {
+	return ty.IsTupleType() || ty.IsListType()
+}
+
+// conform converts the given value, as decoded from JSON with its implied
+// type, to the given type returned by unifyTypes. Object attributes that
+// are missing from the value are set to null.
+func conform(val cty.Value, ty cty.Type) cty.Value {
+	switch {
+	case ty.Equals(cty.DynamicPseudoType):
+		return val
+	case val.IsNull():
+		return cty.NullVal(ty)
+	case ty.IsObjectType():
+		atys := ty.AttributeTypes()
+		attrs := make(map[string]cty.Value, len(atys))
+		for name, aty := range atys {
+			if val.Type().HasAttribute(name) {
+				attrs[name] = conform(val.GetAttr(name), aty)
+			} else {
+				attrs[name] = cty.NullVal(aty)
+			}
+		}
+		return cty.ObjectVal(attrs)
+	case ty.IsListType():
+		if val.LengthInt() == 0 {
+			return cty.ListValEmpty(ty.ElementType())
+		}
+		elems := make([]cty.Value, 0, val.LengthInt())
+		for it := val.ElementIterator(); it.Next(); {
+			_, ev := it.Element()
+			elems = append(elems, conform(ev, ty.ElementType()))
+		}
+		return cty.ListVal(elems)
+	}
+	return val
+}
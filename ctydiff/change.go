@@ -2,7 +2,6 @@ package ctydiff
 
 import (
 	"errors"
-	"fmt"
 
 	"github.com/zclconf/go-cty/cty"
 )
@@ -15,6 +14,13 @@ import (
 type Change interface {
 	changeSigil() changeImpl
 	apply(val cty.Value) (cty.Value, error)
+
+	// path returns the path of the change, which is relative to the
+	// path of any NestedDiff the change belongs to.
+	path() cty.Path
+
+	// withPath returns a copy of the change with its path replaced.
+	withPath(path cty.Path) Change
 }
 
 // Embed changeImpl into a struct to make it a Change implementation
@@ -29,6 +35,10 @@ func (c changeImpl) changeSigil() changeImpl {
 //
 // When adding a new element to a map value, this change type should be used
 // with OldValue set to a null value of the appropriate type.
+//
+// If OldValue is cty.NilVal then the existing value is not checked, though
+// it must still exist. This is for diffs converted from formats that do not
+// record old values, such as JSON Patch.
 type ReplaceChange struct {
 	changeImpl
 	Path     cty.Path
@@ -36,65 +46,44 @@ type ReplaceChange struct {
 	NewValue cty.Value
 }
 
+func (c ReplaceChange) path() cty.Path {
+	return c.Path
+}
+
+func (c ReplaceChange) withPath(path cty.Path) Change {
+	c.Path = path
+	return c
+}
+
 func (c ReplaceChange) apply(val cty.Value) (cty.Value, error) {
 	if len(c.Path) == 0 {
 		// Empty path, replace entire value.
-		if !val.RawEquals(c.OldValue) {
+		if c.OldValue != cty.NilVal && !rawEquals(val, c.OldValue) {
 			return cty.NilVal, errors.New("existing value does not match")
 		}
 		return c.NewValue, nil
 	}
-	parent, _ := c.Path[:len(c.Path)-1].Apply(val)
-	if !c.OldValue.IsNull() || !parent.Type().IsMapType() {
-		// Compare existing.
-		existing, err := c.Path.Apply(val)
-		if err != nil {
-			return cty.NilVal, c.Path.NewErrorf("path does not exist in value")
-		}
-		if !existing.RawEquals(c.OldValue) {
-			return cty.NilVal, c.Path.NewErrorf("existing value does not match")
-		}
-	}
 	key := c.Path[len(c.Path)-1]
-	ty := parent.Type()
-	switch {
-	case ty.IsObjectType():
-		kv := parent.AsValueMap()
-		kv[key.(cty.GetAttrStep).Name] = c.NewValue
-		return cty.ObjectVal(kv), nil
-	case ty.IsMapType():
-		kv := parent.AsValueMap()
-		if kv == nil {
-			kv = make(map[string]cty.Value)
+	return transformPath(val, c.Path[:len(c.Path)-1], func(parent cty.Value) (cty.Value, error) {
+		if err := requireKnown(parent); err != nil {
+			return cty.NilVal, c.Path.NewErrorf("cannot replace an element of this value: %s", err)
 		}
-		kv[key.(cty.IndexStep).Key.AsString()] = c.NewValue
-		return cty.MapVal(kv), nil
-	case ty.IsListType():
-		var vv []cty.Value
-		idx := key.(cty.IndexStep).Key
-		for it := parent.ElementIterator(); it.Next(); {
-			i, ev := it.Element()
-			if i.RawEquals(idx) {
-				vv = append(vv, c.NewValue)
-				continue
+		if c.OldValue == cty.NilVal || !c.OldValue.IsNull() || !parent.Type().IsMapType() {
+			// Compare existing.
+			existing, err := applyStep(parent, key)
+			if err != nil {
+				return cty.NilVal, c.Path.NewErrorf("path does not exist in value: %s", err)
 			}
-			vv = append(vv, ev)
-		}
-		return cty.ListVal(vv), nil
-	case ty.IsTupleType():
-		var vv []cty.Value
-		idx := key.(cty.IndexStep).Key
-		for it := parent.ElementIterator(); it.Next(); {
-			i, ev := it.Element()
-			if i.RawEquals(idx) {
-				vv = append(vv, c.NewValue)
-				continue
+			if c.OldValue != cty.NilVal && !rawEquals(existing, c.OldValue) {
+				return cty.NilVal, c.Path.NewErrorf("existing value does not match")
 			}
-			vv = append(vv, ev)
 		}
-		return cty.TupleVal(vv), nil
-	}
-	panic(fmt.Sprintf("Not supported: %s", ty.FriendlyName()))
+		ret, err := replaceStep(parent, key, c.NewValue)
+		if err != nil {
+			return cty.NilVal, c.Path.NewError(err)
+		}
+		return ret, nil
+	})
 }
 
 // DeleteChange is a Change implementation that represents removing an
@@ -106,88 +95,151 @@ func (c ReplaceChange) apply(val cty.Value) (cty.Value, error) {
 // on the same list must be careful to consider the new state of the element
 // indices after each step, or present the deletions in reverse order to
 // avoid such complexity.
+//
+// As with ReplaceChange, if OldValue is cty.NilVal then the existing value
+// is not checked.
 type DeleteChange struct {
 	changeImpl
 	Path     cty.Path
 	OldValue cty.Value
 }
 
+func (c DeleteChange) path() cty.Path {
+	return c.Path
+}
+
+func (c DeleteChange) withPath(path cty.Path) Change {
+	c.Path = path
+	return c
+}
+
 func (c DeleteChange) apply(val cty.Value) (cty.Value, error) {
+	if len(c.Path) == 0 {
+		return cty.NilVal, errors.New("cannot delete the entire value")
+	}
 	// Compare existing.
-	existing, err := c.Path.Apply(val)
+	existing, err := applyPath(val, c.Path)
 	if err != nil {
-		return cty.NilVal, c.Path.NewErrorf("path does not exist in value")
+		return cty.NilVal, c.Path.NewErrorf("path does not exist in value: %s", err)
 	}
-	if !existing.RawEquals(c.OldValue) {
+	if c.OldValue != cty.NilVal && !rawEquals(existing, c.OldValue) {
 		return cty.NilVal, c.Path.NewErrorf("existing value does not match")
 	}
-	parent, _ := c.Path[:len(c.Path)-1].Apply(val)
 	key := c.Path[len(c.Path)-1]
-	ty := parent.Type()
-	switch {
-	case ty.IsObjectType():
-		kv := parent.AsValueMap()
-		delete(kv, key.(cty.GetAttrStep).Name)
-		return cty.ObjectVal(kv), nil
-	case ty.IsMapType():
-		kv := parent.AsValueMap()
-		delete(kv, key.(cty.IndexStep).Key.AsString())
-		return cty.MapVal(kv), nil
-	case ty.IsListType():
-		var vv []cty.Value
-		idx := key.(cty.IndexStep).Key
-		for it := parent.ElementIterator(); it.Next(); {
-			i, ev := it.Element()
-			if i.RawEquals(idx) {
-				// Skip
-				continue
-			}
-			vv = append(vv, ev)
-		}
-		return cty.ListVal(vv), nil
-	case ty.IsTupleType():
-		var vv []cty.Value
-		idx := key.(cty.IndexStep).Key
-		for it := parent.ElementIterator(); it.Next(); {
-			i, ev := it.Element()
-			if i.RawEquals(idx) {
-				// Skip
-				continue
-			}
-			vv = append(vv, ev)
+	return transformPath(val, c.Path[:len(c.Path)-1], func(parent cty.Value) (cty.Value, error) {
+		ret, err := deleteStep(parent, key)
+		if err != nil {
+			return cty.NilVal, c.Path.NewError(err)
 		}
-		return cty.TupleVal(vv), nil
-	}
-	return cty.NilVal, c.Path.NewErrorf("value is not indexable")
+		return ret, nil
+	})
 }
 
 // InsertChange is a Change implementation that represents inserting a new
 // element into a list.
 //
-// When appending to a list, the Path should be to the not-yet-existing index
-// and BeforeValue should be a null of the appropriate type.
+// The Path is to the list itself, and the new element is inserted before
+// the first element that is equal to BeforeValue. When appending to a list,
+// BeforeValue should be a null of the appropriate type, or cty.NilVal to
+// append without checking that the list is empty.
+//
+// If ElementPath is true then the Path is instead to the index where the
+// new element will be placed, and BeforeValue is the element currently at
+// that index, which will follow the new element. When appending, the Path
+// is to the not-yet-existing index and BeforeValue is a null of the
+// appropriate type, and if BeforeValue is cty.NilVal then the element at
+// the index is not checked. This form is what NewDiff produces, since it
+// places the new element even when the list contains duplicates.
 type InsertChange struct {
 	changeImpl
 	Path        cty.Path
 	NewValue    cty.Value
 	BeforeValue cty.Value
+	ElementPath bool
+}
+
+func (c InsertChange) path() cty.Path {
+	return c.Path
+}
+
+func (c InsertChange) withPath(path cty.Path) Change {
+	c.Path = path
+	return c
 }
 
 func (c InsertChange) apply(val cty.Value) (cty.Value, error) {
-	list, err := c.Path.Apply(val)
+	if !c.ElementPath {
+		return transformPath(val, c.Path, c.insertBefore)
+	}
+	var index cty.IndexStep
+	if len(c.Path) > 0 {
+		index, _ = c.Path[len(c.Path)-1].(cty.IndexStep)
+	}
+	if index.Key == cty.NilVal {
+		return cty.NilVal, c.Path.NewErrorf("path must be to a list index")
+	}
+	return transformPath(val, c.Path[:len(c.Path)-1], func(list cty.Value) (cty.Value, error) {
+		if err := requireKnown(list); err != nil {
+			return cty.NilVal, c.Path.NewErrorf("cannot insert into this value: %s", err)
+		}
+		if !isKnownSequence(list) {
+			return cty.NilVal, c.Path.NewErrorf("value is not a list or tuple")
+		}
+		return c.insertAt(list, index.Key)
+	})
+}
+
+// insertAt implements the apply operation for an InsertChange whose path
+// is to the index of the new element.
+func (c InsertChange) insertAt(list cty.Value, key cty.Value) (cty.Value, error) {
+	ty := list.Type()
+	if err := requireElementType(ty, c.NewValue); err != nil {
+		return cty.NilVal, c.Path.NewError(err)
+	}
+	vals := list.AsValueSlice()
+	idx, err := listIndex(key, len(vals)+1)
 	if err != nil {
-		return cty.NilVal, c.Path.NewErrorf("path does not exist in value")
+		return cty.NilVal, c.Path.NewError(err)
 	}
-	if !list.CanIterateElements() {
-		return cty.NilVal, c.Path.NewErrorf("value is not iterable")
+	switch {
+	case c.BeforeValue == cty.NilVal:
+		// Unchecked.
+	case idx == len(vals):
+		if !c.BeforeValue.IsNull() {
+			return cty.NilVal, c.Path.NewErrorf("before value does not exist")
+		}
+	case !vals[idx].RawEquals(c.BeforeValue):
+		return cty.NilVal, c.Path.NewErrorf("before value does not match")
+	}
+	out := make([]cty.Value, 0, len(vals)+1)
+	out = append(out, vals[:idx]...)
+	out = append(out, c.NewValue)
+	out = append(out, vals[idx:]...)
+	return sequenceVal(ty, out), nil
+}
+
+// insertBefore implements the apply operation for an InsertChange whose
+// path is to the list itself.
+func (c InsertChange) insertBefore(list cty.Value) (cty.Value, error) {
+	if err := requireKnown(list); err != nil {
+		return cty.NilVal, c.Path.NewErrorf("cannot insert into this value: %s", err)
 	}
 	ty := list.Type()
+	if !(ty.IsListType() || ty.IsTupleType()) {
+		return cty.NilVal, c.Path.NewErrorf("value is not a list or tuple")
+	}
+	if err := requireElementType(ty, c.NewValue); err != nil {
+		return cty.NilVal, c.Path.NewError(err)
+	}
 	vals := list.AsValueSlice()
 	out := make([]cty.Value, 0, len(vals)+1)
-	if len(vals) == 0 && c.BeforeValue.IsNull() {
+	if c.BeforeValue == cty.NilVal {
+		out = append(out, vals...)
+		out = append(out, c.NewValue)
+	} else if len(vals) == 0 && c.BeforeValue.IsNull() {
 		if ty.IsListType() {
 			if !c.BeforeValue.Type().Equals(ty.ElementType()) {
-				return cty.NilVal, c.Path.NewErrorf("before value must be a %s", ty.ElementType())
+				return cty.NilVal, c.Path.NewErrorf("before value must be a %s", ty.ElementType().FriendlyName())
 			}
 		}
 		out = append(out, c.NewValue)
@@ -204,13 +256,7 @@ func (c InsertChange) apply(val cty.Value) (cty.Value, error) {
 			return cty.NilVal, c.Path.NewErrorf("before value does not exist")
 		}
 	}
-	switch {
-	case ty.IsListType():
-		return cty.ListVal(out), nil
-	case ty.IsTupleType():
-		return cty.TupleVal(out), nil
-	}
-	panic(fmt.Sprintf("Not supported: %s", ty.FriendlyName()))
+	return sequenceVal(ty, out), nil
 }
 
 // AddChange is a Change implementation that represents adding a value to
@@ -221,17 +267,30 @@ type AddChange struct {
 	NewValue cty.Value
 }
 
+func (c AddChange) path() cty.Path {
+	return c.Path
+}
+
+func (c AddChange) withPath(path cty.Path) Change {
+	c.Path = path
+	return c
+}
+
 func (c AddChange) apply(val cty.Value) (cty.Value, error) {
-	set, err := c.Path.Apply(val)
-	if err != nil {
-		return cty.NilVal, c.Path.NewErrorf("path does not exist in value")
-	}
-	if !set.Type().IsSetType() {
-		return cty.NilVal, c.Path.NewErrorf("value is not a set")
-	}
-	s := set.AsValueSet()
-	s.Add(c.NewValue)
-	return cty.SetVal(s.Values()), nil
+	return transformPath(val, c.Path, func(set cty.Value) (cty.Value, error) {
+		if err := requireKnown(set); err != nil {
+			return cty.NilVal, c.Path.NewErrorf("cannot add to this value: %s", err)
+		}
+		if !set.Type().IsSetType() {
+			return cty.NilVal, c.Path.NewErrorf("value is not a set")
+		}
+		if err := requireElementType(set.Type(), c.NewValue); err != nil {
+			return cty.NilVal, c.Path.NewError(err)
+		}
+		s := set.AsValueSet()
+		s.Add(c.NewValue)
+		return cty.SetValFromValueSet(s), nil
+	})
 }
 
 // RemoveChange is a Change implementation that represents removing a value
@@ -249,20 +308,33 @@ type RemoveChange struct {
 	OldValue cty.Value
 }
 
+func (c RemoveChange) path() cty.Path {
+	return c.Path
+}
+
+func (c RemoveChange) withPath(path cty.Path) Change {
+	c.Path = path
+	return c
+}
+
 func (c RemoveChange) apply(val cty.Value) (cty.Value, error) {
-	set, err := c.Path.Apply(val)
-	if err != nil {
-		return cty.NilVal, c.Path.NewErrorf("path does not exist in value")
-	}
-	if !set.Type().IsSetType() {
-		return cty.NilVal, c.Path.NewErrorf("value is not a set")
-	}
-	s := set.AsValueSet()
-	if !s.Has(c.OldValue) {
-		return cty.NilVal, c.Path.NewErrorf("old value does not exist")
-	}
-	s.Remove(c.OldValue)
-	return cty.SetVal(s.Values()), nil
+	return transformPath(val, c.Path, func(set cty.Value) (cty.Value, error) {
+		if err := requireKnown(set); err != nil {
+			return cty.NilVal, c.Path.NewErrorf("cannot remove from this value: %s", err)
+		}
+		if !set.Type().IsSetType() {
+			return cty.NilVal, c.Path.NewErrorf("value is not a set")
+		}
+		if err := requireElementType(set.Type(), c.OldValue); err != nil {
+			return cty.NilVal, c.Path.NewError(err)
+		}
+		s := set.AsValueSet()
+		if !s.Has(c.OldValue) {
+			return cty.NilVal, c.Path.NewErrorf("old value does not exist")
+		}
+		s.Remove(c.OldValue)
+		return cty.SetValFromValueSet(s), nil
+	})
 }
 
 // NestedDiff is a Change implementation that applies a nested diff to a
@@ -281,6 +353,9 @@ func (c RemoveChange) apply(val cty.Value) (cty.Value, error) {
 // the value path only once and apply a number of other operations to it.
 // However, it's acceptable to use NestedDiff on any value type as long as
 // the nested diff is valid for that type.
+//
+// A set element is addressed by an IndexStep whose key is the element
+// itself. Applying the nested diff replaces that element with the result.
 type NestedDiff struct {
 	changeImpl
 	Path     cty.Path
@@ -288,8 +363,26 @@ type NestedDiff struct {
 	Diff     Diff
 }
 
+func (c NestedDiff) path() cty.Path {
+	return c.Path
+}
+
+func (c NestedDiff) withPath(path cty.Path) Change {
+	c.Path = path
+	return c
+}
+
 func (c NestedDiff) apply(val cty.Value) (cty.Value, error) {
-	return cty.NullVal(val.Type()), errors.New("not yet implemented")
+	return transformPath(val, c.Path, func(existing cty.Value) (cty.Value, error) {
+		if !rawEquals(existing, c.OldValue) {
+			return cty.NilVal, c.Path.NewErrorf("existing value does not match")
+		}
+		new, err := c.Diff.Apply(existing)
+		if err != nil {
+			return cty.NilVal, c.Path.NewError(err)
+		}
+		return new, nil
+	})
 }
 
 // Context is a funny sort of Change implementation that doesn't actually
@@ -304,12 +397,21 @@ type Context struct {
 	WantValue cty.Value
 }
 
+func (c Context) path() cty.Path {
+	return c.Path
+}
+
+func (c Context) withPath(path cty.Path) Change {
+	c.Path = path
+	return c
+}
+
 func (c Context) apply(val cty.Value) (cty.Value, error) {
-	existing, err := c.Path.Apply(val)
+	existing, err := applyPath(val, c.Path)
 	if err != nil {
-		return cty.NilVal, c.Path.NewErrorf("path does not exist in value")
+		return cty.NilVal, c.Path.NewErrorf("path does not exist in value: %s", err)
 	}
-	if !existing.RawEquals(c.WantValue) {
+	if !rawEquals(existing, c.WantValue) {
 		return cty.NilVal, c.Path.NewErrorf("existing value does not match")
 	}
 	return val, nil
@@ -0,0 +1,574 @@
+package ctydiff
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Conflict describes a change that cannot be applied to a value, as
+// reported by Check.
+type Conflict struct {
+	// Change is the change itself, and Path is its absolute path.
+	Change Change
+	Path   cty.Path
+
+	// Err describes the precondition of the change that is not met. Any
+	// cty.PathError it contains has an absolute path.
+	Err error
+}
+
+// Check reports whether the receiver can be applied to the given value,
+// without producing the new value. It returns a Conflict for each change
+// that would fail, or nil if the receiver would apply cleanly.
+//
+// Check runs the preconditions of each change: that the value at its path
+// exists and matches its OldValue or WantValue, that the element at an
+// insertion point matches its BeforeValue, and that a set contains the
+// values to be removed from it. Rather than rebuilding the value after
+// each change, it records only the parts of the value that the changes so
+// far have touched, such as the elements of a list whose indices an insert
+// or delete has shifted, or the members added to and removed from a set.
+// It builds the current version of a value only when a change compares a
+// value that contains earlier changes.
+//
+// As for ApplyPartial, a change that fails is skipped and each change is
+// checked against the state left by the changes before it that succeeded,
+// so Check reports every conflict rather than stopping at the first. The
+// changes inside a NestedDiff that conflicts are not checked, since there
+// is no value to check them against.
+func (d Diff) Check(source cty.Value) []Conflict {
+	var conflicts []Conflict
+	d.check(&checkNode{val: source}, nil, &conflicts)
+	return conflicts
+}
+
+func (d Diff) check(root *checkNode, prefix cty.Path, conflicts *[]Conflict) {
+	for i, c := range d {
+		if c == nil {
+			*conflicts = append(*conflicts, Conflict{
+				Err: prefixError(prefix, fmt.Errorf("change %d is nil", i)),
+			})
+			continue
+		}
+		absPath := joinPath(prefix, c.path())
+		if err := checkChange(root, c, absPath, conflicts); err != nil {
+			*conflicts = append(*conflicts, Conflict{
+				Change: c,
+				Path:   absPath,
+				Err:    prefixError(prefix, err),
+			})
+		}
+	}
+}
+
+// checkChange checks the preconditions of the given change against the
+// value represented by the given node and, if they are met, records the
+// effect of the change in the node. It returns an error, with a path
+// relative to the node, if the change would fail.
+//
+// The changes inside a NestedDiff are checked with the given absolute path
+// as their prefix, and any conflicts among them are added to conflicts.
+func checkChange(root *checkNode, c Change, absPath cty.Path, conflicts *[]Conflict) error {
+	switch c := c.(type) {
+	case ReplaceChange:
+		if len(c.Path) == 0 {
+			if c.OldValue != cty.NilVal && !rawEquals(root.value(), c.OldValue) {
+				return errors.New("existing value does not match")
+			}
+			*root = checkNode{val: c.NewValue}
+			return nil
+		}
+		chain, err := resolve(root, c.Path[:len(c.Path)-1])
+		if err != nil {
+			return err
+		}
+		parent := chain[len(chain)-1]
+		if err := requireKnown(parent.val); err != nil {
+			return c.Path.NewErrorf("cannot replace an element of this value: %s", err)
+		}
+		key := c.Path[len(c.Path)-1]
+		var existing *checkNode
+		if c.OldValue == cty.NilVal || !c.OldValue.IsNull() || !parent.val.Type().IsMapType() {
+			existing, err = parent.child(key)
+			if err != nil {
+				return c.Path.NewErrorf("path does not exist in value: %s", err)
+			}
+			if c.OldValue != cty.NilVal && !rawEquals(existing.value(), c.OldValue) {
+				return c.Path.NewErrorf("existing value does not match")
+			}
+		}
+		if err := checkReplaceStep(parent.val.Type(), key, c.NewValue); err != nil {
+			return c.Path.NewError(err)
+		}
+		if existing == nil {
+			// This is a map element that might not exist yet.
+			existing = parent.mapElem(key.(cty.IndexStep).Key.AsString())
+		}
+		saved := *existing
+		*existing = checkNode{val: c.NewValue}
+		if err := checkTypes(append(chain, existing), c.Path); err != nil {
+			*existing = saved
+			return err
+		}
+		return nil
+
+	case DeleteChange:
+		if len(c.Path) == 0 {
+			return errors.New("cannot delete the entire value")
+		}
+		chain, err := resolve(root, c.Path)
+		if err != nil {
+			return c.Path.NewErrorf("path does not exist in value: %s", err)
+		}
+		if c.OldValue != cty.NilVal && !rawEquals(chain[len(chain)-1].value(), c.OldValue) {
+			return c.Path.NewErrorf("existing value does not match")
+		}
+		child := chain[len(chain)-1]
+		chain = chain[:len(chain)-1]
+		parent := chain[len(chain)-1]
+		savedParent, savedChild := *parent, *child
+		switch ty := parent.val.Type(); {
+		case ty.IsObjectType() || ty.IsMapType():
+			*child = checkNode{deleted: true}
+		case ty.IsListType() || ty.IsTupleType():
+			idx, _ := listIndex(c.Path[len(c.Path)-1].(cty.IndexStep).Key, len(parent.elems))
+			parent.elems = append(parent.elems[:idx:idx], parent.elems[idx+1:]...)
+		default:
+			return c.Path.NewErrorf("value is not indexable")
+		}
+		if err := checkTypes(chain, c.Path); err != nil {
+			*parent, *child = savedParent, savedChild
+			return err
+		}
+		return nil
+
+	case InsertChange:
+		listPath := c.Path
+		if c.ElementPath {
+			if len(c.Path) > 0 {
+				if index, ok := c.Path[len(c.Path)-1].(cty.IndexStep); ok && index.Key != cty.NilVal {
+					listPath = c.Path[:len(c.Path)-1]
+				}
+			}
+			if len(listPath) == len(c.Path) {
+				return c.Path.NewErrorf("path must be to a list index")
+			}
+		}
+		chain, err := resolve(root, listPath)
+		if err != nil {
+			return err
+		}
+		list := chain[len(chain)-1]
+		saved := *list
+		if err := list.insert(c); err != nil {
+			return err
+		}
+		if err := checkTypes(chain, listPath); err != nil {
+			*list = saved
+			return err
+		}
+		return nil
+
+	case AddChange:
+		chain, err := resolve(root, c.Path)
+		if err != nil {
+			return err
+		}
+		set := chain[len(chain)-1]
+		if err := requireKnown(set.val); err != nil {
+			return c.Path.NewErrorf("cannot add to this value: %s", err)
+		}
+		if !set.val.Type().IsSetType() {
+			return c.Path.NewErrorf("value is not a set")
+		}
+		if err := requireElementType(set.val.Type(), c.NewValue); err != nil {
+			return c.Path.NewError(err)
+		}
+		if set.member(c.NewValue) < 0 {
+			set.elems = append(set.elems, &checkNode{val: c.NewValue})
+		}
+		return nil
+
+	case RemoveChange:
+		chain, err := resolve(root, c.Path)
+		if err != nil {
+			return err
+		}
+		set := chain[len(chain)-1]
+		if err := requireKnown(set.val); err != nil {
+			return c.Path.NewErrorf("cannot remove from this value: %s", err)
+		}
+		if !set.val.Type().IsSetType() {
+			return c.Path.NewErrorf("value is not a set")
+		}
+		if err := requireElementType(set.val.Type(), c.OldValue); err != nil {
+			return c.Path.NewError(err)
+		}
+		if set.member(c.OldValue) < 0 {
+			return c.Path.NewErrorf("old value does not exist")
+		}
+		// Earlier changes to the members may have made some of them equal,
+		// in which case the set really holds only one of them.
+		for i := set.member(c.OldValue); i >= 0; i = set.member(c.OldValue) {
+			set.elems = append(set.elems[:i:i], set.elems[i+1:]...)
+		}
+		return nil
+
+	case NestedDiff:
+		chain, err := resolve(root, c.Path)
+		if err != nil {
+			return err
+		}
+		node := chain[len(chain)-1]
+		existing := node.value()
+		if !rawEquals(existing, c.OldValue) {
+			return c.Path.NewErrorf("existing value does not match")
+		}
+		// The nested changes are checked against a fresh node, which we
+		// can discard if the result turns out not to fit where it is.
+		before := len(*conflicts)
+		sub := &checkNode{val: existing}
+		c.Diff.check(sub, absPath, conflicts)
+		if sub.val == cty.NilVal && len(c.Path) > 0 {
+			// A nested ReplaceChange with an empty path can remove the
+			// value altogether.
+			*conflicts = (*conflicts)[:before]
+			return c.Path.NewErrorf("new value is missing")
+		}
+		saved := *node
+		*node = *sub
+		if err := checkTypes(chain, c.Path); err != nil {
+			*node = saved
+			*conflicts = (*conflicts)[:before]
+			return err
+		}
+		return nil
+
+	case Context:
+		chain, err := resolve(root, c.Path)
+		if err != nil {
+			return c.Path.NewErrorf("path does not exist in value: %s", err)
+		}
+		if !rawEquals(chain[len(chain)-1].value(), c.WantValue) {
+			return c.Path.NewErrorf("existing value does not match")
+		}
+		return nil
+
+	default:
+		// Should never happen, since the above covers all change types.
+		panic("unsupported change type")
+	}
+}
+
+// resolve returns the nodes along the given path, starting with the given
+// root node, or an error like that of applyPath if the path does not exist.
+func resolve(root *checkNode, path cty.Path) ([]*checkNode, error) {
+	chain := make([]*checkNode, 1, len(path)+2)
+	chain[0] = root
+	for i, step := range path {
+		next, err := chain[i].child(step)
+		if err != nil {
+			return nil, path[:i+1].NewError(err)
+		}
+		chain = append(chain, next)
+	}
+	return chain, nil
+}
+
+// checkTypes returns an error if a change to the last of the given nodes,
+// which are along the given path, has changed its type in a way that
+// leaves it nested inside an element of a collection whose element type
+// it no longer matches. Apply would fail when rebuilding that collection.
+func checkTypes(chain []*checkNode, path cty.Path) error {
+	for i := len(chain) - 1; i > 0; i-- {
+		ty := chain[i-1].val.Type()
+		if !ty.IsCollectionType() {
+			// The type of a structural value follows its elements, so
+			// we must look further up.
+			continue
+		}
+		if ety, got := ty.ElementType(), chain[i].typ(); !got.Equals(ety) {
+			return path[:i].NewErrorf("value must be a %s, not a %s", ety.FriendlyName(), got.FriendlyName())
+		}
+		return nil
+	}
+	return nil
+}
+
+// checkNode represents a value as Check works through a diff. It records
+// the changes made inside the value as nodes for the parts of it that
+// changes have touched, so that the value itself need not be rebuilt.
+type checkNode struct {
+	// val is the value before any of the changes recorded in attrs and
+	// elems.
+	val cty.Value
+
+	// attrs holds the nodes for object attributes and map elements that
+	// changes have touched, keyed by name.
+	attrs map[string]*checkNode
+
+	// elems holds a node for every element of a list, tuple or set, once
+	// a change has touched any of them, in their current order. It is nil
+	// until then.
+	elems []*checkNode
+
+	// deleted is true for an object attribute or map element that has
+	// been deleted.
+	deleted bool
+}
+
+// value returns the current value represented by the node.
+func (n *checkNode) value() cty.Value {
+	if n.attrs == nil && n.elems == nil {
+		return n.val
+	}
+	ty := n.val.Type()
+	switch {
+	case ty.IsObjectType() || ty.IsMapType():
+		kv := n.val.AsValueMap()
+		if kv == nil {
+			kv = make(map[string]cty.Value)
+		}
+		for k, c := range n.attrs {
+			if c.deleted {
+				delete(kv, k)
+			} else {
+				kv[k] = c.value()
+			}
+		}
+		if ty.IsObjectType() {
+			return cty.ObjectVal(kv)
+		}
+		if len(kv) == 0 {
+			return cty.MapValEmpty(ty.ElementType())
+		}
+		return cty.MapVal(kv)
+	default:
+		vals := make([]cty.Value, len(n.elems))
+		for i, c := range n.elems {
+			vals[i] = c.value()
+		}
+		if !ty.IsSetType() {
+			return sequenceVal(ty, vals)
+		}
+		if len(vals) == 0 {
+			return cty.SetValEmpty(ty.ElementType())
+		}
+		return cty.SetVal(vals)
+	}
+}
+
+// typ returns the type of the current value represented by the node,
+// without building the value.
+func (n *checkNode) typ() cty.Type {
+	ty := n.val.Type()
+	switch {
+	case n.attrs != nil && ty.IsObjectType():
+		atys := make(map[string]cty.Type)
+		for k, aty := range ty.AttributeTypes() {
+			atys[k] = aty
+		}
+		for k, c := range n.attrs {
+			if c.deleted {
+				delete(atys, k)
+			} else {
+				atys[k] = c.typ()
+			}
+		}
+		return cty.Object(atys)
+	case n.elems != nil && ty.IsTupleType():
+		etys := make([]cty.Type, len(n.elems))
+		for i, c := range n.elems {
+			etys[i] = c.typ()
+		}
+		return cty.Tuple(etys)
+	}
+	return ty
+}
+
+// child returns the node for the element of the value that the given step
+// selects, with the same errors as applyStep.
+func (n *checkNode) child(step cty.PathStep) (*checkNode, error) {
+	if err := requireKnown(n.val); err != nil {
+		return nil, err
+	}
+	ty := n.val.Type()
+
+	switch step := step.(type) {
+	case cty.GetAttrStep:
+		if !ty.IsObjectType() {
+			return nil, fmt.Errorf("cannot access attribute %q on a value of type %s", step.Name, ty.FriendlyName())
+		}
+		if c, ok := n.attrs[step.Name]; ok && !c.deleted {
+			return c, nil
+		}
+		if _, ok := n.attrs[step.Name]; ok || !ty.HasAttribute(step.Name) {
+			return nil, fmt.Errorf("object has no attribute %q", step.Name)
+		}
+		return n.storeAttr(step.Name, n.val.GetAttr(step.Name)), nil
+	case cty.IndexStep:
+		switch {
+		case ty.IsListType() || ty.IsTupleType():
+			n.loadElems()
+			idx, err := listIndex(step.Key, len(n.elems))
+			if err != nil {
+				return nil, err
+			}
+			return n.elems[idx], nil
+		case ty.IsMapType():
+			key, err := mapKey(step.Key)
+			if err != nil {
+				return nil, err
+			}
+			if c, ok := n.attrs[key]; ok && !c.deleted {
+				return c, nil
+			}
+			if _, ok := n.attrs[key]; ok || !n.val.HasIndex(cty.StringVal(key)).True() {
+				return nil, fmt.Errorf("map has no element with key %q", key)
+			}
+			return n.storeAttr(key, n.val.Index(cty.StringVal(key))), nil
+		case ty.IsSetType():
+			// Set elements are addressed by their own value.
+			if err := requireElementType(ty, step.Key); err != nil {
+				return nil, fmt.Errorf("invalid set element: %s", err)
+			}
+			idx := n.member(step.Key)
+			if idx < 0 {
+				return nil, errors.New("set does not contain the given element")
+			}
+			return n.elems[idx], nil
+		default:
+			return nil, fmt.Errorf("cannot index a value of type %s", ty.FriendlyName())
+		}
+	default:
+		return nil, fmt.Errorf("unsupported path step %T", step)
+	}
+}
+
+// storeAttr records a node with the given value for the given attribute
+// or map element, and returns it.
+func (n *checkNode) storeAttr(name string, val cty.Value) *checkNode {
+	if n.attrs == nil {
+		n.attrs = make(map[string]*checkNode)
+	}
+	c := &checkNode{val: val}
+	n.attrs[name] = c
+	return c
+}
+
+// loadElems populates elems from the value, if it has not been already.
+func (n *checkNode) loadElems() {
+	if n.elems != nil {
+		return
+	}
+	n.elems = make([]*checkNode, 0, n.val.LengthInt())
+	for it := n.val.ElementIterator(); it.Next(); {
+		_, v := it.Element()
+		n.elems = append(n.elems, &checkNode{val: v})
+	}
+}
+
+// member returns the index in elems of a member of a set equal to the
+// given value, or -1 if there is none. As for cty's own sets, unknown
+// values are not equal to anything.
+func (n *checkNode) member(val cty.Value) int {
+	n.loadElems()
+	for i, c := range n.elems {
+		if eq := c.value().Equals(val); eq.IsKnown() && eq.True() {
+			return i
+		}
+	}
+	return -1
+}
+
+// mapElem returns the node for the given map element, adding one if the
+// element does not exist.
+func (n *checkNode) mapElem(key string) *checkNode {
+	if c, ok := n.attrs[key]; ok {
+		return c
+	}
+	return n.storeAttr(key, cty.NilVal)
+}
+
+// insert records the insertion of a new element by the given change into
+// the list or tuple that the node represents, with the same errors as the
+// change's apply method.
+func (n *checkNode) insert(c InsertChange) error {
+	if err := requireKnown(n.val); err != nil {
+		return c.Path.NewErrorf("cannot insert into this value: %s", err)
+	}
+	ty := n.val.Type()
+	if !(ty.IsListType() || ty.IsTupleType()) {
+		return c.Path.NewErrorf("value is not a list or tuple")
+	}
+	if err := requireElementType(ty, c.NewValue); err != nil {
+		return c.Path.NewError(err)
+	}
+	n.loadElems()
+
+	var idx int
+	if c.ElementPath {
+		var err error
+		idx, err = listIndex(c.Path[len(c.Path)-1].(cty.IndexStep).Key, len(n.elems)+1)
+		if err != nil {
+			return c.Path.NewError(err)
+		}
+		switch {
+		case c.BeforeValue == cty.NilVal:
+			// Unchecked.
+		case idx == len(n.elems):
+			if !c.BeforeValue.IsNull() {
+				return c.Path.NewErrorf("before value does not exist")
+			}
+		case !rawEquals(n.elems[idx].value(), c.BeforeValue):
+			return c.Path.NewErrorf("before value does not match")
+		}
+	} else {
+		switch {
+		case c.BeforeValue == cty.NilVal:
+			idx = len(n.elems)
+		case len(n.elems) == 0 && c.BeforeValue.IsNull():
+			if ty.IsListType() && !c.BeforeValue.Type().Equals(ty.ElementType()) {
+				return c.Path.NewErrorf("before value must be a %s", ty.ElementType().FriendlyName())
+			}
+		default:
+			idx = -1
+			for i, e := range n.elems {
+				if rawEquals(e.value(), c.BeforeValue) {
+					idx = i
+					break
+				}
+			}
+			if idx < 0 {
+				return c.Path.NewErrorf("before value does not exist")
+			}
+		}
+	}
+
+	elems := make([]*checkNode, 0, len(n.elems)+1)
+	elems = append(elems, n.elems[:idx]...)
+	elems = append(elems, &checkNode{val: c.NewValue})
+	n.elems = append(elems, n.elems[idx:]...)
+	return nil
+}
+
+// checkReplaceStep returns the error that replaceStep would return for
+// replacing an element of a value of the given type with the given value,
+// other than for an element that does not exist.
+func checkReplaceStep(ty cty.Type, step cty.PathStep, new cty.Value) error {
+	if new == cty.NilVal {
+		return errors.New("new value is missing")
+	}
+	if ty.IsMapType() {
+		index, ok := step.(cty.IndexStep)
+		if !ok {
+			return errors.New("map elements must be selected by key")
+		}
+		if _, err := mapKey(index.Key); err != nil {
+			return err
+		}
+	}
+	return requireElementType(ty, new)
+}
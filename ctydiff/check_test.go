@@ -0,0 +1,208 @@
+package ctydiff
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestDiffCheck(t *testing.T) {
+	source := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("a"),
+		"list": cty.ListVal([]cty.Value{cty.StringVal("x"), cty.StringVal("y")}),
+		"set":  cty.SetVal([]cty.Value{cty.StringVal("p")}),
+		"objs": cty.ListVal([]cty.Value{
+			cty.ObjectVal(map[string]cty.Value{"id": cty.StringVal("o")}),
+		}),
+	})
+	obj := func(id string) cty.Value {
+		return cty.ObjectVal(map[string]cty.Value{"id": cty.StringVal(id)})
+	}
+
+	tests := []struct {
+		name string
+		diff Diff
+		want []string
+	}{
+		{
+			"Clean",
+			NewDiff(source, cty.ObjectVal(map[string]cty.Value{
+				"name": cty.StringVal("b"),
+				"list": cty.ListVal([]cty.Value{cty.StringVal("y")}),
+				"set":  cty.SetVal([]cty.Value{cty.StringVal("q")}),
+				"objs": cty.ListVal([]cty.Value{obj("o")}),
+			})),
+			nil,
+		},
+		{
+			"ListShifts",
+			Diff{
+				InsertChange{
+					Path:        cty.GetAttrPath("list"),
+					NewValue:    cty.StringVal("w"),
+					BeforeValue: cty.StringVal("x"),
+				},
+				InsertChange{
+					Path:        cty.GetAttrPath("list").Index(cty.NumberIntVal(3)),
+					NewValue:    cty.StringVal("z"),
+					BeforeValue: cty.NullVal(cty.String),
+					ElementPath: true,
+				},
+				DeleteChange{
+					Path:     cty.GetAttrPath("list").Index(cty.NumberIntVal(1)),
+					OldValue: cty.StringVal("x"),
+				},
+				Context{
+					Path:      cty.GetAttrPath("list").Index(cty.NumberIntVal(1)),
+					WantValue: cty.StringVal("y"),
+				},
+				Context{
+					Path:      cty.GetAttrPath("list"),
+					WantValue: cty.ListVal([]cty.Value{cty.StringVal("w"), cty.StringVal("y"), cty.StringVal("z")}),
+				},
+				// The deletion above means that there is no element here.
+				DeleteChange{
+					Path:     cty.GetAttrPath("list").Index(cty.NumberIntVal(3)),
+					OldValue: cty.StringVal("z"),
+				},
+			},
+			[]string{
+				".list[3]: path does not exist in value: list index 3 is out of range",
+			},
+		},
+		{
+			"SetMembership",
+			Diff{
+				AddChange{
+					Path:     cty.GetAttrPath("set"),
+					NewValue: cty.StringVal("q"),
+				},
+				RemoveChange{
+					Path:     cty.GetAttrPath("set"),
+					OldValue: cty.StringVal("p"),
+				},
+				RemoveChange{
+					Path:     cty.GetAttrPath("set"),
+					OldValue: cty.StringVal("q"),
+				},
+				RemoveChange{
+					Path:     cty.GetAttrPath("set"),
+					OldValue: cty.StringVal("p"),
+				},
+				Context{
+					Path:      cty.GetAttrPath("set"),
+					WantValue: cty.SetValEmpty(cty.String),
+				},
+			},
+			[]string{
+				".set: old value does not exist",
+			},
+		},
+		{
+			"NestedConflict",
+			Diff{
+				NestedDiff{
+					Path:     cty.GetAttrPath("objs").Index(cty.NumberIntVal(0)),
+					OldValue: obj("p"),
+					Diff: Diff{
+						ReplaceChange{
+							Path:     cty.GetAttrPath("id"),
+							OldValue: cty.StringVal("x"),
+							NewValue: cty.StringVal("q"),
+						},
+					},
+				},
+				NestedDiff{
+					Path:     cty.GetAttrPath("objs").Index(cty.NumberIntVal(0)),
+					OldValue: obj("o"),
+					Diff: Diff{
+						ReplaceChange{
+							Path:     cty.GetAttrPath("id"),
+							OldValue: cty.StringVal("x"),
+							NewValue: cty.StringVal("q"),
+						},
+					},
+				},
+			},
+			[]string{
+				".objs[0]: existing value does not match",
+				".objs[0].id: existing value does not match",
+			},
+		},
+		{
+			"ElementType",
+			Diff{
+				// The list's element type cannot change.
+				ReplaceChange{
+					Path:     cty.GetAttrPath("objs").Index(cty.NumberIntVal(0)).GetAttr("id"),
+					OldValue: cty.StringVal("o"),
+					NewValue: cty.True,
+				},
+				ReplaceChange{
+					Path:     cty.GetAttrPath("objs").Index(cty.NumberIntVal(0)).GetAttr("id"),
+					OldValue: cty.StringVal("o"),
+					NewValue: cty.StringVal("p"),
+				},
+				Context{
+					Path:      cty.GetAttrPath("objs"),
+					WantValue: cty.ListVal([]cty.Value{obj("p")}),
+				},
+			},
+			[]string{
+				".objs[0].id: value must be a object, not a object",
+			},
+		},
+		{
+			"Conflicts",
+			Diff{
+				ReplaceChange{
+					Path:     cty.GetAttrPath("name"),
+					OldValue: cty.StringVal("z"),
+					NewValue: cty.StringVal("b"),
+				},
+				Context{
+					Path:      cty.GetAttrPath("list").Index(cty.NumberIntVal(0)),
+					WantValue: cty.StringVal("x"),
+				},
+				DeleteChange{
+					Path:     cty.GetAttrPath("list").Index(cty.NumberIntVal(0)),
+					OldValue: cty.StringVal("x"),
+				},
+				// The deletion above means that this no longer matches.
+				Context{
+					Path:      cty.GetAttrPath("list").Index(cty.NumberIntVal(1)),
+					WantValue: cty.StringVal("y"),
+				},
+				InsertChange{
+					Path:        cty.GetAttrPath("list").Index(cty.NumberIntVal(0)),
+					NewValue:    cty.StringVal("w"),
+					BeforeValue: cty.StringVal("x"),
+					ElementPath: true,
+				},
+				RemoveChange{
+					Path:     cty.GetAttrPath("set"),
+					OldValue: cty.StringVal("q"),
+				},
+			},
+			[]string{
+				".name: existing value does not match",
+				".list[1]: path does not exist in value: list index 1 is out of range",
+				".list[0]: before value does not match",
+				".set: old value does not exist",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, c := range tt.diff.Check(source) {
+				got = append(got, FormatPath(c.Path)+": "+c.Err.Error())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wrong conflicts\ngot:  %q\nwant: %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package ctydiff
 
 import (
+	"fmt"
+
 	"github.com/zclconf/go-cty/cty"
 )
 
@@ -28,15 +30,62 @@ type Diff []Change
 // still attempt to construct such a diff since it may still be useful to
 // display to a user.
 func NewDiff(source, target cty.Value) Diff {
-	panic("NewDiff is not yet implemented")
+	return NewDiffWithOptions(source, target, DiffOptions{})
+}
+
+// DiffOptions represents optional settings for NewDiffWithOptions.
+type DiffOptions struct {
+	// Ignore is a set of patterns for paths that should not be compared.
+	// No changes are produced for a path matched by any of these patterns,
+	// or for anything nested inside it, and list and set elements that
+	// differ only at ignored paths are considered to be equal.
+	//
+	// Since the ignored parts of the target value are not represented in
+	// the diff, applying the diff to the source value will retain the
+	// source value's versions of them.
+	Ignore []PathPattern
+
+	// ContextRadius, if greater than zero, is the greatest number of
+	// unchanged list elements to report as Context changes either side of
+	// each run of changes to a list, like the -U option of diff(1). By
+	// default every unchanged element of a list is reported, which makes
+	// diffs of large lists correspondingly large.
+	ContextRadius int
+
+	// NoContext causes no Context changes to be reported at all, giving the
+	// smallest diff that transforms the source value into the target value.
+	// Such a diff cannot detect when it is applied to a list that has been
+	// changed in other ways, so it is suited to callers that apply diffs
+	// only to the source value they were produced from.
+	NoContext bool
+}
+
+// NewDiffWithOptions is like NewDiff but allows the comparison to be
+// customized using the given options.
+//
+// Values of the same object, map or tuple type are compared element by
+// element. Lists are aligned using a longest-common-subsequence algorithm,
+// with elements that were changed in place compared element by element, and
+// sets are compared by membership. Any other difference, including a
+// difference in type, is represented by a ReplaceChange of the whole value.
+func NewDiffWithOptions(source, target cty.Value, opts DiffOptions) Diff {
+	d := &differ{opts: opts}
+	return d.diffValues(source, target, nil)
 }
 
 // Apply produces a new value by applying the receiving Diff to the given
 // source value. If any one change fails then the entire operation is
 // considered to have failed.
+//
+// Apply returns an error, rather than panicking, for any change that is not
+// valid for the value it is applied to, so it is safe to apply diffs that
+// were constructed from untrusted input.
 func (d Diff) Apply(source cty.Value) (cty.Value, error) {
 	val := source
-	for _, c := range d {
+	for i, c := range d {
+		if c == nil {
+			return cty.NilVal, fmt.Errorf("change %d is nil", i)
+		}
 		v, err := c.apply(val)
 		if err != nil {
 			return cty.NilVal, err
@@ -46,6 +95,156 @@ func (d Diff) Apply(source cty.Value) (cty.Value, error) {
 	return val, nil
 }
 
+// Walk calls the given function for each change in the receiver, in order,
+// along with the absolute path of the change. When a change is a NestedDiff,
+// the function is called for the NestedDiff itself and then for each of the
+// changes in its nested diff, whose absolute paths are the concatenation of
+// the NestedDiff's path and their own.
+//
+// If the given function returns an error then the walk stops and Walk
+// returns that error.
+//
+// The path passed to the function is a fresh copy that the function may
+// retain.
+func (d Diff) Walk(fn func(absPath cty.Path, c Change) error) error {
+	return d.walk(nil, fn)
+}
+
+func (d Diff) walk(prefix cty.Path, fn func(cty.Path, Change) error) error {
+	for _, c := range d {
+		if c == nil {
+			continue
+		}
+		absPath := joinPath(prefix, c.path())
+		if err := fn(absPath, c); err != nil {
+			return err
+		}
+		if nested, ok := c.(NestedDiff); ok {
+			if err := nested.Diff.walk(absPath, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Flatten returns a diff equivalent to the receiver where NestedDiff changes
+// are replaced with their nested changes, using absolute paths. The old
+// value recorded in each expanded NestedDiff is retained as a Context change
+// so that the result fails under the same conditions as the original.
+//
+// A NestedDiff whose path selects a set element by a value other than a
+// number or string cannot be expanded, because the first nested change
+// would alter the element and so the remaining changes would no longer be
+// able to address it. Such NestedDiffs are retained, with their own nested
+// diffs flattened.
+func (d Diff) Flatten() Diff {
+	return d.flatten(nil)
+}
+
+func (d Diff) flatten(prefix cty.Path) Diff {
+	var ret Diff
+	for _, c := range d {
+		if c == nil {
+			continue
+		}
+		nested, ok := c.(NestedDiff)
+		if !ok {
+			ret = append(ret, c.withPath(joinPath(prefix, c.path())))
+			continue
+		}
+		if !canFlattenPath(nested.Path) {
+			nested.Path = joinPath(prefix, nested.Path)
+			nested.Diff = nested.Diff.flatten(nil)
+			ret = append(ret, nested)
+			continue
+		}
+		absPath := joinPath(prefix, nested.Path)
+		ret = append(ret, Context{
+			Path:      absPath,
+			WantValue: nested.OldValue,
+		})
+		ret = append(ret, nested.Diff.flatten(absPath)...)
+	}
+	return ret
+}
+
+// Filter returns a copy of the receiver containing only the changes whose
+// absolute paths are matched by at least one of the include patterns and by
+// none of the exclude patterns. If include is empty then all changes not
+// matched by an exclude pattern are retained.
+//
+// Since a pattern also matches paths nested within the path it matches,
+// excluding a path also excludes changes nested inside it. Changes are
+// selected only by their own paths, so a change to a value containing a
+// matched path is retained or excluded based on the path of that value.
+//
+// NestedDiff changes are filtered recursively, and are removed altogether
+// if none of their nested changes remain.
+//
+// Removing some of the changes to a list may cause the indices in the
+// remaining changes to that list to become incorrect, so callers should
+// generally avoid filtering individual list elements.
+func (d Diff) Filter(include, exclude []PathPattern) Diff {
+	return d.filter(nil, include, exclude)
+}
+
+func (d Diff) filter(prefix cty.Path, include, exclude []PathPattern) Diff {
+	var ret Diff
+	for _, c := range d {
+		if c == nil {
+			continue
+		}
+		absPath := joinPath(prefix, c.path())
+		if matchAny(exclude, absPath) {
+			continue
+		}
+		included := len(include) == 0 || matchAny(include, absPath)
+		nested, ok := c.(NestedDiff)
+		if !ok {
+			if included {
+				ret = append(ret, c)
+			}
+			continue
+		}
+		if len(nested.Diff) == 0 {
+			if included {
+				ret = append(ret, c)
+			}
+			continue
+		}
+		nested.Diff = nested.Diff.filter(absPath, include, exclude)
+		if len(nested.Diff) > 0 {
+			ret = append(ret, nested)
+		}
+	}
+	return ret
+}
+
+// canFlattenPath returns false if the given path might select a set element
+// by a value whose identity would be changed by a nested diff.
+func canFlattenPath(path cty.Path) bool {
+	for _, step := range path {
+		index, ok := step.(cty.IndexStep)
+		if !ok || index.Key == cty.NilVal {
+			continue
+		}
+		ty := index.Key.Type()
+		if !(ty.Equals(cty.Number) || ty.Equals(cty.String)) {
+			return false
+		}
+	}
+	return true
+}
+
+// joinPath returns a new path that is the concatenation of the two given
+// paths.
+func joinPath(prefix, path cty.Path) cty.Path {
+	ret := make(cty.Path, 0, len(prefix)+len(path))
+	ret = append(ret, prefix...)
+	return append(ret, path...)
+}
+
 // Replace returns a copy of the receiver with a ReplaceChange appended.
 func (d Diff) Replace(path cty.Path, old, new cty.Value) Diff {
 	return d.append(ReplaceChange{
@@ -0,0 +1,241 @@
+package ctydiff
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// listConflictContext is the greatest number of unchanged elements either
+// side of a conflicting hunk that MergeLists reports as its context, like
+// the default for the -U option of diff(1).
+const listConflictContext = 3
+
+// ListConflict describes a hunk of a list that was changed in different ways
+// on the two sides of a three-way list merge.
+type ListConflict struct {
+	// Index is the index in the merged list of the first element of the
+	// hunk, where our side's version of the hunk is placed.
+	Index int
+
+	// Base, Ours and Theirs are the three versions of the hunk, any of
+	// which may be empty.
+	Base   []cty.Value
+	Ours   []cty.Value
+	Theirs []cty.Value
+
+	// Before and After are the elements that are unchanged on both sides
+	// immediately before and after the hunk, up to three of each, to help
+	// locate the hunk within the list.
+	Before []cty.Value
+	After  []cty.Value
+}
+
+// ListMergeError is the error returned by MergeLists when the inputs have
+// overlapping changes.
+type ListMergeError struct {
+	Conflicts []ListConflict
+}
+
+func (e *ListMergeError) Error() string {
+	if len(e.Conflicts) == 1 {
+		return "list merge has 1 conflict"
+	}
+	return fmt.Sprintf("list merge has %d conflicts", len(e.Conflicts))
+}
+
+// MergeLists performs a three-way merge of lists in the manner of diff3(1),
+// combining the changes made to the given base list in ours with those made
+// in theirs. All three values must be known, non-null lists of the same
+// type.
+//
+// The lists are aligned by finding the longest common subsequence of base
+// and each side, which divides them into stable hunks, which are unchanged
+// on both sides, and unstable hunks between them. An unstable hunk that only
+// one side changed, or that both sides changed in the same way, has the
+// changed elements in the result. Any other unstable hunk is a conflict.
+//
+// If there are conflicts then MergeLists returns a *ListMergeError
+// describing them, along with a merged list that has our side's version of
+// each conflicting hunk.
+func MergeLists(base, ours, theirs cty.Value) (cty.Value, error) {
+	for _, v := range []cty.Value{base, ours, theirs} {
+		if err := requireKnown(v); err != nil {
+			return cty.NilVal, err
+		}
+		if !v.Type().IsListType() {
+			return cty.NilVal, errors.New("value is not a list")
+		}
+		if !v.Type().Equals(base.Type()) {
+			return cty.NilVal, fmt.Errorf("values must all be of type %s", base.Type().FriendlyName())
+		}
+	}
+
+	baseEls := base.AsValueSlice()
+	hunks := diff3Hunks(baseEls, ours.AsValueSlice(), theirs.AsValueSlice())
+
+	var elems []cty.Value
+	var conflicts []ListConflict
+	for i, h := range hunks {
+		switch {
+		case h.stable:
+			elems = append(elems, h.base...)
+			continue
+		case elemsEqual(h.ours, h.theirs) || elemsEqual(h.base, h.theirs):
+			elems = append(elems, h.ours...)
+			continue
+		case elemsEqual(h.base, h.ours):
+			elems = append(elems, h.theirs...)
+			continue
+		}
+
+		conflict := ListConflict{
+			Index:  len(elems),
+			Base:   h.base,
+			Ours:   h.ours,
+			Theirs: h.theirs,
+		}
+		// Unstable hunks are always separated by stable ones, which are
+		// the context of the hunks either side of them.
+		if i > 0 {
+			before := hunks[i-1].base
+			if len(before) > listConflictContext {
+				before = before[len(before)-listConflictContext:]
+			}
+			conflict.Before = before
+		}
+		if i < len(hunks)-1 {
+			after := hunks[i+1].base
+			if len(after) > listConflictContext {
+				after = after[:listConflictContext]
+			}
+			conflict.After = after
+		}
+		conflicts = append(conflicts, conflict)
+		elems = append(elems, h.ours...)
+	}
+
+	var ret cty.Value
+	if len(elems) == 0 {
+		ret = cty.ListValEmpty(base.Type().ElementType())
+	} else {
+		ret = cty.ListVal(elems)
+	}
+	if len(conflicts) > 0 {
+		return ret, &ListMergeError{Conflicts: conflicts}
+	}
+	return ret, nil
+}
+
+// diff3Hunk is a hunk of the lists being merged by MergeLists, with each
+// side's version of it.
+type diff3Hunk struct {
+	// stable is true if the hunk is unchanged on both sides, in which case
+	// all three versions are the same.
+	stable bool
+
+	base, ours, theirs []cty.Value
+}
+
+// diff3Hunks divides the given lists into alternating stable and unstable
+// hunks, which together cover every element of each list in order.
+func diff3Hunks(base, ours, theirs []cty.Value) []diff3Hunk {
+	matchOurs := alignElems(base, ours)
+	matchTheirs := alignElems(base, theirs)
+
+	var hunks []diff3Hunk
+	i, a, b := 0, 0, 0
+	for i < len(base) || a < len(ours) || b < len(theirs) {
+		// A stable hunk continues for as long as the next base element is
+		// the next element on both sides.
+		start := i
+		for i < len(base) && matchOurs[i] == a && matchTheirs[i] == b {
+			i++
+			a++
+			b++
+		}
+		if i > start {
+			hunks = append(hunks, diff3Hunk{
+				stable: true,
+				base:   base[start:i],
+				ours:   base[start:i],
+				theirs: base[start:i],
+			})
+			continue
+		}
+
+		// Otherwise, an unstable hunk continues up to the next base element
+		// that is retained on both sides, or to the end of the lists.
+		j := i
+		for j < len(base) && (matchOurs[j] < 0 || matchTheirs[j] < 0) {
+			j++
+		}
+		aEnd, bEnd := len(ours), len(theirs)
+		if j < len(base) {
+			aEnd, bEnd = matchOurs[j], matchTheirs[j]
+		}
+		hunks = append(hunks, diff3Hunk{
+			base:   base[i:j],
+			ours:   ours[a:aEnd],
+			theirs: theirs[b:bEnd],
+		})
+		i, a, b = j, aEnd, bEnd
+	}
+	return hunks
+}
+
+// alignElems returns, for each element of xs, the index of the element of ys
+// that it is aligned with in a longest common subsequence of the two, or -1
+// if it is not in the subsequence.
+func alignElems(xs, ys []cty.Value) []int {
+	ret := make([]int, len(xs))
+	for i := range ret {
+		ret[i] = -1
+	}
+
+	prefix := 0
+	for prefix < len(xs) && prefix < len(ys) && lcsEqual(xs[prefix], ys[prefix]) {
+		ret[prefix] = prefix
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(xs)-prefix && suffix < len(ys)-prefix &&
+		lcsEqual(xs[len(xs)-1-suffix], ys[len(ys)-1-suffix]) {
+		ret[len(xs)-1-suffix] = len(ys) - 1 - suffix
+		suffix++
+	}
+	xEnd := len(xs) - suffix
+	yEnd := len(ys) - suffix
+
+	// The subsequence is made of values rather than indices, but we can
+	// find a valid alignment by matching each of its elements with the
+	// first remaining equal element of each list.
+	lcs := longestCommonSubsequence(xs[prefix:xEnd], ys[prefix:yEnd])
+	x, y := prefix, prefix
+	for _, v := range lcs {
+		for !lcsEqual(xs[x], v) {
+			x++
+		}
+		for !lcsEqual(ys[y], v) {
+			y++
+		}
+		ret[x] = y
+		x++
+		y++
+	}
+	return ret
+}
+
+// elemsEqual returns true if the given slices have identical elements.
+func elemsEqual(a, b []cty.Value) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !rawEquals(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,197 @@
+package ctydiff
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestMergeLists(t *testing.T) {
+	strs := func(ss ...string) cty.Value {
+		if len(ss) == 0 {
+			return cty.ListValEmpty(cty.String)
+		}
+		vals := make([]cty.Value, len(ss))
+		for i, s := range ss {
+			vals[i] = cty.StringVal(s)
+		}
+		return cty.ListVal(vals)
+	}
+
+	tests := []struct {
+		name   string
+		base   cty.Value
+		ours   cty.Value
+		theirs cty.Value
+		want   cty.Value
+	}{
+		{
+			"Unchanged",
+			strs("a", "b"),
+			strs("a", "b"),
+			strs("a", "b"),
+			strs("a", "b"),
+		},
+		{
+			"OursOnly",
+			strs("a", "b", "c"),
+			strs("a", "x", "c"),
+			strs("a", "b", "c"),
+			strs("a", "x", "c"),
+		},
+		{
+			"TheirsOnly",
+			strs("a", "b", "c"),
+			strs("a", "b", "c"),
+			strs("b", "c", "d"),
+			strs("b", "c", "d"),
+		},
+		{
+			"SeparateHunks",
+			strs("a", "b", "c", "d", "e"),
+			strs("x", "a", "b", "c", "d", "e"),
+			strs("a", "b", "c", "y", "e"),
+			strs("x", "a", "b", "c", "y", "e"),
+		},
+		{
+			"DeleteAndAppend",
+			strs("a", "b", "c", "d"),
+			strs("a", "c", "d"),
+			strs("a", "b", "c", "d", "e"),
+			strs("a", "c", "d", "e"),
+		},
+		{
+			"SameChange",
+			strs("a", "b", "c"),
+			strs("a", "x", "y", "c"),
+			strs("a", "x", "y", "c"),
+			strs("a", "x", "y", "c"),
+		},
+		{
+			"FromEmpty",
+			strs(),
+			strs("a"),
+			strs(),
+			strs("a"),
+		},
+		{
+			"ToEmpty",
+			strs("a", "b"),
+			strs("a", "b"),
+			strs(),
+			strs(),
+		},
+		{
+			"Repeated",
+			strs("a", "a", "b", "a"),
+			strs("a", "a", "x", "b", "a"),
+			strs("a", "a", "b", "a", "a"),
+			strs("a", "a", "x", "b", "a", "a"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MergeLists(tt.base, tt.ours, tt.theirs)
+			if err != nil {
+				t.Fatalf("MergeLists() err = %v", err)
+			}
+			if !got.RawEquals(tt.want) {
+				t.Errorf("MergeLists\nGot\n%#v\nWant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeLists_conflicts(t *testing.T) {
+	strs := func(ss ...string) []cty.Value {
+		vals := make([]cty.Value, len(ss))
+		for i, s := range ss {
+			vals[i] = cty.StringVal(s)
+		}
+		return vals
+	}
+	base := cty.ListVal(strs("a", "b", "c", "d", "e", "f", "g", "h", "i"))
+	ours := cty.ListVal(strs("x", "b", "c", "d", "e", "f", "o", "h", "i"))
+	theirs := cty.ListVal(strs("y", "b", "c", "d", "e", "f", "h", "i"))
+
+	got, err := MergeLists(base, ours, theirs)
+	mergeErr, ok := err.(*ListMergeError)
+	if !ok {
+		t.Fatalf("MergeLists() err = %#v; want *ListMergeError", err)
+	}
+	if got, want := mergeErr.Error(), "list merge has 2 conflicts"; got != want {
+		t.Errorf("wrong error %q; want %q", got, want)
+	}
+	if !got.RawEquals(ours) {
+		t.Errorf("MergeLists\nGot\n%#v\nWant\n%#v", got, ours)
+	}
+
+	want := []ListConflict{
+		{
+			Index:  0,
+			Base:   strs("a"),
+			Ours:   strs("x"),
+			Theirs: strs("y"),
+			After:  strs("b", "c", "d"),
+		},
+		{
+			Index:  6,
+			Base:   strs("g"),
+			Ours:   strs("o"),
+			Theirs: strs(),
+			Before: strs("d", "e", "f"),
+			After:  strs("h", "i"),
+		},
+	}
+	if !reflect.DeepEqual(mergeErr.Conflicts, want) {
+		t.Errorf("wrong conflicts\n%s", prettyDiff.Compare(want, mergeErr.Conflicts))
+	}
+}
+
+func TestMergeLists_errors(t *testing.T) {
+	list := cty.ListVal([]cty.Value{cty.StringVal("a")})
+
+	tests := []struct {
+		name   string
+		base   cty.Value
+		ours   cty.Value
+		theirs cty.Value
+		want   string
+	}{
+		{
+			"Null",
+			cty.NullVal(list.Type()),
+			list,
+			list,
+			"value is null",
+		},
+		{
+			"NotList",
+			cty.TupleVal([]cty.Value{cty.StringVal("a")}),
+			list,
+			list,
+			"value is not a list",
+		},
+		{
+			"MismatchedTypes",
+			list,
+			list,
+			cty.ListVal([]cty.Value{cty.True}),
+			"values must all be of type list of string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := MergeLists(tt.base, tt.ours, tt.theirs)
+			if err == nil {
+				t.Fatalf("MergeLists() succeeded; want error")
+			}
+			if got := err.Error(); got != tt.want {
+				t.Errorf("wrong error\ngot:  %s\nwant: %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -9,8 +9,6 @@ import (
 // direct list members, even if they are themselves collection- or
 // structural-typed values.
 func diffListsShallow(old cty.Value, new cty.Value, path cty.Path) Diff {
-	var diff Diff
-
 	oldEls := make([]cty.Value, 0, old.LengthInt())
 	newEls := make([]cty.Value, 0, old.LengthInt())
 	it := old.ElementIterator()
@@ -24,22 +22,63 @@ func diffListsShallow(old cty.Value, new cty.Value, path cty.Path) Diff {
 		newEls = append(newEls, v)
 	}
 
-	lcs := longestCommonSubsequence(oldEls, newEls)
-	op := 0        // position in "old"
-	np := 0        // position in "new"
+	return diffElemsShallow(oldEls, newEls, old.Type().ElementType(), path)
+}
+
+// diffElemsShallow is the main implementation of diffListsShallow, working
+// on slices of elements of the given element type so that callers can
+// substitute their own versions of the elements to be compared.
+//
+// Lists usually differ only in a small region, so we skip over any common
+// leading and trailing elements before aligning the rest, which makes the
+// common case of a small edit to a large list nearly linear. The skipped
+// elements are still reported as Context changes.
+func diffElemsShallow(oldEls, newEls []cty.Value, ety cty.Type, path cty.Path) Diff {
+	var diff Diff
+
+	prefix := 0
+	for prefix < len(oldEls) && prefix < len(newEls) && lcsEqual(oldEls[prefix], newEls[prefix]) {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldEls)-prefix && suffix < len(newEls)-prefix &&
+		lcsEqual(oldEls[len(oldEls)-1-suffix], newEls[len(newEls)-1-suffix]) {
+		suffix++
+	}
+	oldEnd := len(oldEls) - suffix
+	newEnd := len(newEls) - suffix
+
+	lcs := longestCommonSubsequence(oldEls[prefix:oldEnd], newEls[prefix:newEnd])
+	op := prefix   // position in "old"
+	np := prefix   // position in "new"
 	cp := 0        // position in "lcs"
 	ip := int64(0) // current index for diff changes
 
 	path = append(path, nil)
 	step := &path[len(path)-1]
 
-	for op < len(oldEls) || np < len(newEls) {
+	context := func(v cty.Value) {
+		*step = cty.IndexStep{
+			Key: cty.NumberIntVal(ip),
+		}
+		diff = append(diff, Context{
+			Path:      path.Copy(),
+			WantValue: v,
+		})
+		ip++
+	}
+
+	for _, v := range oldEls[:prefix] {
+		context(v)
+	}
+
+	for op < oldEnd || np < newEnd {
 
 		// We want to produce blocks of removes, adds, and contexts
 		// until we run out of items.
 
 		// Elements unique to old are deleted
-		for op < len(oldEls) {
+		for op < oldEnd {
 			if cp < len(lcs) {
 				eq := oldEls[op].Equals(lcs[cp])
 				if eq.IsKnown() && eq.True() {
@@ -57,7 +96,7 @@ func diffListsShallow(old cty.Value, new cty.Value, path cty.Path) Diff {
 		}
 
 		// Elements unique to new are inserted
-		for np < len(newEls) {
+		for np < newEnd {
 			if cp < len(lcs) {
 				eq := newEls[np].Equals(lcs[cp])
 				if eq.IsKnown() && eq.True() {
@@ -71,12 +110,13 @@ func diffListsShallow(old cty.Value, new cty.Value, path cty.Path) Diff {
 			if op < len(oldEls) {
 				beforeVal = oldEls[op]
 			} else {
-				beforeVal = cty.NullVal(old.Type().ElementType())
+				beforeVal = cty.NullVal(ety)
 			}
 			diff = append(diff, InsertChange{
 				Path:        path.Copy(),
 				NewValue:    newEls[np],
 				BeforeValue: beforeVal,
+				ElementPath: true,
 			})
 			np++
 			ip++
@@ -85,7 +125,7 @@ func diffListsShallow(old cty.Value, new cty.Value, path cty.Path) Diff {
 		// Elements common to both are context.
 		// For this loop we'll advance all three pointers at once because
 		// we expect to be walking through the same elements in all three.
-		for cp < len(lcs) && op < len(oldEls) && np < len(newEls) {
+		for cp < len(lcs) && op < oldEnd && np < newEnd {
 			oeq := oldEls[op].Equals(lcs[cp])
 			if !(oeq.IsKnown() && oeq.True()) {
 				break
@@ -95,21 +135,18 @@ func diffListsShallow(old cty.Value, new cty.Value, path cty.Path) Diff {
 				break
 			}
 
-			*step = cty.IndexStep{
-				Key: cty.NumberIntVal(ip),
-			}
-			diff = append(diff, Context{
-				Path:      path.Copy(),
-				WantValue: lcs[cp],
-			})
+			context(lcs[cp])
 
 			cp++
 			op++
 			np++
-			ip++
 		}
 
 	}
 
+	for _, v := range oldEls[oldEnd:] {
+		context(v)
+	}
+
 	return diff
 }
@@ -61,6 +61,7 @@ func TestDiffListsShallow(t *testing.T) {
 					},
 					NewValue:    cty.NumberIntVal(1),
 					BeforeValue: cty.NullVal(cty.Number),
+					ElementPath: true,
 				},
 			},
 		},
@@ -74,6 +75,7 @@ func TestDiffListsShallow(t *testing.T) {
 					},
 					NewValue:    cty.NumberIntVal(1),
 					BeforeValue: cty.NullVal(cty.Number),
+					ElementPath: true,
 				},
 				InsertChange{
 					Path: cty.Path{
@@ -81,6 +83,7 @@ func TestDiffListsShallow(t *testing.T) {
 					},
 					NewValue:    cty.NumberIntVal(2),
 					BeforeValue: cty.NullVal(cty.Number),
+					ElementPath: true,
 				},
 			},
 		},
@@ -112,6 +115,7 @@ func TestDiffListsShallow(t *testing.T) {
 					},
 					NewValue:    cty.NumberIntVal(1),
 					BeforeValue: cty.NullVal(cty.Number),
+					ElementPath: true,
 				},
 			},
 		},
@@ -125,6 +129,7 @@ func TestDiffListsShallow(t *testing.T) {
 					},
 					NewValue:    cty.NumberIntVal(1),
 					BeforeValue: cty.NumberIntVal(2),
+					ElementPath: true,
 				},
 				Context{
 					Path: cty.Path{
@@ -179,6 +184,7 @@ func TestDiffListsShallow(t *testing.T) {
 					},
 					NewValue:    cty.NumberIntVal(2),
 					BeforeValue: cty.NumberIntVal(4),
+					ElementPath: true,
 				},
 				InsertChange{
 					Path: cty.Path{
@@ -186,6 +192,7 @@ func TestDiffListsShallow(t *testing.T) {
 					},
 					NewValue:    cty.NumberIntVal(3),
 					BeforeValue: cty.NumberIntVal(4),
+					ElementPath: true,
 				},
 				Context{
 					Path: cty.Path{
@@ -199,6 +206,7 @@ func TestDiffListsShallow(t *testing.T) {
 					},
 					NewValue:    cty.NumberIntVal(5),
 					BeforeValue: cty.NumberIntVal(6),
+					ElementPath: true,
 				},
 				Context{
 					Path: cty.Path{
@@ -240,6 +248,7 @@ func TestDiffListsShallow(t *testing.T) {
 					},
 					NewValue:    cty.NumberIntVal(2),
 					BeforeValue: cty.NumberIntVal(6),
+					ElementPath: true,
 				},
 				InsertChange{
 					Path: cty.Path{
@@ -247,6 +256,7 @@ func TestDiffListsShallow(t *testing.T) {
 					},
 					NewValue:    cty.NumberIntVal(3),
 					BeforeValue: cty.NumberIntVal(6),
+					ElementPath: true,
 				},
 				InsertChange{
 					Path: cty.Path{
@@ -254,6 +264,7 @@ func TestDiffListsShallow(t *testing.T) {
 					},
 					NewValue:    cty.NumberIntVal(5),
 					BeforeValue: cty.NumberIntVal(6),
+					ElementPath: true,
 				},
 				Context{
 					Path: cty.Path{
@@ -309,6 +320,73 @@ func TestDiffListsShallow(t *testing.T) {
 				},
 			},
 		},
+		{
+			// Common leading elements are trimmed before alignment, but
+			// still reported as context.
+			[]cty.Value{cty.NumberIntVal(1), cty.NumberIntVal(2), cty.NumberIntVal(3)},
+			[]cty.Value{cty.NumberIntVal(1), cty.NumberIntVal(2), cty.NumberIntVal(3), cty.NumberIntVal(4)},
+			Diff{
+				Context{
+					Path: cty.Path{
+						cty.IndexStep{Key: cty.NumberIntVal(0)},
+					},
+					WantValue: cty.NumberIntVal(1),
+				},
+				Context{
+					Path: cty.Path{
+						cty.IndexStep{Key: cty.NumberIntVal(1)},
+					},
+					WantValue: cty.NumberIntVal(2),
+				},
+				Context{
+					Path: cty.Path{
+						cty.IndexStep{Key: cty.NumberIntVal(2)},
+					},
+					WantValue: cty.NumberIntVal(3),
+				},
+				InsertChange{
+					Path: cty.Path{
+						cty.IndexStep{Key: cty.NumberIntVal(3)},
+					},
+					NewValue:    cty.NumberIntVal(4),
+					BeforeValue: cty.NullVal(cty.Number),
+					ElementPath: true,
+				},
+			},
+		},
+		{
+			// Common leading and trailing elements are trimmed.
+			[]cty.Value{cty.NumberIntVal(1), cty.NumberIntVal(2), cty.NumberIntVal(3)},
+			[]cty.Value{cty.NumberIntVal(1), cty.NumberIntVal(4), cty.NumberIntVal(3)},
+			Diff{
+				Context{
+					Path: cty.Path{
+						cty.IndexStep{Key: cty.NumberIntVal(0)},
+					},
+					WantValue: cty.NumberIntVal(1),
+				},
+				DeleteChange{
+					Path: cty.Path{
+						cty.IndexStep{Key: cty.NumberIntVal(1)},
+					},
+					OldValue: cty.NumberIntVal(2),
+				},
+				InsertChange{
+					Path: cty.Path{
+						cty.IndexStep{Key: cty.NumberIntVal(1)},
+					},
+					NewValue:    cty.NumberIntVal(4),
+					BeforeValue: cty.NumberIntVal(3),
+					ElementPath: true,
+				},
+				Context{
+					Path: cty.Path{
+						cty.IndexStep{Key: cty.NumberIntVal(2)},
+					},
+					WantValue: cty.NumberIntVal(3),
+				},
+			},
+		},
 	}
 
 	pr := &pretty.Config{
@@ -343,3 +421,21 @@ func TestDiffListsShallow(t *testing.T) {
 		})
 	}
 }
+
+func BenchmarkDiffListsShallow_smallEdit(b *testing.B) {
+	// Without trimming the common leading and trailing elements, aligning
+	// lists of this size would compare every pair of elements.
+	elems := make([]cty.Value, 20000)
+	for i := range elems {
+		elems[i] = cty.NumberIntVal(int64(i))
+	}
+	old := cty.ListVal(elems)
+	edited := append([]cty.Value(nil), elems...)
+	edited[10000] = cty.NumberIntVal(-1)
+	new := cty.ListVal(edited)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		diffListsShallow(old, new, nil)
+	}
+}
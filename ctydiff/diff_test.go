@@ -1,6 +1,8 @@
 package ctydiff
 
 import (
+	"errors"
+	"reflect"
 	"testing"
 
 	"github.com/zclconf/go-cty/cty"
@@ -74,6 +76,24 @@ func TestDiff_Apply(t *testing.T) {
 			cty.ListVal([]cty.Value{cty.StringVal("A"), cty.StringVal("B"), cty.StringVal("C")}),
 			cty.ListVal([]cty.Value{cty.StringVal("A"), cty.StringVal("X"), cty.StringVal("C")}),
 		},
+		{
+			"ReplaceNested",
+			Diff{
+				ReplaceChange{
+					Path:     cty.GetAttrPath("a").Index(cty.NumberIntVal(0)),
+					OldValue: cty.StringVal("A"),
+					NewValue: cty.StringVal("B"),
+				},
+			},
+			cty.ObjectVal(map[string]cty.Value{
+				"a": cty.ListVal([]cty.Value{cty.StringVal("A")}),
+				"b": cty.StringVal("B"),
+			}),
+			cty.ObjectVal(map[string]cty.Value{
+				"a": cty.ListVal([]cty.Value{cty.StringVal("B")}),
+				"b": cty.StringVal("B"),
+			}),
+		},
 		{
 			"ReplaceTuple",
 			Diff{
@@ -121,6 +141,28 @@ func TestDiff_Apply(t *testing.T) {
 			cty.ListVal([]cty.Value{cty.StringVal("A"), cty.StringVal("B"), cty.StringVal("C")}),
 			cty.ListVal([]cty.Value{cty.StringVal("A"), cty.StringVal("C")}),
 		},
+		{
+			"DeleteListLast",
+			Diff{
+				DeleteChange{
+					Path:     cty.IndexPath(cty.NumberIntVal(0)),
+					OldValue: cty.StringVal("A"),
+				},
+			},
+			cty.ListVal([]cty.Value{cty.StringVal("A")}),
+			cty.ListValEmpty(cty.String),
+		},
+		{
+			"DeleteMapLast",
+			Diff{
+				DeleteChange{
+					Path:     cty.IndexPath(cty.StringVal("a")),
+					OldValue: cty.StringVal("A"),
+				},
+			},
+			cty.MapVal(map[string]cty.Value{"a": cty.StringVal("A")}),
+			cty.MapValEmpty(cty.String),
+		},
 		{
 			"DeleteTuple",
 			Diff{
@@ -158,6 +200,54 @@ func TestDiff_Apply(t *testing.T) {
 			cty.ListVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b"), cty.StringVal("a")}),
 			cty.ListVal([]cty.Value{cty.StringVal("x"), cty.StringVal("a"), cty.StringVal("b"), cty.StringVal("a")}),
 		},
+		{
+			"InsertNestedListPath",
+			Diff{
+				InsertChange{
+					Path:        cty.IndexPath(cty.NumberIntVal(0)),
+					NewValue:    cty.StringVal("x"),
+					BeforeValue: cty.StringVal("a"),
+				},
+			},
+			cty.ListVal([]cty.Value{cty.ListVal([]cty.Value{cty.StringVal("a")})}),
+			cty.ListVal([]cty.Value{cty.ListVal([]cty.Value{cty.StringVal("x"), cty.StringVal("a")})}),
+		},
+		{
+			"InsertNestedListIndex",
+			Diff{
+				InsertChange{
+					Path:        cty.IndexPath(cty.NumberIntVal(0)),
+					NewValue:    cty.ListVal([]cty.Value{cty.StringVal("x")}),
+					BeforeValue: cty.ListVal([]cty.Value{cty.StringVal("a")}),
+					ElementPath: true,
+				},
+			},
+			cty.ListVal([]cty.Value{cty.ListVal([]cty.Value{cty.StringVal("a")})}),
+			cty.ListVal([]cty.Value{cty.ListVal([]cty.Value{cty.StringVal("x")}), cty.ListVal([]cty.Value{cty.StringVal("a")})}),
+		},
+		{
+			"InsertListIndex",
+			Diff{
+				InsertChange{
+					Path:        cty.GetAttrPath("l").Index(cty.NumberIntVal(1)),
+					NewValue:    cty.StringVal("x"),
+					BeforeValue: cty.StringVal("b"),
+					ElementPath: true,
+				},
+				InsertChange{
+					Path:        cty.GetAttrPath("l").Index(cty.NumberIntVal(3)),
+					NewValue:    cty.StringVal("y"),
+					BeforeValue: cty.NullVal(cty.String),
+					ElementPath: true,
+				},
+			},
+			cty.ObjectVal(map[string]cty.Value{
+				"l": cty.ListVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+			}),
+			cty.ObjectVal(map[string]cty.Value{
+				"l": cty.ListVal([]cty.Value{cty.StringVal("a"), cty.StringVal("x"), cty.StringVal("b"), cty.StringVal("y")}),
+			}),
+		},
 		{
 			"InsertTupleEmpty",
 			Diff{
@@ -208,6 +298,125 @@ func TestDiff_Apply(t *testing.T) {
 			cty.SetVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
 			cty.SetVal([]cty.Value{cty.StringVal("b")}),
 		},
+		{
+			"RemoveSetLast",
+			Diff{
+				RemoveChange{
+					Path:     nil,
+					OldValue: cty.StringVal("a"),
+				},
+			},
+			cty.SetVal([]cty.Value{cty.StringVal("a")}),
+			cty.SetValEmpty(cty.String),
+		},
+
+		// Nested
+		{
+			"NestedObject",
+			Diff{
+				NestedDiff{
+					Path:     cty.GetAttrPath("a"),
+					OldValue: cty.ObjectVal(map[string]cty.Value{"b": cty.StringVal("B")}),
+					Diff: Diff{
+						ReplaceChange{
+							Path:     cty.GetAttrPath("b"),
+							OldValue: cty.StringVal("B"),
+							NewValue: cty.StringVal("C"),
+						},
+					},
+				},
+			},
+			cty.ObjectVal(map[string]cty.Value{
+				"a": cty.ObjectVal(map[string]cty.Value{"b": cty.StringVal("B")}),
+			}),
+			cty.ObjectVal(map[string]cty.Value{
+				"a": cty.ObjectVal(map[string]cty.Value{"b": cty.StringVal("C")}),
+			}),
+		},
+		{
+			"NestedSetElement",
+			Diff{
+				NestedDiff{
+					Path: cty.IndexPath(cty.ObjectVal(map[string]cty.Value{
+						"name": cty.StringVal("a"),
+						"size": cty.NumberIntVal(1),
+					})),
+					OldValue: cty.ObjectVal(map[string]cty.Value{
+						"name": cty.StringVal("a"),
+						"size": cty.NumberIntVal(1),
+					}),
+					Diff: Diff{
+						ReplaceChange{
+							Path:     cty.GetAttrPath("size"),
+							OldValue: cty.NumberIntVal(1),
+							NewValue: cty.NumberIntVal(2),
+						},
+					},
+				},
+			},
+			cty.SetVal([]cty.Value{
+				cty.ObjectVal(map[string]cty.Value{
+					"name": cty.StringVal("a"),
+					"size": cty.NumberIntVal(1),
+				}),
+				cty.ObjectVal(map[string]cty.Value{
+					"name": cty.StringVal("b"),
+					"size": cty.NumberIntVal(1),
+				}),
+			}),
+			cty.SetVal([]cty.Value{
+				cty.ObjectVal(map[string]cty.Value{
+					"name": cty.StringVal("a"),
+					"size": cty.NumberIntVal(2),
+				}),
+				cty.ObjectVal(map[string]cty.Value{
+					"name": cty.StringVal("b"),
+					"size": cty.NumberIntVal(1),
+				}),
+			}),
+		},
+
+		// Unchecked
+		{
+			"ReplaceUnchecked",
+			Diff{
+				ReplaceChange{
+					NewValue: cty.MapVal(map[string]cty.Value{"a": cty.StringVal("B")}),
+				},
+				ReplaceChange{
+					Path:     cty.IndexPath(cty.StringVal("a")),
+					NewValue: cty.StringVal("C"),
+				},
+			},
+			cty.StringVal("A"),
+			cty.MapVal(map[string]cty.Value{"a": cty.StringVal("C")}),
+		},
+		{
+			"DeleteUnchecked",
+			Diff{
+				DeleteChange{
+					Path: cty.IndexPath(cty.NumberIntVal(0)),
+				},
+			},
+			cty.ListVal([]cty.Value{cty.StringVal("A"), cty.StringVal("B")}),
+			cty.ListVal([]cty.Value{cty.StringVal("B")}),
+		},
+		{
+			"InsertUnchecked",
+			Diff{
+				InsertChange{
+					Path:        cty.IndexPath(cty.NumberIntVal(0)),
+					NewValue:    cty.StringVal("A"),
+					ElementPath: true,
+				},
+				InsertChange{
+					Path:     nil,
+					NewValue: cty.StringVal("C"),
+				},
+			},
+			cty.ListVal([]cty.Value{cty.StringVal("B")}),
+			cty.ListVal([]cty.Value{cty.StringVal("A"), cty.StringVal("B"), cty.StringVal("C")}),
+		},
 
 		// Context
 		{
@@ -235,3 +444,450 @@ func TestDiff_Apply(t *testing.T) {
 		})
 	}
 }
+
+func TestDiff_ApplyErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		diff   Diff
+		source cty.Value
+	}{
+		{
+			"NilChange",
+			Diff{nil},
+			cty.StringVal("A"),
+		},
+		{
+			"ReplaceUnsupportedParent",
+			Diff{
+				ReplaceChange{
+					Path:     cty.IndexPath(cty.StringVal("a")),
+					OldValue: cty.StringVal("a"),
+					NewValue: cty.StringVal("b"),
+				},
+			},
+			cty.StringVal("a"),
+		},
+		{
+			"ReplaceMapWrongKeyType",
+			Diff{
+				ReplaceChange{
+					Path:     cty.IndexPath(cty.NumberIntVal(0)),
+					OldValue: cty.NullVal(cty.String),
+					NewValue: cty.StringVal("A"),
+				},
+			},
+			cty.MapValEmpty(cty.String),
+		},
+		{
+			"ReplaceObjectWithIndex",
+			Diff{
+				ReplaceChange{
+					Path:     cty.IndexPath(cty.StringVal("a")),
+					OldValue: cty.StringVal("A"),
+					NewValue: cty.StringVal("B"),
+				},
+			},
+			cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("A")}),
+		},
+		{
+			"ReplaceListWrongElementType",
+			Diff{
+				ReplaceChange{
+					Path:     cty.IndexPath(cty.NumberIntVal(0)),
+					OldValue: cty.StringVal("A"),
+					NewValue: cty.NumberIntVal(1),
+				},
+			},
+			cty.ListVal([]cty.Value{cty.StringVal("A")}),
+		},
+		{
+			"ReplaceMissingParent",
+			Diff{
+				ReplaceChange{
+					Path:     cty.GetAttrPath("a").GetAttr("b"),
+					OldValue: cty.StringVal("A"),
+					NewValue: cty.StringVal("B"),
+				},
+			},
+			cty.ObjectVal(map[string]cty.Value{"b": cty.StringVal("A")}),
+		},
+		{
+			"ReplaceUncheckedMissing",
+			Diff{
+				ReplaceChange{
+					Path:     cty.IndexPath(cty.StringVal("b")),
+					NewValue: cty.StringVal("B"),
+				},
+			},
+			cty.MapVal(map[string]cty.Value{"a": cty.StringVal("A")}),
+		},
+		{
+			"DeleteEmptyPath",
+			Diff{
+				DeleteChange{
+					OldValue: cty.StringVal("A"),
+				},
+			},
+			cty.StringVal("A"),
+		},
+		{
+			"DeleteFractionalIndex",
+			Diff{
+				DeleteChange{
+					Path:     cty.IndexPath(cty.NumberFloatVal(0.5)),
+					OldValue: cty.StringVal("A"),
+				},
+			},
+			cty.ListVal([]cty.Value{cty.StringVal("A")}),
+		},
+		{
+			"DeleteUnknownIndex",
+			Diff{
+				DeleteChange{
+					Path:     cty.IndexPath(cty.UnknownVal(cty.Number)),
+					OldValue: cty.StringVal("A"),
+				},
+			},
+			cty.TupleVal([]cty.Value{cty.StringVal("A")}),
+		},
+		{
+			"InsertWithoutIndex",
+			Diff{
+				InsertChange{
+					Path:        cty.GetAttrPath("l"),
+					NewValue:    cty.StringVal("A"),
+					BeforeValue: cty.StringVal("B"),
+					ElementPath: true,
+				},
+			},
+			cty.ObjectVal(map[string]cty.Value{
+				"l": cty.ListVal([]cty.Value{cty.StringVal("B")}),
+			}),
+		},
+		{
+			"InsertIntoMap",
+			Diff{
+				InsertChange{
+					NewValue:    cty.StringVal("A"),
+					BeforeValue: cty.NullVal(cty.String),
+				},
+			},
+			cty.MapValEmpty(cty.String),
+		},
+		{
+			"InsertWrongElementType",
+			Diff{
+				InsertChange{
+					NewValue:    cty.NumberIntVal(1),
+					BeforeValue: cty.NullVal(cty.String),
+				},
+			},
+			cty.ListValEmpty(cty.String),
+		},
+		{
+			"InsertUncheckedOutOfRange",
+			Diff{
+				InsertChange{
+					Path:        cty.IndexPath(cty.NumberIntVal(1)),
+					NewValue:    cty.StringVal("A"),
+					ElementPath: true,
+				},
+			},
+			cty.ListValEmpty(cty.String),
+		},
+		{
+			"InsertUnknownList",
+			Diff{
+				InsertChange{
+					NewValue:    cty.StringVal("A"),
+					BeforeValue: cty.NullVal(cty.String),
+				},
+			},
+			cty.UnknownVal(cty.List(cty.String)),
+		},
+		{
+			"AddWrongElementType",
+			Diff{
+				AddChange{
+					NewValue: cty.NumberIntVal(1),
+				},
+			},
+			cty.SetValEmpty(cty.String),
+		},
+		{
+			"RemoveNullSet",
+			Diff{
+				RemoveChange{
+					OldValue: cty.StringVal("a"),
+				},
+			},
+			cty.NullVal(cty.Set(cty.String)),
+		},
+		{
+			"NestedOldValueMismatch",
+			Diff{
+				NestedDiff{
+					Path:     cty.GetAttrPath("a"),
+					OldValue: cty.StringVal("B"),
+				},
+			},
+			cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("A")}),
+		},
+		{
+			"ContextNilWantValue",
+			Diff{
+				Context{
+					Path: cty.GetAttrPath("a"),
+				},
+			},
+			cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("A")}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.diff.Apply(tt.source)
+			if err == nil {
+				t.Fatalf("Apply() succeeded; want error\nGot\n%#v", got)
+			}
+		})
+	}
+}
+
+func TestDiff_Walk(t *testing.T) {
+	setElem := cty.ObjectVal(map[string]cty.Value{"b": cty.StringVal("B")})
+	diff := Diff{
+		Context{
+			Path:      cty.GetAttrPath("a"),
+			WantValue: cty.StringVal("A"),
+		},
+		NestedDiff{
+			Path:     cty.GetAttrPath("s").Index(setElem),
+			OldValue: setElem,
+			Diff: Diff{
+				ReplaceChange{
+					Path:     cty.GetAttrPath("b"),
+					OldValue: cty.StringVal("B"),
+					NewValue: cty.StringVal("C"),
+				},
+			},
+		},
+	}
+
+	var got []cty.Path
+	err := diff.Walk(func(absPath cty.Path, c Change) error {
+		got = append(got, absPath)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() err = %v", err)
+	}
+	want := []cty.Path{
+		cty.GetAttrPath("a"),
+		cty.GetAttrPath("s").Index(setElem),
+		cty.GetAttrPath("s").Index(setElem).GetAttr("b"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong paths\nGot\n%#v\nWant\n%#v", got, want)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = diff.Walk(func(absPath cty.Path, c Change) error {
+		calls++
+		return stop
+	})
+	if err != stop {
+		t.Errorf("Walk() err = %v, want %v", err, stop)
+	}
+	if calls != 1 {
+		t.Errorf("callback called %d times after returning an error, want 1", calls)
+	}
+}
+
+func TestDiff_Flatten(t *testing.T) {
+	inner := cty.ObjectVal(map[string]cty.Value{
+		"b": cty.StringVal("B"),
+		"l": cty.ListVal([]cty.Value{cty.StringVal("X")}),
+	})
+	setElem := cty.ObjectVal(map[string]cty.Value{"n": cty.NumberIntVal(1)})
+	source := cty.ObjectVal(map[string]cty.Value{
+		"a": inner,
+		"s": cty.SetVal([]cty.Value{setElem}),
+	})
+	diff := Diff{
+		NestedDiff{
+			Path:     cty.GetAttrPath("a"),
+			OldValue: inner,
+			Diff: Diff{
+				ReplaceChange{
+					Path:     cty.GetAttrPath("b"),
+					OldValue: cty.StringVal("B"),
+					NewValue: cty.StringVal("C"),
+				},
+				DeleteChange{
+					Path:     cty.GetAttrPath("l").Index(cty.NumberIntVal(0)),
+					OldValue: cty.StringVal("X"),
+				},
+			},
+		},
+		NestedDiff{
+			Path:     cty.GetAttrPath("s").Index(setElem),
+			OldValue: setElem,
+			Diff: Diff{
+				ReplaceChange{
+					Path:     cty.GetAttrPath("n"),
+					OldValue: cty.NumberIntVal(1),
+					NewValue: cty.NumberIntVal(2),
+				},
+			},
+		},
+	}
+
+	got := diff.Flatten()
+	want := Diff{
+		Context{
+			Path:      cty.GetAttrPath("a"),
+			WantValue: inner,
+		},
+		ReplaceChange{
+			Path:     cty.GetAttrPath("a").GetAttr("b"),
+			OldValue: cty.StringVal("B"),
+			NewValue: cty.StringVal("C"),
+		},
+		DeleteChange{
+			Path:     cty.GetAttrPath("a").GetAttr("l").Index(cty.NumberIntVal(0)),
+			OldValue: cty.StringVal("X"),
+		},
+		NestedDiff{
+			Path:     cty.GetAttrPath("s").Index(setElem),
+			OldValue: setElem,
+			Diff: Diff{
+				ReplaceChange{
+					Path:     cty.GetAttrPath("n"),
+					OldValue: cty.NumberIntVal(1),
+					NewValue: cty.NumberIntVal(2),
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong result\nGot\n%#v\nWant\n%#v", got, want)
+	}
+
+	wantVal, err := diff.Apply(source)
+	if err != nil {
+		t.Fatalf("Apply() err = %v", err)
+	}
+	gotVal, err := got.Apply(source)
+	if err != nil {
+		t.Fatalf("Apply() of flattened diff err = %v", err)
+	}
+	if !gotVal.RawEquals(wantVal) {
+		t.Errorf("flattened diff is not equivalent\nGot\n%#v\nWant\n%#v", gotVal, wantVal)
+	}
+}
+
+func TestDiff_Filter(t *testing.T) {
+	setElem := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("a"),
+		"etag": cty.StringVal("1"),
+	})
+	replace := func(path cty.Path) ReplaceChange {
+		return ReplaceChange{
+			Path:     path,
+			OldValue: cty.StringVal("A"),
+			NewValue: cty.StringVal("B"),
+		}
+	}
+	nested := NestedDiff{
+		Path:     cty.GetAttrPath("s").Index(setElem),
+		OldValue: setElem,
+		Diff: Diff{
+			replace(cty.GetAttrPath("name")),
+			replace(cty.GetAttrPath("etag")),
+		},
+	}
+	diff := Diff{
+		replace(cty.GetAttrPath("name")),
+		replace(cty.GetAttrPath("tags").Index(cty.StringVal("env"))),
+		replace(cty.GetAttrPath("items").Index(cty.NumberIntVal(0)).GetAttr("etag")),
+		replace(cty.GetAttrPath("items").Index(cty.NumberIntVal(0)).GetAttr("name")),
+		nested,
+	}
+
+	tests := []struct {
+		name    string
+		include []PathPattern
+		exclude []PathPattern
+		want    Diff
+	}{
+		{
+			"None",
+			nil,
+			nil,
+			diff,
+		},
+		{
+			"Exclude",
+			nil,
+			[]PathPattern{
+				GetAttrPattern("tags"),
+				PathPattern{}.AnyAttr().AnyIndex().GetAttr("etag"),
+				PathPattern{}.AnyAttr().AnySetMember().GetAttr("etag"),
+			},
+			Diff{
+				replace(cty.GetAttrPath("name")),
+				replace(cty.GetAttrPath("items").Index(cty.NumberIntVal(0)).GetAttr("name")),
+				NestedDiff{
+					Path:     nested.Path,
+					OldValue: setElem,
+					Diff: Diff{
+						replace(cty.GetAttrPath("name")),
+					},
+				},
+			},
+		},
+		{
+			"Include",
+			[]PathPattern{
+				GetAttrPattern("items"),
+				GetAttrPattern("s").AnySetMember().GetAttr("etag"),
+			},
+			[]PathPattern{
+				GetAttrPattern("items").AnyIndex().GetAttr("name"),
+			},
+			Diff{
+				replace(cty.GetAttrPath("items").Index(cty.NumberIntVal(0)).GetAttr("etag")),
+				NestedDiff{
+					Path:     nested.Path,
+					OldValue: setElem,
+					Diff: Diff{
+						replace(cty.GetAttrPath("etag")),
+					},
+				},
+			},
+		},
+		{
+			"ExcludeNested",
+			nil,
+			[]PathPattern{
+				GetAttrPattern("s"),
+				GetAttrPattern("items"),
+				GetAttrPattern("tags"),
+			},
+			Diff{
+				replace(cty.GetAttrPath("name")),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diff.Filter(tt.include, tt.exclude)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wrong result\nGot\n%#v\nWant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
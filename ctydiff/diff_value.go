@@ -0,0 +1,348 @@
+package ctydiff
+
+import (
+	"sort"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// differ holds the settings for a single call to NewDiffWithOptions.
+type differ struct {
+	opts DiffOptions
+}
+
+// diffValues returns a diff that transforms old into new, where both values
+// are at the given path within the values being compared.
+func (d *differ) diffValues(old, new cty.Value, path cty.Path) Diff {
+	if matchAny(d.opts.Ignore, path) {
+		return nil
+	}
+
+	ty := old.Type()
+	switch {
+	case !old.IsKnown() || !new.IsKnown():
+		// Unknown values are never equal, even to themselves.
+		return d.replace(old, new, path)
+	case !ty.Equals(new.Type()) || old.IsNull() || new.IsNull():
+		if old.RawEquals(new) {
+			return nil
+		}
+		return d.replace(old, new, path)
+	case ty.IsObjectType():
+		return d.diffObjects(old, new, path)
+	case ty.IsMapType():
+		return d.diffMaps(old, new, path)
+	case ty.IsTupleType():
+		return d.diffTuples(old, new, path)
+	case ty.IsListType():
+		return d.diffLists(old, new, path)
+	case ty.IsSetType():
+		return d.diffSets(old, new, path)
+	default:
+		if old.RawEquals(new) {
+			return nil
+		}
+		return d.replace(old, new, path)
+	}
+}
+
+func (d *differ) replace(old, new cty.Value, path cty.Path) Diff {
+	return Diff{
+		ReplaceChange{
+			Path:     path.Copy(),
+			OldValue: old,
+			NewValue: new,
+		},
+	}
+}
+
+func (d *differ) diffObjects(old, new cty.Value, path cty.Path) Diff {
+	atys := old.Type().AttributeTypes()
+	names := make([]string, 0, len(atys))
+	for name := range atys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var diff Diff
+	for _, name := range names {
+		diff = append(diff, d.diffValues(old.GetAttr(name), new.GetAttr(name), path.GetAttr(name))...)
+	}
+	return diff
+}
+
+func (d *differ) diffMaps(old, new cty.Value, path cty.Path) Diff {
+	oldMap := old.AsValueMap()
+	newMap := new.AsValueMap()
+	keys := make([]string, 0, len(oldMap)+len(newMap))
+	for k := range oldMap {
+		keys = append(keys, k)
+	}
+	for k := range newMap {
+		if _, exists := oldMap[k]; !exists {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var diff Diff
+	for _, k := range keys {
+		elemPath := path.Index(cty.StringVal(k))
+		if matchAny(d.opts.Ignore, elemPath) {
+			continue
+		}
+		ov, inOld := oldMap[k]
+		nv, inNew := newMap[k]
+		switch {
+		case !inNew:
+			diff = append(diff, DeleteChange{
+				Path:     elemPath,
+				OldValue: ov,
+			})
+		case !inOld:
+			diff = append(diff, ReplaceChange{
+				Path:     elemPath,
+				OldValue: cty.NullVal(new.Type().ElementType()),
+				NewValue: nv,
+			})
+		default:
+			diff = append(diff, d.diffValues(ov, nv, elemPath)...)
+		}
+	}
+	return diff
+}
+
+func (d *differ) diffTuples(old, new cty.Value, path cty.Path) Diff {
+	var diff Diff
+	for i := 0; i < old.LengthInt(); i++ {
+		idx := cty.NumberIntVal(int64(i))
+		diff = append(diff, d.diffValues(old.Index(idx), new.Index(idx), path.Index(idx))...)
+	}
+	return diff
+}
+
+// diffLists aligns the elements of two lists using diffElemsShallow and then
+// refines the result, comparing the elements that were replaced in the same
+// position more deeply.
+func (d *differ) diffLists(old, new cty.Value, path cty.Path) Diff {
+	oldEls := old.AsValueSlice()
+	newEls := new.AsValueSlice()
+	ety := old.Type().ElementType()
+
+	shallow := diffElemsShallow(d.maskElems(oldEls, path), d.maskElems(newEls, path), ety, path)
+
+	// diffElemsShallow saw the masked elements, so we must put the real
+	// elements back. Each change consumes elements from the two lists in
+	// order, so we can recover which elements each change refers to.
+	op, np := 0, 0
+	for i, c := range shallow {
+		switch c := c.(type) {
+		case DeleteChange:
+			c.OldValue = oldEls[op]
+			shallow[i] = c
+			op++
+		case InsertChange:
+			c.NewValue = newEls[np]
+			if op < len(oldEls) {
+				c.BeforeValue = oldEls[op]
+			}
+			shallow[i] = c
+			np++
+		case Context:
+			c.WantValue = oldEls[op]
+			shallow[i] = c
+			op++
+			np++
+		}
+	}
+
+	// diffElemsShallow produces runs of deletions followed by runs of
+	// insertions at the same index. Where both are present, we pair them up
+	// as in-place changes to the elements at those indices.
+	var diff Diff
+	for i := 0; i < len(shallow); {
+		dels := 0
+		for i+dels < len(shallow) {
+			if _, ok := shallow[i+dels].(DeleteChange); !ok {
+				break
+			}
+			dels++
+		}
+		ins := 0
+		for i+dels+ins < len(shallow) {
+			if _, ok := shallow[i+dels+ins].(InsertChange); !ok {
+				break
+			}
+			ins++
+		}
+		if dels == 0 || ins == 0 {
+			if dels+ins == 0 {
+				diff = append(diff, shallow[i])
+				i++
+				continue
+			}
+			diff = append(diff, shallow[i:i+dels+ins]...)
+			i += dels + ins
+			continue
+		}
+
+		pairs := dels
+		if ins < pairs {
+			pairs = ins
+		}
+		start, _ := shallow[i].(DeleteChange).Path[len(path)].(cty.IndexStep).Key.AsBigFloat().Int64()
+		for j := 0; j < pairs; j++ {
+			ov := shallow[i+j].(DeleteChange).OldValue
+			nv := shallow[i+dels+j].(InsertChange).NewValue
+			idx := cty.NumberIntVal(start + int64(j))
+			diff = append(diff, d.diffValues(ov, nv, path.Index(idx))...)
+		}
+		for j := pairs; j < dels; j++ {
+			c := shallow[i+j].(DeleteChange)
+			c.Path = path.Index(cty.NumberIntVal(start + int64(pairs)))
+			diff = append(diff, c)
+		}
+		diff = append(diff, shallow[i+dels+pairs:i+dels+ins]...)
+		i += dels + ins
+	}
+	return d.trimContext(diff, path)
+}
+
+// trimContext removes the Context changes for elements of the list at the
+// given path from the given diff of that list, as directed by the
+// ContextRadius and NoContext options.
+func (d *differ) trimContext(diff Diff, path cty.Path) Diff {
+	if !d.opts.NoContext && d.opts.ContextRadius <= 0 {
+		return diff
+	}
+
+	isContext := func(c Change) bool {
+		ctx, ok := c.(Context)
+		return ok && len(ctx.Path) == len(path)+1
+	}
+	var ret Diff
+	for i := 0; i < len(diff); {
+		if !isContext(diff[i]) {
+			ret = append(ret, diff[i])
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(diff) && isContext(diff[j]) {
+			j++
+		}
+		if !d.opts.NoContext {
+			// We keep elements after the preceding change and before the
+			// following change, if there are any.
+			after, before := 0, 0
+			if i > 0 {
+				after = d.opts.ContextRadius
+			}
+			if j < len(diff) {
+				before = d.opts.ContextRadius
+			}
+			if after+before >= j-i {
+				ret = append(ret, diff[i:j]...)
+			} else {
+				ret = append(ret, diff[i:i+after]...)
+				ret = append(ret, diff[j-before:j]...)
+			}
+		}
+		i = j
+	}
+	return ret
+}
+
+func (d *differ) diffSets(old, new cty.Value, path cty.Path) Diff {
+	oldEls := old.AsValueSlice()
+	newEls := new.AsValueSlice()
+
+	var diff Diff
+	if !d.mayIgnoreBelow(path) {
+		oldSet := old.AsValueSet()
+		newSet := new.AsValueSet()
+		for _, v := range oldEls {
+			if !newSet.Has(v) {
+				diff = append(diff, RemoveChange{Path: path.Copy(), OldValue: v})
+			}
+		}
+		for _, v := range newEls {
+			if !oldSet.Has(v) {
+				diff = append(diff, AddChange{Path: path.Copy(), NewValue: v})
+			}
+		}
+		return diff
+	}
+
+	// With ignored paths inside the set elements we must compare the
+	// masked versions of the elements pairwise.
+	oldMasked := make([]cty.Value, len(oldEls))
+	for i, v := range oldEls {
+		oldMasked[i] = d.mask(v, path.Index(v))
+	}
+	newMasked := make([]cty.Value, len(newEls))
+	for i, v := range newEls {
+		newMasked[i] = d.mask(v, path.Index(v))
+	}
+	matched := make([]bool, len(newEls))
+	for i, ov := range oldMasked {
+		found := false
+		for j, nv := range newMasked {
+			if !matched[j] && ov.RawEquals(nv) && ov.IsWhollyKnown() {
+				matched[j] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			diff = append(diff, RemoveChange{Path: path.Copy(), OldValue: oldEls[i]})
+		}
+	}
+	for j, v := range newEls {
+		if !matched[j] {
+			diff = append(diff, AddChange{Path: path.Copy(), NewValue: v})
+		}
+	}
+	return diff
+}
+
+// maskElems returns the given list elements with any ignored paths masked,
+// or the given slice itself if no paths within it are ignored.
+func (d *differ) maskElems(elems []cty.Value, path cty.Path) []cty.Value {
+	if !d.mayIgnoreBelow(path) {
+		return elems
+	}
+	ret := make([]cty.Value, len(elems))
+	for i, v := range elems {
+		ret[i] = d.mask(v, path.Index(cty.NumberIntVal(int64(i))))
+	}
+	return ret
+}
+
+// mask replaces any values at ignored paths within the given value, which
+// is at the given path, with nulls of the same type, so that values that
+// differ only in ignored paths compare as equal.
+func (d *differ) mask(val cty.Value, path cty.Path) cty.Value {
+	ret, err := cty.Transform(val, func(p cty.Path, v cty.Value) (cty.Value, error) {
+		if matchAny(d.opts.Ignore, joinPath(path, p)) {
+			return cty.NullVal(v.Type()), nil
+		}
+		return v, nil
+	})
+	if err != nil {
+		// Should never happen, since our callback never returns an error.
+		return val
+	}
+	return ret
+}
+
+// mayIgnoreBelow returns true if any of the ignore patterns could match
+// a path nested inside the given path.
+func (d *differ) mayIgnoreBelow(path cty.Path) bool {
+	for _, p := range d.opts.Ignore {
+		if len(p) > len(path) && p[:len(path)].Match(path) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,420 @@
+package ctydiff
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/zclconf/go-cty/cty"
+)
+
+var prettyDiff = &pretty.Config{
+	Diffable: true,
+	Formatter: map[reflect.Type]interface{}{
+		reflect.TypeOf(cty.NilVal): func(val cty.Value) string {
+			return val.GoString()
+		},
+	},
+}
+
+func TestNewDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		source cty.Value
+		target cty.Value
+		want   Diff
+	}{
+		{
+			"Equal",
+			cty.StringVal("a"),
+			cty.StringVal("a"),
+			nil,
+		},
+		{
+			"Primitive",
+			cty.StringVal("a"),
+			cty.StringVal("b"),
+			Diff{
+				ReplaceChange{
+					Path:     cty.Path{},
+					OldValue: cty.StringVal("a"),
+					NewValue: cty.StringVal("b"),
+				},
+			},
+		},
+		{
+			"TypeChange",
+			cty.StringVal("a"),
+			cty.NumberIntVal(1),
+			Diff{
+				ReplaceChange{
+					Path:     cty.Path{},
+					OldValue: cty.StringVal("a"),
+					NewValue: cty.NumberIntVal(1),
+				},
+			},
+		},
+		{
+			"Object",
+			cty.ObjectVal(map[string]cty.Value{
+				"a": cty.StringVal("A"),
+				"b": cty.StringVal("B"),
+			}),
+			cty.ObjectVal(map[string]cty.Value{
+				"a": cty.StringVal("A"),
+				"b": cty.StringVal("C"),
+			}),
+			Diff{
+				ReplaceChange{
+					Path:     cty.GetAttrPath("b"),
+					OldValue: cty.StringVal("B"),
+					NewValue: cty.StringVal("C"),
+				},
+			},
+		},
+		{
+			"Map",
+			cty.MapVal(map[string]cty.Value{
+				"a": cty.StringVal("A"),
+				"b": cty.StringVal("B"),
+				"c": cty.StringVal("C"),
+			}),
+			cty.MapVal(map[string]cty.Value{
+				"b": cty.StringVal("X"),
+				"c": cty.StringVal("C"),
+				"d": cty.StringVal("D"),
+			}),
+			Diff{
+				DeleteChange{
+					Path:     cty.IndexPath(cty.StringVal("a")),
+					OldValue: cty.StringVal("A"),
+				},
+				ReplaceChange{
+					Path:     cty.IndexPath(cty.StringVal("b")),
+					OldValue: cty.StringVal("B"),
+					NewValue: cty.StringVal("X"),
+				},
+				ReplaceChange{
+					Path:     cty.IndexPath(cty.StringVal("d")),
+					OldValue: cty.NullVal(cty.String),
+					NewValue: cty.StringVal("D"),
+				},
+			},
+		},
+		{
+			"Tuple",
+			cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.NumberIntVal(1)}),
+			cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.NumberIntVal(2)}),
+			Diff{
+				ReplaceChange{
+					Path:     cty.IndexPath(cty.NumberIntVal(1)),
+					OldValue: cty.NumberIntVal(1),
+					NewValue: cty.NumberIntVal(2),
+				},
+			},
+		},
+		{
+			"List",
+			cty.ListVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b"), cty.StringVal("c")}),
+			cty.ListVal([]cty.Value{cty.StringVal("a"), cty.StringVal("x"), cty.StringVal("c"), cty.StringVal("d")}),
+			Diff{
+				Context{
+					Path:      cty.IndexPath(cty.NumberIntVal(0)),
+					WantValue: cty.StringVal("a"),
+				},
+				ReplaceChange{
+					Path:     cty.IndexPath(cty.NumberIntVal(1)),
+					OldValue: cty.StringVal("b"),
+					NewValue: cty.StringVal("x"),
+				},
+				Context{
+					Path:      cty.IndexPath(cty.NumberIntVal(2)),
+					WantValue: cty.StringVal("c"),
+				},
+				InsertChange{
+					Path:        cty.IndexPath(cty.NumberIntVal(3)),
+					NewValue:    cty.StringVal("d"),
+					BeforeValue: cty.NullVal(cty.String),
+					ElementPath: true,
+				},
+			},
+		},
+		{
+			"ListDeepChange",
+			cty.ListVal([]cty.Value{
+				cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("A"), "b": cty.StringVal("B")}),
+				cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("C"), "b": cty.StringVal("D")}),
+			}),
+			cty.ListVal([]cty.Value{
+				cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("A"), "b": cty.StringVal("X")}),
+			}),
+			Diff{
+				ReplaceChange{
+					Path:     cty.IndexPath(cty.NumberIntVal(0)).GetAttr("b"),
+					OldValue: cty.StringVal("B"),
+					NewValue: cty.StringVal("X"),
+				},
+				DeleteChange{
+					Path:     cty.IndexPath(cty.NumberIntVal(1)),
+					OldValue: cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("C"), "b": cty.StringVal("D")}),
+				},
+			},
+		},
+		{
+			"Set",
+			cty.SetVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+			cty.SetVal([]cty.Value{cty.StringVal("b"), cty.StringVal("c")}),
+			Diff{
+				RemoveChange{
+					Path:     cty.Path{},
+					OldValue: cty.StringVal("a"),
+				},
+				AddChange{
+					Path:     cty.Path{},
+					NewValue: cty.StringVal("c"),
+				},
+			},
+		},
+		{
+			"Null",
+			cty.NullVal(cty.List(cty.String)),
+			cty.ListVal([]cty.Value{cty.StringVal("a")}),
+			Diff{
+				ReplaceChange{
+					Path:     cty.Path{},
+					OldValue: cty.NullVal(cty.List(cty.String)),
+					NewValue: cty.ListVal([]cty.Value{cty.StringVal("a")}),
+				},
+			},
+		},
+		{
+			"Unknown",
+			cty.UnknownVal(cty.String),
+			cty.UnknownVal(cty.String),
+			Diff{
+				ReplaceChange{
+					Path:     cty.Path{},
+					OldValue: cty.UnknownVal(cty.String),
+					NewValue: cty.UnknownVal(cty.String),
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewDiff(tt.source, tt.target)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("wrong result\n%s", prettyDiff.Compare(tt.want, got))
+			}
+
+			if !tt.source.IsWhollyKnown() {
+				return
+			}
+			applied, err := got.Apply(tt.source)
+			if err != nil {
+				t.Fatalf("Apply() err = %v", err)
+			}
+			if !applied.RawEquals(tt.target) {
+				t.Errorf("Apply\nGot\n%#v\nWant\n%#v", applied, tt.target)
+			}
+		})
+	}
+}
+
+func TestNewDiffWithOptions_ignore(t *testing.T) {
+	item := func(name, etag string) cty.Value {
+		return cty.ObjectVal(map[string]cty.Value{
+			"name": cty.StringVal(name),
+			"etag": cty.StringVal(etag),
+		})
+	}
+	source := cty.ObjectVal(map[string]cty.Value{
+		"name":          cty.StringVal("a"),
+		"last_modified": cty.StringVal("yesterday"),
+		"tags":          cty.MapVal(map[string]cty.Value{"env": cty.StringVal("dev")}),
+		"items":         cty.ListVal([]cty.Value{item("x", "1"), item("y", "2")}),
+		"members":       cty.SetVal([]cty.Value{item("x", "1"), item("y", "2")}),
+	})
+	target := cty.ObjectVal(map[string]cty.Value{
+		"name":          cty.StringVal("b"),
+		"last_modified": cty.StringVal("today"),
+		"tags":          cty.MapVal(map[string]cty.Value{"env": cty.StringVal("prod")}),
+		"items":         cty.ListVal([]cty.Value{item("x", "3"), item("z", "4")}),
+		"members":       cty.SetVal([]cty.Value{item("x", "3"), item("z", "4")}),
+	})
+	opts := DiffOptions{
+		Ignore: []PathPattern{
+			GetAttrPattern("tags"),
+			GetAttrPattern("last_modified"),
+			GetAttrPattern("items").AnyIndex().GetAttr("etag"),
+			GetAttrPattern("members").AnySetMember().GetAttr("etag"),
+		},
+	}
+
+	got := NewDiffWithOptions(source, target, opts)
+	want := Diff{
+		Context{
+			Path:      cty.GetAttrPath("items").Index(cty.NumberIntVal(0)),
+			WantValue: item("x", "1"),
+		},
+		ReplaceChange{
+			Path:     cty.GetAttrPath("items").Index(cty.NumberIntVal(1)).GetAttr("name"),
+			OldValue: cty.StringVal("y"),
+			NewValue: cty.StringVal("z"),
+		},
+		RemoveChange{
+			Path:     cty.GetAttrPath("members"),
+			OldValue: item("y", "2"),
+		},
+		AddChange{
+			Path:     cty.GetAttrPath("members"),
+			NewValue: item("z", "4"),
+		},
+		ReplaceChange{
+			Path:     cty.GetAttrPath("name"),
+			OldValue: cty.StringVal("a"),
+			NewValue: cty.StringVal("b"),
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong result\n%s", prettyDiff.Compare(want, got))
+	}
+	if _, err := got.Apply(source); err != nil {
+		t.Fatalf("Apply() err = %v", err)
+	}
+}
+
+func TestNewDiffWithOptions_context(t *testing.T) {
+	str := func(ss ...string) cty.Value {
+		vals := make([]cty.Value, len(ss))
+		for i, s := range ss {
+			vals[i] = cty.StringVal(s)
+		}
+		return cty.ListVal(vals)
+	}
+	context := func(i int, s string) Change {
+		return Context{
+			Path:      cty.IndexPath(cty.NumberIntVal(int64(i))),
+			WantValue: cty.StringVal(s),
+		}
+	}
+	source := str("a", "b", "c", "d", "e", "f", "g", "h")
+	target := str("a", "b", "x", "d", "e", "f", "g", "y")
+	replace := func(i int, old, new string) Change {
+		return ReplaceChange{
+			Path:     cty.IndexPath(cty.NumberIntVal(int64(i))),
+			OldValue: cty.StringVal(old),
+			NewValue: cty.StringVal(new),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		opts   DiffOptions
+		source cty.Value
+		target cty.Value
+		want   Diff
+	}{
+		{
+			"Radius1",
+			DiffOptions{ContextRadius: 1},
+			source,
+			target,
+			Diff{
+				context(1, "b"),
+				replace(2, "c", "x"),
+				context(3, "d"),
+				context(6, "g"),
+				replace(7, "h", "y"),
+			},
+		},
+		{
+			"Radius2",
+			DiffOptions{ContextRadius: 2},
+			source,
+			target,
+			Diff{
+				context(0, "a"),
+				context(1, "b"),
+				replace(2, "c", "x"),
+				context(3, "d"),
+				context(4, "e"),
+				context(5, "f"),
+				context(6, "g"),
+				replace(7, "h", "y"),
+			},
+		},
+		{
+			"NoContext",
+			DiffOptions{NoContext: true},
+			source,
+			target,
+			Diff{
+				replace(2, "c", "x"),
+				replace(7, "h", "y"),
+			},
+		},
+		{
+			"Unchanged",
+			DiffOptions{ContextRadius: 3},
+			source,
+			source,
+			nil,
+		},
+		{
+			"Nested",
+			DiffOptions{ContextRadius: 1},
+			cty.ObjectVal(map[string]cty.Value{
+				"list": cty.ListVal([]cty.Value{source, str("a")}),
+			}),
+			cty.ObjectVal(map[string]cty.Value{
+				"list": cty.ListVal([]cty.Value{target, str("a")}),
+			}),
+			Diff{
+				Context{
+					Path:      cty.GetAttrPath("list").Index(cty.NumberIntVal(0)).Index(cty.NumberIntVal(1)),
+					WantValue: cty.StringVal("b"),
+				},
+				ReplaceChange{
+					Path:     cty.GetAttrPath("list").Index(cty.NumberIntVal(0)).Index(cty.NumberIntVal(2)),
+					OldValue: cty.StringVal("c"),
+					NewValue: cty.StringVal("x"),
+				},
+				Context{
+					Path:      cty.GetAttrPath("list").Index(cty.NumberIntVal(0)).Index(cty.NumberIntVal(3)),
+					WantValue: cty.StringVal("d"),
+				},
+				Context{
+					Path:      cty.GetAttrPath("list").Index(cty.NumberIntVal(0)).Index(cty.NumberIntVal(6)),
+					WantValue: cty.StringVal("g"),
+				},
+				ReplaceChange{
+					Path:     cty.GetAttrPath("list").Index(cty.NumberIntVal(0)).Index(cty.NumberIntVal(7)),
+					OldValue: cty.StringVal("h"),
+					NewValue: cty.StringVal("y"),
+				},
+				Context{
+					Path:      cty.GetAttrPath("list").Index(cty.NumberIntVal(1)),
+					WantValue: str("a"),
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewDiffWithOptions(tt.source, tt.target, tt.opts)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("wrong result\n%s", prettyDiff.Compare(tt.want, got))
+			}
+			applied, err := got.Apply(tt.source)
+			if err != nil {
+				t.Fatalf("Apply() err = %v", err)
+			}
+			if !applied.RawEquals(tt.target) {
+				t.Errorf("Apply\nGot\n%#v\nWant\n%#v", applied, tt.target)
+			}
+		})
+	}
+}
@@ -8,5 +8,11 @@
 // This package does not contain any functions for rendering a diff for
 // display to a user, since it is expected that a language building on cty
 // will want to use its own familiar syntax for presenting diffs and will thus
-// provide its own diff-presentation functionality.
+// provide its own diff-presentation functionality. The subpackage "render"
+// provides a framework for doing so, which takes care of arranging changes
+// into a nested structure so that a language need only provide the
+// formatting for its own syntax.
+//
+// The subpackage "funcs" provides functions for computing and applying
+// diffs from within a language that uses the cty/function package.
 package ctydiff
@@ -0,0 +1,85 @@
+// Package funcs provides cty functions for computing and applying diffs,
+// for languages built on cty that expose functions from the cty/function
+// package, such as HCL.
+//
+// The diff function returns a diff as a tuple of objects, one for each
+// change, so that configuration authors can inspect it and pass it to the
+// patch function to apply it to a value.
+package funcs
+
+import (
+	"github.com/zclconf/go-cty-diff/ctydiff"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// DiffFunc is a function that takes two values and returns a diff that
+// transforms the first into the second, in the representation returned by
+// ctydiff.Diff.ToValue: a tuple with an object for each change, describing
+// the kind of change, its path, and the values that it removes and adds.
+var DiffFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{
+			Name:             "old",
+			Type:             cty.DynamicPseudoType,
+			AllowNull:        true,
+			AllowDynamicType: true,
+		},
+		{
+			Name:             "new",
+			Type:             cty.DynamicPseudoType,
+			AllowNull:        true,
+			AllowDynamicType: true,
+		},
+	},
+	Type: function.StaticReturnType(cty.DynamicPseudoType),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		return ctydiff.NewDiff(args[0], args[1]).ToValue(), nil
+	},
+})
+
+// PatchFunc is a function that takes a value and a diff, as returned by
+// DiffFunc, and returns the result of applying the diff to the value. The
+// diff is converted using ctydiff.DiffFromValue.
+var PatchFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{
+			Name:             "value",
+			Type:             cty.DynamicPseudoType,
+			AllowNull:        true,
+			AllowDynamicType: true,
+		},
+		{
+			Name:             "diff",
+			Type:             cty.DynamicPseudoType,
+			AllowDynamicType: true,
+		},
+	},
+	Type: function.StaticReturnType(cty.DynamicPseudoType),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		if !args[1].IsWhollyKnown() {
+			return cty.DynamicVal, nil
+		}
+		d, err := ctydiff.DiffFromValue(args[1])
+		if err != nil {
+			return cty.NilVal, function.NewArgError(1, err)
+		}
+		ret, err := d.Apply(args[0])
+		if err != nil {
+			return cty.NilVal, err
+		}
+		return ret, nil
+	},
+})
+
+// Diff returns a diff that transforms the first given value into the
+// second, in the representation described for DiffFunc.
+func Diff(old, new cty.Value) (cty.Value, error) {
+	return DiffFunc.Call([]cty.Value{old, new})
+}
+
+// Patch returns the result of applying the given diff, in the
+// representation returned by DiffFunc, to the given value.
+func Patch(val, diff cty.Value) (cty.Value, error) {
+	return PatchFunc.Call([]cty.Value{val, diff})
+}
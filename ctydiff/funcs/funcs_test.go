@@ -0,0 +1,178 @@
+package funcs
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		old  cty.Value
+		new  cty.Value
+		want cty.Value
+	}{
+		{
+			"Equal",
+			cty.StringVal("a"),
+			cty.StringVal("a"),
+			cty.EmptyTupleVal,
+		},
+		{
+			"Replace",
+			cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("x")}),
+			cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("y")}),
+			cty.TupleVal([]cty.Value{
+				cty.ObjectVal(map[string]cty.Value{
+					"kind": cty.StringVal("replace"),
+					"path": cty.TupleVal([]cty.Value{
+						cty.ObjectVal(map[string]cty.Value{
+							"type": cty.StringVal("attr"),
+							"name": cty.StringVal("a"),
+						}),
+					}),
+					"old": cty.StringVal("x"),
+					"new": cty.StringVal("y"),
+				}),
+			}),
+		},
+		{
+			"Null",
+			cty.NullVal(cty.String),
+			cty.StringVal("a"),
+			cty.TupleVal([]cty.Value{
+				cty.ObjectVal(map[string]cty.Value{
+					"kind": cty.StringVal("replace"),
+					"path": cty.EmptyTupleVal,
+					"old":  cty.NullVal(cty.String),
+					"new":  cty.StringVal("a"),
+				}),
+			}),
+		},
+		{
+			"Unknown",
+			cty.UnknownVal(cty.String),
+			cty.StringVal("a"),
+			cty.DynamicVal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Diff(tt.old, tt.new)
+			if err != nil {
+				t.Fatalf("Diff() err = %v", err)
+			}
+			if !got.RawEquals(tt.want) {
+				t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatch(t *testing.T) {
+	tests := []struct {
+		name string
+		old  cty.Value
+		new  cty.Value
+	}{
+		{
+			"Object",
+			cty.ObjectVal(map[string]cty.Value{
+				"name": cty.StringVal("a"),
+				"tags": cty.MapVal(map[string]cty.Value{"env": cty.StringVal("dev")}),
+			}),
+			cty.ObjectVal(map[string]cty.Value{
+				"name": cty.StringVal("b"),
+				"tags": cty.MapVal(map[string]cty.Value{"team": cty.StringVal("core")}),
+			}),
+		},
+		{
+			"List",
+			cty.ListVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b"), cty.StringVal("c")}),
+			cty.ListVal([]cty.Value{cty.StringVal("b"), cty.StringVal("d"), cty.StringVal("c"), cty.StringVal("e")}),
+		},
+		{
+			"Set",
+			cty.SetVal([]cty.Value{
+				cty.ObjectVal(map[string]cty.Value{"id": cty.StringVal("a"), "n": cty.NumberIntVal(1)}),
+			}),
+			cty.SetVal([]cty.Value{
+				cty.ObjectVal(map[string]cty.Value{"id": cty.StringVal("a"), "n": cty.NumberIntVal(2)}),
+			}),
+		},
+		{
+			"Type",
+			cty.StringVal("a"),
+			cty.NumberIntVal(1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := Diff(tt.old, tt.new)
+			if err != nil {
+				t.Fatalf("Diff() err = %v", err)
+			}
+			got, err := Patch(tt.old, d)
+			if err != nil {
+				t.Fatalf("Patch() err = %v", err)
+			}
+			if !got.RawEquals(tt.new) {
+				t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, tt.new)
+			}
+		})
+	}
+}
+
+func TestPatch_errors(t *testing.T) {
+	tests := []struct {
+		name string
+		val  cty.Value
+		diff cty.Value
+		want string
+	}{
+		{
+			"NotTuple",
+			cty.StringVal("a"),
+			cty.StringVal("a"),
+			"diff must be a tuple or list",
+		},
+		{
+			"Conflict",
+			cty.StringVal("a"),
+			cty.TupleVal([]cty.Value{
+				cty.ObjectVal(map[string]cty.Value{
+					"kind": cty.StringVal("replace"),
+					"path": cty.EmptyTupleVal,
+					"old":  cty.StringVal("b"),
+					"new":  cty.StringVal("c"),
+				}),
+			}),
+			"existing value does not match",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Patch(tt.val, tt.diff)
+			if err == nil {
+				t.Fatal("Patch() succeeded; want error")
+			}
+			if got := err.Error(); got != tt.want {
+				t.Errorf("wrong error\ngot:  %s\nwant: %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatch_unknown(t *testing.T) {
+	got, err := Patch(cty.StringVal("a"), cty.DynamicVal)
+	if err != nil {
+		t.Fatalf("Patch() err = %v", err)
+	}
+	if !got.RawEquals(cty.DynamicVal) {
+		t.Errorf("wrong result %#v; want %#v", got, cty.DynamicVal)
+	}
+}
@@ -0,0 +1,270 @@
+//go:build go1.18
+// +build go1.18
+
+package ctydiff
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// FuzzDiff_Apply checks that applying arbitrary diffs to arbitrary values
+// returns an error rather than panicking.
+//
+// The fuzzer input is used as a sequence of choices from fixed pools of
+// values, paths and change types, so that the fuzzer can explore
+// combinations of them without having to discover valid cty values itself.
+func FuzzDiff_Apply(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{0, 0, 0, 0})
+	for i := 0; i < 64; i++ {
+		f.Add([]byte{byte(i), byte(i * 7), byte(i * 13), byte(i * 17), byte(i * 23), byte(i * 29)})
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := &fuzzChoices{data: data}
+		source := r.value()
+		diff := r.diff(2)
+		diff.Apply(source)
+	})
+}
+
+// FuzzNewDiff checks that applying the result of NewDiff to its source
+// value produces its target value.
+func FuzzNewDiff(f *testing.F) {
+	for i := 0; i < len(fuzzValues); i++ {
+		f.Add([]byte{byte(i), byte(i + 1)})
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := &fuzzChoices{data: data}
+		source, target := r.value(), r.value()
+		if source == cty.NilVal || target == cty.NilVal || !source.IsWhollyKnown() {
+			return
+		}
+		got, err := NewDiff(source, target).Apply(source)
+		if err != nil {
+			t.Fatalf("Apply() err = %v", err)
+		}
+		if !got.RawEquals(target) {
+			t.Fatalf("Apply\nGot\n%#v\nWant\n%#v", got, target)
+		}
+	})
+}
+
+// FuzzDiff_Invert checks that applying the inverse of the result of NewDiff
+// to its target value produces its source value.
+func FuzzDiff_Invert(f *testing.F) {
+	for i := 0; i < len(fuzzValues); i++ {
+		f.Add([]byte{byte(i), byte(i + 1)})
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := &fuzzChoices{data: data}
+		source, target := r.value(), r.value()
+		if source == cty.NilVal || target == cty.NilVal || !source.IsWhollyKnown() || !target.IsWhollyKnown() {
+			return
+		}
+		inverse, err := NewDiff(source, target).Invert()
+		if err != nil {
+			t.Fatalf("Invert() err = %v", err)
+		}
+		got, err := inverse.Apply(target)
+		if err != nil {
+			t.Fatalf("Apply() err = %v", err)
+		}
+		if !got.RawEquals(source) {
+			t.Fatalf("Apply\nGot\n%#v\nWant\n%#v", got, source)
+		}
+	})
+}
+
+// FuzzDiff_Check checks that Check reports a conflict for exactly the
+// changes that ApplyPartial fails to apply, with the same errors.
+func FuzzDiff_Check(f *testing.F) {
+	f.Add([]byte{})
+	for i := 0; i < 64; i++ {
+		f.Add([]byte{byte(i), byte(i * 7), byte(i * 13), byte(i * 17), byte(i * 23), byte(i * 29)})
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := &fuzzChoices{data: data}
+		source := r.value()
+		diff := r.diff(2)
+
+		_, results := diff.ApplyPartial(source)
+		var want []string
+		for _, r := range results {
+			if r.Status == ChangeFailed {
+				want = append(want, FormatPath(r.Path)+": "+r.Err.Error())
+			}
+		}
+		var got []string
+		for _, c := range diff.Check(source) {
+			got = append(got, FormatPath(c.Path)+": "+c.Err.Error())
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("wrong conflicts for %#v\ngot:  %q\nwant: %q", diff, got, want)
+		}
+	})
+}
+
+// FuzzParsePath checks that any path accepted by ParsePath is formatted by
+// FormatPath as a string that ParsePath parses as the same path.
+func FuzzParsePath(f *testing.F) {
+	f.Add("")
+	f.Add(`.a["b"][0][true]`)
+	f.Add(`."a b"[1.5]`)
+	f.Add(`[{"value":{"id":"a"},"type":["object",{"id":"string"}]}]`)
+
+	f.Fuzz(func(t *testing.T, s string) {
+		path, err := ParsePath(s)
+		if err != nil {
+			return
+		}
+		formatted := FormatPath(path)
+		got, err := ParsePath(formatted)
+		if err != nil {
+			t.Fatalf("ParsePath(%s) err = %v", formatted, err)
+		}
+		if !pathsEqual(got, path) {
+			t.Fatalf("ParsePath(%s) = %#v; want %#v", formatted, got, path)
+		}
+	})
+}
+
+type fuzzChoices struct {
+	data []byte
+}
+
+func (r *fuzzChoices) choose(n int) int {
+	if len(r.data) == 0 {
+		return 0
+	}
+	b := r.data[0]
+	r.data = r.data[1:]
+	return int(b) % n
+}
+
+func (r *fuzzChoices) diff(depth int) Diff {
+	n := r.choose(4)
+	diff := make(Diff, n)
+	for i := range diff {
+		diff[i] = r.change(depth)
+	}
+	return diff
+}
+
+func (r *fuzzChoices) change(depth int) Change {
+	switch r.choose(8) {
+	case 0:
+		return ReplaceChange{Path: r.path(), OldValue: r.value(), NewValue: r.value()}
+	case 1:
+		return DeleteChange{Path: r.path(), OldValue: r.value()}
+	case 2:
+		return InsertChange{Path: r.path(), NewValue: r.value(), BeforeValue: r.value(), ElementPath: r.choose(2) == 0}
+	case 3:
+		return AddChange{Path: r.path(), NewValue: r.value()}
+	case 4:
+		return RemoveChange{Path: r.path(), OldValue: r.value()}
+	case 5:
+		if depth > 0 {
+			return NestedDiff{Path: r.path(), OldValue: r.value(), Diff: r.diff(depth - 1)}
+		}
+		return nil
+	case 6:
+		return Context{Path: r.path(), WantValue: r.value()}
+	default:
+		return nil
+	}
+}
+
+func (r *fuzzChoices) path() cty.Path {
+	n := r.choose(4)
+	var path cty.Path
+	for i := 0; i < n; i++ {
+		path = append(path, r.step())
+	}
+	return path
+}
+
+func (r *fuzzChoices) step() cty.PathStep {
+	switch r.choose(4) {
+	case 0:
+		return cty.GetAttrStep{Name: fuzzAttrs[r.choose(len(fuzzAttrs))]}
+	case 1:
+		return cty.IndexStep{Key: fuzzKeys[r.choose(len(fuzzKeys))]}
+	case 2:
+		return cty.IndexStep{Key: r.value()}
+	default:
+		return nil
+	}
+}
+
+func (r *fuzzChoices) value() cty.Value {
+	return fuzzValues[r.choose(len(fuzzValues))]
+}
+
+var fuzzAttrs = []string{"a", "b", "c", ""}
+
+var fuzzKeys = []cty.Value{
+	cty.NilVal,
+	cty.NumberIntVal(0),
+	cty.NumberIntVal(1),
+	cty.NumberIntVal(-1),
+	cty.NumberIntVal(1 << 40),
+	cty.NumberFloatVal(0.5),
+	cty.PositiveInfinity,
+	cty.UnknownVal(cty.Number),
+	cty.NullVal(cty.Number),
+	cty.StringVal("a"),
+	cty.StringVal("b"),
+	cty.UnknownVal(cty.String),
+	cty.NullVal(cty.String),
+	cty.True,
+	cty.DynamicVal,
+}
+
+var fuzzValues = []cty.Value{
+	cty.NilVal,
+	cty.DynamicVal,
+	cty.NullVal(cty.DynamicPseudoType),
+	cty.StringVal("a"),
+	cty.StringVal("b"),
+	cty.NullVal(cty.String),
+	cty.UnknownVal(cty.String),
+	cty.NumberIntVal(1),
+	cty.True,
+	cty.ListValEmpty(cty.String),
+	cty.ListVal([]cty.Value{cty.StringVal("a")}),
+	cty.ListVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+	cty.ListVal([]cty.Value{cty.UnknownVal(cty.String)}),
+	cty.ListVal([]cty.Value{cty.ListVal([]cty.Value{cty.StringVal("a")})}),
+	cty.ListVal([]cty.Value{cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("a")})}),
+	cty.ListVal([]cty.Value{cty.TupleVal([]cty.Value{cty.StringVal("a")})}),
+	cty.UnknownVal(cty.List(cty.String)),
+	cty.NullVal(cty.List(cty.String)),
+	cty.MapValEmpty(cty.String),
+	cty.MapVal(map[string]cty.Value{"a": cty.StringVal("a")}),
+	cty.MapVal(map[string]cty.Value{"a": cty.StringVal("a"), "b": cty.StringVal("b")}),
+	cty.UnknownVal(cty.Map(cty.String)),
+	cty.SetValEmpty(cty.String),
+	cty.SetVal([]cty.Value{cty.StringVal("a")}),
+	cty.SetVal([]cty.Value{cty.StringVal("a"), cty.UnknownVal(cty.String)}),
+	cty.SetVal([]cty.Value{cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("a")})}),
+	cty.UnknownVal(cty.Set(cty.String)),
+	cty.EmptyTupleVal,
+	cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.NumberIntVal(1)}),
+	cty.UnknownVal(cty.Tuple([]cty.Type{cty.String})),
+	cty.EmptyObjectVal,
+	cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("a")}),
+	cty.ObjectVal(map[string]cty.Value{
+		"a": cty.ListVal([]cty.Value{cty.StringVal("a")}),
+		"b": cty.MapVal(map[string]cty.Value{"a": cty.StringVal("a")}),
+		"c": cty.SetVal([]cty.Value{cty.StringVal("a")}),
+	}),
+	cty.NullVal(cty.Object(map[string]cty.Type{"a": cty.String})),
+	cty.UnknownVal(cty.Object(map[string]cty.Type{"a": cty.String})),
+}
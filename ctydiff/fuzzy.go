@@ -0,0 +1,182 @@
+package ctydiff
+
+import (
+	"fmt"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// FuzzyOptions controls how ApplyFuzzy looks for list elements that have
+// moved since a diff was produced.
+type FuzzyOptions struct {
+	// Window is the greatest distance, in list elements, from its expected
+	// index at which ApplyFuzzy will look for the element that a change
+	// refers to. If Window is zero then ApplyFuzzy is equivalent to Apply.
+	Window int
+}
+
+// Relocation describes a change that ApplyFuzzy applied at a different
+// path than the one given in the diff.
+type Relocation struct {
+	// Change is the index of the change within the diff.
+	Change int
+
+	// Path is the path given in the change, and NewPath is the path at
+	// which the change was applied. They differ only in the index of a
+	// list element.
+	Path    cty.Path
+	NewPath cty.Path
+}
+
+// ApplyFuzzy is like Apply except that it tolerates list elements that
+// have been inserted or deleted since the diff was produced, in the manner
+// of the "fuzz" of patch(1).
+//
+// When a change fails and it records the value it expects to find, as
+// Context changes do along with changes whose OldValue or BeforeValue is
+// not cty.NilVal, ApplyFuzzy looks for that value at nearby indices of the
+// innermost list in the change's path, up to the distance given by the
+// Window option, trying the nearest indices first. If there is no match in
+// that list then the next list outwards is tried, and so on. Changes that
+// record no expected value are never relocated, since there would be
+// nothing to confirm that they had found the intended element.
+//
+// Once a change to a particular list has been relocated, the subsequent
+// changes to the same list are expected to have moved by the same distance,
+// so a diff that includes Context changes for the elements around each
+// change, as NewDiff produces, is relocated as a whole by its first Context
+// change.
+//
+// A NestedDiff is relocated as a whole, using its OldValue, but the changes
+// in its nested diff are applied as by Apply.
+//
+// ApplyFuzzy returns the new value along with a Relocation for each change
+// that was applied at a different path than the one it gave. As with
+// Apply, if any one change fails then the entire operation fails.
+func (d Diff) ApplyFuzzy(source cty.Value, opts FuzzyOptions) (cty.Value, []Relocation, error) {
+	f := &fuzzer{
+		opts:    opts,
+		offsets: make(map[string]int),
+	}
+	val := source
+	var relocs []Relocation
+	for i, c := range d {
+		if c == nil {
+			return cty.NilVal, nil, fmt.Errorf("change %d is nil", i)
+		}
+		v, path, err := f.apply(val, c)
+		if err != nil {
+			return cty.NilVal, nil, err
+		}
+		if path != nil {
+			relocs = append(relocs, Relocation{
+				Change:  i,
+				Path:    c.path().Copy(),
+				NewPath: path,
+			})
+		}
+		val = v
+	}
+	return val, relocs, nil
+}
+
+// fuzzer holds the state for a single call to ApplyFuzzy.
+type fuzzer struct {
+	opts FuzzyOptions
+
+	// offsets records the distance by which the elements of each list
+	// have moved, as found by relocating earlier changes. The lists are
+	// identified by their paths as given in the diff, in the syntax of
+	// FormatPath.
+	offsets map[string]int
+}
+
+// apply applies the given change to the given value, relocating it if
+// necessary. If the change was relocated then apply also returns the path
+// at which it was applied.
+func (f *fuzzer) apply(val cty.Value, c Change) (cty.Value, cty.Path, error) {
+	orig := c.path()
+	path, shifted := f.shift(orig)
+	ret, err := c.withPath(path).apply(val)
+	if err == nil {
+		if !shifted {
+			path = nil
+		}
+		return ret, path, nil
+	}
+	if f.opts.Window <= 0 || !isChecked(c) {
+		return cty.NilVal, nil, err
+	}
+
+	for i := len(path) - 1; i >= 0; i-- {
+		step, ok := path[i].(cty.IndexStep)
+		if !ok {
+			continue
+		}
+		list, lerr := applyPath(val, path[:i])
+		if lerr != nil || !isKnownSequence(list) {
+			continue
+		}
+		// An InsertChange may refer to the index just past the end of
+		// the list.
+		length := list.LengthInt()
+		idx, ierr := listIndex(step.Key, length+1)
+		if ierr != nil {
+			continue
+		}
+		for dist := 1; dist <= f.opts.Window; dist++ {
+			for _, try := range []int{idx - dist, idx + dist} {
+				if try < 0 || try > length {
+					continue
+				}
+				tryPath := path.Copy()
+				tryPath[i] = cty.IndexStep{Key: cty.NumberIntVal(int64(try))}
+				if ret, terr := c.withPath(tryPath).apply(val); terr == nil {
+					f.offsets[FormatPath(orig[:i])] += try - idx
+					return ret, tryPath, nil
+				}
+			}
+		}
+	}
+	return cty.NilVal, nil, err
+}
+
+// shift returns a copy of the given path with the index of each list
+// element moved by the offset so far recorded for its list, and whether
+// any index was moved.
+func (f *fuzzer) shift(path cty.Path) (cty.Path, bool) {
+	ret := path.Copy()
+	shifted := false
+	for i, step := range path {
+		offset := f.offsets[FormatPath(path[:i])]
+		if offset == 0 {
+			continue
+		}
+		// The recorded offset applies only to list indices, so any other
+		// step is left alone.
+		idx, ok := stepIndex(step)
+		if !ok {
+			continue
+		}
+		ret[i] = cty.IndexStep{Key: cty.NumberIntVal(int64(idx + offset))}
+		shifted = true
+	}
+	return ret, shifted
+}
+
+// isChecked returns true if the given change records a value that must be
+// present for it to apply, which ApplyFuzzy can use to confirm that it has
+// found the intended element of a list.
+func isChecked(c Change) bool {
+	switch c := c.(type) {
+	case ReplaceChange:
+		return c.OldValue != cty.NilVal
+	case DeleteChange:
+		return c.OldValue != cty.NilVal
+	case InsertChange:
+		return c.BeforeValue != cty.NilVal
+	case RemoveChange, NestedDiff, Context:
+		return true
+	}
+	return false
+}
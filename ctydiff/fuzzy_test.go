@@ -0,0 +1,171 @@
+package ctydiff
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestDiffApplyFuzzy(t *testing.T) {
+	strs := func(ss ...string) cty.Value {
+		vals := make([]cty.Value, len(ss))
+		for i, s := range ss {
+			vals[i] = cty.StringVal(s)
+		}
+		return cty.ListVal(vals)
+	}
+	index := func(i int) cty.Path {
+		return cty.IndexPath(cty.NumberIntVal(int64(i)))
+	}
+	items := func(ss ...string) cty.Value {
+		vals := make([]cty.Value, len(ss))
+		for i, s := range ss {
+			vals[i] = cty.ObjectVal(map[string]cty.Value{"name": cty.StringVal(s)})
+		}
+		return cty.ObjectVal(map[string]cty.Value{"items": cty.ListVal(vals)})
+	}
+	item := func(i int) cty.Path {
+		return cty.GetAttrPath("items").Index(cty.NumberIntVal(int64(i))).GetAttr("name")
+	}
+
+	tests := []struct {
+		name   string
+		diff   Diff
+		source cty.Value
+		window int
+		want   cty.Value
+		relocs []Relocation
+	}{
+		{
+			"Unmoved",
+			NewDiff(strs("a", "b", "c"), strs("a", "x", "c")),
+			strs("a", "b", "c"),
+			2,
+			strs("a", "x", "c"),
+			nil,
+		},
+		{
+			"InsertedBefore",
+			NewDiff(strs("a", "b", "c", "d"), strs("a", "b", "x", "d")),
+			strs("z", "a", "b", "c", "d"),
+			2,
+			strs("z", "a", "b", "x", "d"),
+			[]Relocation{
+				{Change: 0, Path: index(0), NewPath: index(1)},
+				{Change: 1, Path: index(1), NewPath: index(2)},
+				{Change: 2, Path: index(2), NewPath: index(3)},
+				{Change: 3, Path: index(3), NewPath: index(4)},
+			},
+		},
+		{
+			"DeletedBefore",
+			Diff{
+				DeleteChange{Path: index(3), OldValue: cty.StringVal("d")},
+				InsertChange{Path: index(3), NewValue: cty.StringVal("y"), BeforeValue: cty.NullVal(cty.String), ElementPath: true},
+			},
+			strs("b", "c", "d"),
+			1,
+			strs("b", "c", "y"),
+			[]Relocation{
+				{Change: 0, Path: index(3), NewPath: index(2)},
+				{Change: 1, Path: index(3), NewPath: index(2)},
+			},
+		},
+		{
+			"Append",
+			Diff{
+				InsertChange{Path: index(2), NewValue: cty.StringVal("c"), BeforeValue: cty.NullVal(cty.String), ElementPath: true},
+			},
+			strs("a", "b", "x", "y"),
+			2,
+			strs("a", "b", "x", "y", "c"),
+			[]Relocation{
+				{Change: 0, Path: index(2), NewPath: index(4)},
+			},
+		},
+		{
+			"Nested",
+			Diff{
+				ReplaceChange{Path: item(0), OldValue: cty.StringVal("a"), NewValue: cty.StringVal("x")},
+				ReplaceChange{Path: item(1), OldValue: cty.StringVal("b"), NewValue: cty.StringVal("y")},
+			},
+			items("z", "a", "b"),
+			1,
+			items("z", "x", "y"),
+			[]Relocation{
+				{Change: 0, Path: item(0), NewPath: item(1)},
+				{Change: 1, Path: item(1), NewPath: item(2)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, relocs, err := tt.diff.ApplyFuzzy(tt.source, FuzzyOptions{Window: tt.window})
+			if err != nil {
+				t.Fatalf("ApplyFuzzy() err = %v", err)
+			}
+			if !got.RawEquals(tt.want) {
+				t.Errorf("ApplyFuzzy\nGot\n%#v\nWant\n%#v", got, tt.want)
+			}
+			if !reflect.DeepEqual(relocs, tt.relocs) {
+				t.Errorf("wrong relocations\n%s", prettyDiff.Compare(tt.relocs, relocs))
+			}
+		})
+	}
+}
+
+func TestDiffApplyFuzzy_errors(t *testing.T) {
+	strs := func(ss ...string) cty.Value {
+		vals := make([]cty.Value, len(ss))
+		for i, s := range ss {
+			vals[i] = cty.StringVal(s)
+		}
+		return cty.ListVal(vals)
+	}
+
+	tests := []struct {
+		name   string
+		diff   Diff
+		source cty.Value
+		window int
+		want   string
+	}{
+		{
+			"OutsideWindow",
+			NewDiff(strs("a", "b"), strs("a", "x")),
+			strs("y", "z", "a", "b"),
+			1,
+			"existing value does not match",
+		},
+		{
+			"NoWindow",
+			NewDiff(strs("a", "b"), strs("a", "x")),
+			strs("z", "a", "b"),
+			0,
+			"existing value does not match",
+		},
+		{
+			"Unchecked",
+			Diff{
+				DeleteChange{Path: cty.IndexPath(cty.NumberIntVal(2))},
+			},
+			strs("a", "b"),
+			2,
+			"path does not exist in value: list index 2 is out of range",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := tt.diff.ApplyFuzzy(tt.source, FuzzyOptions{Window: tt.window})
+			if err == nil {
+				t.Fatal("ApplyFuzzy() succeeded; want error")
+			}
+			if got := err.Error(); got != tt.want {
+				t.Errorf("wrong error\ngot:  %s\nwant: %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package ctydiff
+
+import (
+	"reflect"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/gocty"
+)
+
+// DiffGo produces a Diff between two Go values, such as structs with
+// fields tagged with `cty:"..."`, by converting them to cty values of the
+// given type using gocty.ToCtyValue and then comparing them with NewDiff.
+//
+// If ty is cty.NilType then the type is instead implied from the Go type
+// of old, using gocty.ImpliedType. This is the type that ApplyGo uses, so
+// a diff that is to be applied with ApplyGo should be produced using it.
+// In particular, a diff produced using a set type for a Go slice addresses
+// elements by value rather than by index, and so cannot be applied to the
+// list that ApplyGo implies for that slice.
+func DiffGo(old, new interface{}, ty cty.Type) (Diff, error) {
+	if ty == cty.NilType {
+		var err error
+		ty, err = gocty.ImpliedType(old)
+		if err != nil {
+			return nil, err
+		}
+	}
+	oldVal, err := gocty.ToCtyValue(old, ty)
+	if err != nil {
+		return nil, err
+	}
+	newVal, err := gocty.ToCtyValue(new, ty)
+	if err != nil {
+		return nil, err
+	}
+	return NewDiff(oldVal, newVal), nil
+}
+
+// ApplyGo applies the given diff to the Go value that the given target
+// points to, such as a struct with fields tagged with `cty:"..."`.
+//
+// The target is converted to a cty value of the type returned by
+// gocty.ImpliedType, the diff is applied to that value, and the result is
+// written back to the target using gocty.FromCtyValue. The target is not
+// modified if the diff cannot be applied.
+//
+// ApplyGo panics if the target is not a non-nil pointer, since that is
+// considered to be a bug in the calling program.
+func ApplyGo(d Diff, target interface{}) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		panic("target value is not a non-nil pointer")
+	}
+
+	ty, err := gocty.ImpliedType(target)
+	if err != nil {
+		return err
+	}
+	source, err := gocty.ToCtyValue(target, ty)
+	if err != nil {
+		return err
+	}
+	result, err := d.Apply(source)
+	if err != nil {
+		return err
+	}
+
+	// We decode into a copy of the target so that a failure part-way
+	// through decoding does not leave the target partially updated. The
+	// copy starts out equal to the target so that any fields that have no
+	// cty tag, and thus are not represented in the value, are retained.
+	tmp := reflect.New(rv.Elem().Type())
+	tmp.Elem().Set(rv.Elem())
+	if err := gocty.FromCtyValue(result, tmp.Interface()); err != nil {
+		return err
+	}
+	rv.Elem().Set(tmp.Elem())
+	return nil
+}
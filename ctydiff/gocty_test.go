@@ -0,0 +1,159 @@
+package ctydiff
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+type testGoConfig struct {
+	Name    string            `cty:"name"`
+	Size    int               `cty:"size"`
+	Tags    map[string]string `cty:"tags"`
+	Servers []testGoServer    `cty:"servers"`
+	Comment string
+}
+
+type testGoServer struct {
+	Host string `cty:"host"`
+	Port *int   `cty:"port"`
+}
+
+func TestDiffGo(t *testing.T) {
+	port := 80
+	old := testGoConfig{
+		Name: "a",
+		Size: 1,
+		Tags: map[string]string{"env": "dev"},
+		Servers: []testGoServer{
+			{Host: "x"},
+			{Host: "y", Port: &port},
+		},
+	}
+	new := testGoConfig{
+		Name: "a",
+		Size: 2,
+		Tags: map[string]string{"env": "dev", "team": "core"},
+		Servers: []testGoServer{
+			{Host: "y", Port: &port},
+		},
+	}
+
+	serverTy := cty.Object(map[string]cty.Type{
+		"host": cty.String,
+		"port": cty.Number,
+	})
+	tests := []struct {
+		name string
+		ty   cty.Type
+	}{
+		{"Implied", cty.NilType},
+		{
+			"Explicit",
+			cty.Object(map[string]cty.Type{
+				"name":    cty.String,
+				"size":    cty.Number,
+				"tags":    cty.Map(cty.String),
+				"servers": cty.List(serverTy),
+			}),
+		},
+	}
+	want := Diff{
+		DeleteChange{
+			Path: cty.GetAttrPath("servers").Index(cty.NumberIntVal(0)),
+			OldValue: cty.ObjectVal(map[string]cty.Value{
+				"host": cty.StringVal("x"),
+				"port": cty.NullVal(cty.Number),
+			}),
+		},
+		Context{
+			Path: cty.GetAttrPath("servers").Index(cty.NumberIntVal(0)),
+			WantValue: cty.ObjectVal(map[string]cty.Value{
+				"host": cty.StringVal("y"),
+				"port": cty.NumberIntVal(80),
+			}),
+		},
+		ReplaceChange{
+			Path:     cty.GetAttrPath("size"),
+			OldValue: cty.NumberIntVal(1),
+			NewValue: cty.NumberIntVal(2),
+		},
+		ReplaceChange{
+			Path:     cty.GetAttrPath("tags").Index(cty.StringVal("team")),
+			OldValue: cty.NullVal(cty.String),
+			NewValue: cty.StringVal("core"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DiffGo(old, new, tt.ty)
+			if err != nil {
+				t.Fatalf("DiffGo() err = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("wrong diff\n%s", prettyDiff.Compare(want, got))
+			}
+		})
+	}
+}
+
+func TestDiffGo_error(t *testing.T) {
+	_, err := DiffGo(testGoConfig{}, testGoConfig{}, cty.String)
+	if err == nil {
+		t.Fatal("DiffGo() succeeded; want error")
+	}
+}
+
+func TestApplyGo(t *testing.T) {
+	port := 80
+	old := testGoConfig{
+		Name: "a",
+		Tags: map[string]string{"env": "dev"},
+		Servers: []testGoServer{
+			{Host: "x"},
+		},
+		Comment: "kept",
+	}
+	new := testGoConfig{
+		Name: "b",
+		Tags: map[string]string{"env": "prod"},
+		Servers: []testGoServer{
+			{Host: "x", Port: &port},
+			{Host: "y"},
+		},
+		Comment: "ignored",
+	}
+
+	d, err := DiffGo(old, new, cty.NilType)
+	if err != nil {
+		t.Fatalf("DiffGo() err = %v", err)
+	}
+	target := old
+	if err := ApplyGo(d, &target); err != nil {
+		t.Fatalf("ApplyGo() err = %v", err)
+	}
+	want := new
+	want.Comment = "kept"
+	if !reflect.DeepEqual(target, want) {
+		t.Errorf("wrong result\n%s", prettyDiff.Compare(want, target))
+	}
+}
+
+func TestApplyGo_conflict(t *testing.T) {
+	d := Diff{
+		ReplaceChange{
+			Path:     cty.GetAttrPath("name"),
+			OldValue: cty.StringVal("z"),
+			NewValue: cty.StringVal("b"),
+		},
+	}
+	target := testGoConfig{Name: "a"}
+	if err := ApplyGo(d, &target); err == nil {
+		t.Fatal("ApplyGo() succeeded; want error")
+	}
+	if want := (testGoConfig{Name: "a"}); !reflect.DeepEqual(target, want) {
+		t.Errorf("target was modified\n%s", prettyDiff.Compare(want, target))
+	}
+}
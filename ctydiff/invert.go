@@ -0,0 +1,135 @@
+package ctydiff
+
+import (
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Invert returns a diff that undoes the changes made by the receiver, so
+// that applying the receiver and then its inverse to a value produces the
+// original value.
+//
+// Each change must record the values it replaces or removes, so Invert
+// returns an error for changes whose old values are cty.NilVal, such as
+// those produced by FromJSONPatch. It also returns an error for an
+// InsertChange whose ElementPath is false, since the index of the inserted
+// element is not known, and for a DeleteChange of an object attribute,
+// which cannot be restored.
+//
+// Since the diff does not record the elements that follow an insertion or
+// deletion in a list, the inverted changes for those do not check the
+// neighbouring elements.
+func (d Diff) Invert() (Diff, error) {
+	ret := make(Diff, 0, len(d))
+	for i := len(d) - 1; i >= 0; i-- {
+		c, err := invertChange(d[i])
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, c)
+	}
+	return ret, nil
+}
+
+func invertChange(c Change) (Change, error) {
+	if c == nil {
+		return nil, cty.Path(nil).NewErrorf("change is nil")
+	}
+	path := c.path()
+	var last cty.PathStep
+	if len(path) > 0 {
+		last = path[len(path)-1]
+	}
+
+	switch c := c.(type) {
+	case ReplaceChange:
+		if c.OldValue == cty.NilVal {
+			return nil, path.NewErrorf("cannot invert a change whose old value is not recorded")
+		}
+		if c.OldValue.IsKnown() && c.OldValue.IsNull() && isMapKeyStep(last) {
+			// The change added a new map element.
+			return DeleteChange{
+				Path:     path,
+				OldValue: c.NewValue,
+			}, nil
+		}
+		return ReplaceChange{
+			Path:     path,
+			OldValue: c.NewValue,
+			NewValue: c.OldValue,
+		}, nil
+	case DeleteChange:
+		if c.OldValue == cty.NilVal {
+			return nil, path.NewErrorf("cannot invert a change whose old value is not recorded")
+		}
+		switch {
+		case isNumberStep(last):
+			return InsertChange{
+				Path:        path,
+				NewValue:    c.OldValue,
+				ElementPath: true,
+			}, nil
+		case isMapKeyStep(last):
+			return ReplaceChange{
+				Path:     path,
+				OldValue: cty.NullVal(c.OldValue.Type()),
+				NewValue: c.OldValue,
+			}, nil
+		default:
+			return nil, path.NewErrorf("cannot invert the deletion of an object attribute")
+		}
+	case InsertChange:
+		if !c.ElementPath || !isNumberStep(last) {
+			return nil, path.NewErrorf("cannot invert an insertion whose index is not recorded")
+		}
+		return DeleteChange{
+			Path:     path,
+			OldValue: c.NewValue,
+		}, nil
+	case AddChange:
+		return RemoveChange{
+			Path:     path,
+			OldValue: c.NewValue,
+		}, nil
+	case RemoveChange:
+		return AddChange{
+			Path:     path,
+			NewValue: c.OldValue,
+		}, nil
+	case NestedDiff:
+		if c.OldValue == cty.NilVal {
+			return nil, path.NewErrorf("cannot invert a change whose old value is not recorded")
+		}
+		newValue, err := c.Diff.Apply(c.OldValue)
+		if err != nil {
+			return nil, path.NewError(err)
+		}
+		inverted, err := c.Diff.Invert()
+		if err != nil {
+			return nil, path.NewError(err)
+		}
+		if index, ok := last.(cty.IndexStep); ok && rawEquals(index.Key, c.OldValue) {
+			// The path addresses a set element by its value, which the
+			// nested diff has changed.
+			path = append(path[:len(path)-1:len(path)-1], cty.IndexStep{Key: newValue})
+		}
+		return NestedDiff{
+			Path:     path,
+			OldValue: newValue,
+			Diff:     inverted,
+		}, nil
+	case Context:
+		return c, nil
+	default:
+		return nil, path.NewErrorf("unsupported change type %T", c)
+	}
+}
+
+// isMapKeyStep returns true if the given step selects a map element.
+func isMapKeyStep(step cty.PathStep) bool {
+	index, ok := step.(cty.IndexStep)
+	if !ok {
+		return false
+	}
+	_, err := mapKey(index.Key)
+	return err == nil
+}
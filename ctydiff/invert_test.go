@@ -0,0 +1,173 @@
+package ctydiff
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestDiff_Invert(t *testing.T) {
+	tests := []struct {
+		name   string
+		source cty.Value
+		diff   Diff
+	}{
+		{
+			"NewDiff",
+			cty.ObjectVal(map[string]cty.Value{
+				"name": cty.StringVal("a"),
+				"list": cty.ListVal([]cty.Value{cty.StringVal("w"), cty.StringVal("x"), cty.StringVal("y")}),
+				"tags": cty.MapVal(map[string]cty.Value{"env": cty.StringVal("dev"), "old": cty.StringVal("o")}),
+				"set":  cty.SetVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+			}),
+			NewDiff(
+				cty.ObjectVal(map[string]cty.Value{
+					"name": cty.StringVal("a"),
+					"list": cty.ListVal([]cty.Value{cty.StringVal("w"), cty.StringVal("x"), cty.StringVal("y")}),
+					"tags": cty.MapVal(map[string]cty.Value{"env": cty.StringVal("dev"), "old": cty.StringVal("o")}),
+					"set":  cty.SetVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+				}),
+				cty.ObjectVal(map[string]cty.Value{
+					"name": cty.StringVal("b"),
+					"list": cty.ListVal([]cty.Value{cty.StringVal("v"), cty.StringVal("x"), cty.StringVal("z"), cty.StringVal("q")}),
+					"tags": cty.MapVal(map[string]cty.Value{"env": cty.StringVal("prod"), "new": cty.StringVal("n")}),
+					"set":  cty.SetVal([]cty.Value{cty.StringVal("b"), cty.StringVal("c")}),
+				}),
+			),
+		},
+		{
+			"ListDeletions",
+			cty.ListVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b"), cty.StringVal("c"), cty.StringVal("d")}),
+			Diff{
+				DeleteChange{
+					Path:     cty.IndexPath(cty.NumberIntVal(1)),
+					OldValue: cty.StringVal("b"),
+				},
+				DeleteChange{
+					Path:     cty.IndexPath(cty.NumberIntVal(1)),
+					OldValue: cty.StringVal("c"),
+				},
+			},
+		},
+		{
+			"NestedSetElement",
+			cty.SetVal([]cty.Value{
+				cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("A")}),
+			}),
+			Diff{
+				NestedDiff{
+					Path:     cty.IndexPath(cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("A")})),
+					OldValue: cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("A")}),
+					Diff: Diff{
+						ReplaceChange{
+							Path:     cty.GetAttrPath("a"),
+							OldValue: cty.StringVal("A"),
+							NewValue: cty.StringVal("B"),
+						},
+					},
+				},
+			},
+		},
+		{
+			"Context",
+			cty.ListVal([]cty.Value{cty.StringVal("a")}),
+			Diff{
+				Context{
+					Path:      cty.IndexPath(cty.NumberIntVal(0)),
+					WantValue: cty.StringVal("a"),
+				},
+				InsertChange{
+					Path:        cty.IndexPath(cty.NumberIntVal(1)),
+					NewValue:    cty.StringVal("b"),
+					BeforeValue: cty.NullVal(cty.String),
+					ElementPath: true,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := tt.diff.Apply(tt.source)
+			if err != nil {
+				t.Fatalf("Apply() err = %v", err)
+			}
+			inverse, err := tt.diff.Invert()
+			if err != nil {
+				t.Fatalf("Invert() err = %v", err)
+			}
+			got, err := inverse.Apply(target)
+			if err != nil {
+				t.Fatalf("Apply(Invert()) err = %v\n%s", err, prettyDiff.Sprint(inverse))
+			}
+			if !got.RawEquals(tt.source) {
+				t.Errorf("Apply(Invert())\nGot\n%#v\nWant\n%#v", got, tt.source)
+			}
+		})
+	}
+}
+
+func TestDiff_InvertErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		diff Diff
+	}{
+		{
+			"Nil",
+			Diff{nil},
+		},
+		{
+			"ReplaceUnchecked",
+			Diff{
+				ReplaceChange{
+					Path:     cty.GetAttrPath("a"),
+					NewValue: cty.StringVal("A"),
+				},
+			},
+		},
+		{
+			"DeleteAttribute",
+			Diff{
+				DeleteChange{
+					Path:     cty.GetAttrPath("a"),
+					OldValue: cty.StringVal("A"),
+				},
+			},
+		},
+		{
+			"InsertBefore",
+			Diff{
+				InsertChange{
+					Path:        cty.GetAttrPath("a"),
+					NewValue:    cty.StringVal("A"),
+					BeforeValue: cty.StringVal("B"),
+					ElementPath: true,
+				},
+			},
+		},
+		{
+			"NestedInvalid",
+			Diff{
+				NestedDiff{
+					Path:     cty.GetAttrPath("a"),
+					OldValue: cty.StringVal("A"),
+					Diff: Diff{
+						DeleteChange{
+							Path:     cty.GetAttrPath("b"),
+							OldValue: cty.StringVal("B"),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.diff.Invert()
+			if err == nil {
+				t.Fatalf("Invert() succeeded; want error\n%s", prettyDiff.Sprint(got))
+			}
+		})
+	}
+}
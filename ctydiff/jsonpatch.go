@@ -0,0 +1,354 @@
+package ctydiff
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// jsonPatchOp is a single operation in a JSON Patch document.
+type jsonPatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// ToJSONPatch returns a JSON Patch document, as defined in RFC 6902, that
+// makes the same changes as the receiver to the JSON encoding of a value.
+//
+// Each ReplaceChange becomes a "replace" operation, or an "add" operation
+// when it adds a new element to a map. DeleteChange becomes "remove",
+// InsertChange becomes "add" and Context becomes "test". NestedDiff changes
+// are flattened as with Flatten. The old values of the other changes are
+// not represented, so only the Context changes are checked by the patch.
+//
+// JSON Pointer has no way to address the elements of a set, so ToJSONPatch
+// returns an error if the diff contains an AddChange or a RemoveChange, or
+// any change whose path passes through a set. Such diffs can be represented
+// only by replacing the whole set. Unknown values also cannot be represented
+// and cause an error.
+func (d Diff) ToJSONPatch() ([]byte, error) {
+	ops := []jsonPatchOp{}
+	for _, c := range d.Flatten() {
+		op, err := jsonPatchOpFor(c)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, op)
+	}
+	return json.Marshal(ops)
+}
+
+func jsonPatchOpFor(c Change) (jsonPatchOp, error) {
+	var op jsonPatchOp
+	var val cty.Value
+	path := c.path()
+	switch c := c.(type) {
+	case ReplaceChange:
+		op.Op = "replace"
+		if len(path) > 0 && isKeyStep(path[len(path)-1]) && c.OldValue != cty.NilVal && c.OldValue.IsKnown() && c.OldValue.IsNull() {
+			op.Op = "add"
+		}
+		val = c.NewValue
+	case DeleteChange:
+		op.Op = "remove"
+	case InsertChange:
+		op.Op = "add"
+		val = c.NewValue
+		if !c.ElementPath {
+			// The path is to the list itself, which is supported only for
+			// appending to the list.
+			if c.BeforeValue != cty.NilVal && !c.BeforeValue.IsNull() {
+				return op, path.NewErrorf("JSON Patch cannot insert before an element selected by value")
+			}
+			ptr, err := jsonPointer(path)
+			if err != nil {
+				return op, err
+			}
+			op.Path = ptr + "/-"
+		}
+	case Context:
+		op.Op = "test"
+		val = c.WantValue
+	default:
+		return op, path.NewErrorf("JSON Patch cannot represent changes to set elements")
+	}
+
+	if op.Path == "" {
+		ptr, err := jsonPointer(path)
+		if err != nil {
+			return op, err
+		}
+		op.Path = ptr
+	}
+	if op.Op != "remove" {
+		if val == cty.NilVal {
+			return op, path.NewErrorf("value is missing")
+		}
+		raw, err := ctyjson.Marshal(val, val.Type())
+		if err != nil {
+			return op, path.NewError(err)
+		}
+		op.Value = raw
+	}
+	return op, nil
+}
+
+// jsonPointer returns the JSON Pointer, as defined in RFC 6901, for the
+// given path.
+func jsonPointer(path cty.Path) (string, error) {
+	var buf strings.Builder
+	for i, step := range path {
+		buf.WriteString("/")
+		switch step := step.(type) {
+		case cty.GetAttrStep:
+			buf.WriteString(escapePointerToken(step.Name))
+		case cty.IndexStep:
+			if key, err := mapKey(step.Key); err == nil {
+				buf.WriteString(escapePointerToken(key))
+				continue
+			}
+			if !isNumberStep(step) {
+				return "", path[:i+1].NewErrorf("JSON Pointer cannot address set elements")
+			}
+			idx, err := listIndex(step.Key, int(^uint(0)>>1))
+			if err != nil {
+				return "", path[:i+1].NewError(err)
+			}
+			buf.WriteString(strconv.Itoa(idx))
+		default:
+			return "", path[:i+1].NewErrorf("unsupported path step %T", step)
+		}
+	}
+	return buf.String(), nil
+}
+
+func escapePointerToken(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+func unescapePointerToken(s string) string {
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(s)
+}
+
+func isKeyStep(step cty.PathStep) bool {
+	switch step := step.(type) {
+	case cty.GetAttrStep:
+		return true
+	case cty.IndexStep:
+		_, err := mapKey(step.Key)
+		return err == nil
+	}
+	return false
+}
+
+func isNumberStep(step cty.PathStep) bool {
+	index, ok := step.(cty.IndexStep)
+	return ok && index.Key != cty.NilVal && index.Key.Type().Equals(cty.Number)
+}
+
+// FromJSONPatch converts the given JSON Patch document, as defined in
+// RFC 6902, into a diff that makes the same changes to a value of the given
+// type.
+//
+// The type is used to interpret the paths and values of the operations. An
+// "add" operation becomes an InsertChange for a list element and a
+// ReplaceChange otherwise, "remove" becomes a DeleteChange, "replace"
+// becomes a ReplaceChange and "test" becomes a Context. Since a JSON Patch
+// does not record the values that it replaces or removes, the resulting
+// changes have cty.NilVal old values and so do not check the existing
+// values.
+//
+// Object attributes cannot be removed from a cty object, so removing an
+// object attribute sets it to null instead. The "move" and "copy"
+// operations, and paths that pass through sets or values of dynamic type,
+// are not supported. Appending with the "-" index is supported only for
+// lists that are not themselves directly within a list.
+func FromJSONPatch(ty cty.Type, patch []byte) (Diff, error) {
+	var ops []jsonPatchOp
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("invalid JSON Patch document: %s", err)
+	}
+	diff := make(Diff, 0, len(ops))
+	for i, op := range ops {
+		c, err := changeFromJSONPatchOp(ty, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %q): %s", i, op.Op, op.Path, err)
+		}
+		diff = append(diff, c)
+	}
+	return diff, nil
+}
+
+func changeFromJSONPatchOp(ty cty.Type, op jsonPatchOp) (Change, error) {
+	switch op.Op {
+	case "add", "remove", "replace", "test":
+	case "move", "copy":
+		return nil, fmt.Errorf("unsupported operation %q", op.Op)
+	default:
+		return nil, fmt.Errorf("unknown operation %q", op.Op)
+	}
+
+	target, err := resolveJSONPointer(ty, op.Path)
+	if err != nil {
+		return nil, err
+	}
+	if target.end && op.Op != "add" {
+		return nil, errors.New(`the "-" index can be used only to add an element`)
+	}
+	var val cty.Value
+	if op.Op != "remove" {
+		val, err = unmarshalJSONPatchValue(op.Value, target.ty)
+		if err != nil {
+			return nil, err
+		}
+	}
+	parent := target.parentTy
+	isRoot := len(target.path) == 0
+
+	switch op.Op {
+	case "add":
+		switch {
+		case target.end:
+			return InsertChange{Path: target.path, NewValue: val}, nil
+		case isRoot || parent.IsObjectType():
+			return ReplaceChange{Path: target.path, NewValue: val}, nil
+		case parent.IsMapType():
+			return ReplaceChange{Path: target.path, OldValue: cty.NullVal(target.ty), NewValue: val}, nil
+		case parent.IsListType():
+			return InsertChange{Path: target.path, NewValue: val, ElementPath: true}, nil
+		default:
+			return nil, errors.New("cannot add an element to a tuple")
+		}
+	case "remove":
+		switch {
+		case isRoot:
+			return nil, errors.New("cannot remove the entire value")
+		case parent.IsObjectType():
+			return ReplaceChange{Path: target.path, NewValue: cty.NullVal(target.ty)}, nil
+		case parent.IsTupleType():
+			return nil, errors.New("cannot remove an element from a tuple")
+		default:
+			return DeleteChange{Path: target.path}, nil
+		}
+	case "replace":
+		return ReplaceChange{Path: target.path, NewValue: val}, nil
+	default: // "test"
+		return Context{Path: target.path, WantValue: val}, nil
+	}
+}
+
+// jsonPointerTarget describes the location that a JSON Pointer refers to
+// within a value of a particular type.
+type jsonPointerTarget struct {
+	// path is the path of the location, or of the list being appended to
+	// if end is true.
+	path cty.Path
+
+	// ty is the type of the value at the location, and parentTy the type
+	// of its container.
+	ty, parentTy cty.Type
+
+	// end is true if the pointer ends with the "-" token, which refers to
+	// the position after the last element of a list.
+	end bool
+}
+
+func resolveJSONPointer(ty cty.Type, ptr string) (jsonPointerTarget, error) {
+	target := jsonPointerTarget{path: cty.Path{}, ty: ty}
+	if ptr == "" {
+		return target, nil
+	}
+	if !strings.HasPrefix(ptr, "/") {
+		return target, errors.New("JSON Pointer must be empty or start with a slash")
+	}
+	tokens := strings.Split(ptr[1:], "/")
+	for i, token := range tokens {
+		token = unescapePointerToken(token)
+		cur := target.ty
+		if cur.IsListType() && token == "-" && i == len(tokens)-1 {
+			if len(target.path) > 0 && (target.parentTy.IsListType() || target.parentTy.IsTupleType()) {
+				return target, errors.New(`the "-" index is not supported for a list within a list`)
+			}
+			target.ty = cur.ElementType()
+			target.parentTy = cur
+			target.end = true
+			return target, nil
+		}
+
+		switch {
+		case cur.IsObjectType():
+			if !cur.HasAttribute(token) {
+				return target, fmt.Errorf("object has no attribute %q", token)
+			}
+			target.path = target.path.GetAttr(token)
+			target.ty = cur.AttributeType(token)
+		case cur.IsMapType():
+			target.path = target.path.Index(cty.StringVal(token))
+			target.ty = cur.ElementType()
+		case cur.IsListType() || cur.IsTupleType():
+			idx, err := parsePointerIndex(token)
+			if err != nil {
+				return target, err
+			}
+			if cur.IsTupleType() {
+				etys := cur.TupleElementTypes()
+				if idx >= len(etys) {
+					return target, fmt.Errorf("tuple index %d is out of range", idx)
+				}
+				target.ty = etys[idx]
+			} else {
+				target.ty = cur.ElementType()
+			}
+			target.path = target.path.Index(cty.NumberIntVal(int64(idx)))
+		case cur.IsSetType():
+			return target, errors.New("JSON Pointer cannot address set elements")
+		case cur.Equals(cty.DynamicPseudoType):
+			return target, errors.New("cannot address elements of a value of dynamic type")
+		default:
+			return target, fmt.Errorf("cannot address elements of a value of type %s", cur.FriendlyName())
+		}
+		target.parentTy = cur
+	}
+	return target, nil
+}
+
+// parsePointerIndex parses an array index token from a JSON Pointer, which
+// must be a non-negative decimal integer without leading zeros.
+func parsePointerIndex(token string) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.TrimLeft(token, "0123456789") != "" {
+		return 0, fmt.Errorf("invalid list index %q", token)
+	}
+	idx, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, fmt.Errorf("invalid list index %q", token)
+	}
+	return idx, nil
+}
+
+// unmarshalJSONPatchValue decodes the value of a JSON Patch operation as a
+// value of the given type, or of the type implied by the JSON if the given
+// type is cty.DynamicPseudoType.
+func unmarshalJSONPatchValue(raw json.RawMessage, ty cty.Type) (cty.Value, error) {
+	if raw == nil {
+		return cty.NilVal, errors.New("operation has no value")
+	}
+	if ty.Equals(cty.DynamicPseudoType) {
+		implied, err := ctyjson.ImpliedType(raw)
+		if err != nil {
+			return cty.NilVal, fmt.Errorf("invalid value: %s", err)
+		}
+		ty = implied
+	}
+	val, err := ctyjson.Unmarshal(raw, ty)
+	if err != nil {
+		return cty.NilVal, fmt.Errorf("invalid value: %s", err)
+	}
+	return val, nil
+}
@@ -0,0 +1,328 @@
+package ctydiff
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestDiff_ToJSONPatch(t *testing.T) {
+	tests := []struct {
+		name string
+		diff Diff
+		want string
+	}{
+		{
+			"Empty",
+			nil,
+			`[]`,
+		},
+		{
+			"Replace",
+			Diff{
+				ReplaceChange{
+					Path:     cty.GetAttrPath("a/b").GetAttr("c~d"),
+					OldValue: cty.StringVal("A"),
+					NewValue: cty.StringVal("B"),
+				},
+				ReplaceChange{
+					Path:     cty.Path{},
+					OldValue: cty.True,
+					NewValue: cty.NullVal(cty.Bool),
+				},
+			},
+			`[{"op":"replace","path":"/a~1b/c~0d","value":"B"},{"op":"replace","path":"","value":null}]`,
+		},
+		{
+			"ReplaceAddMapElement",
+			Diff{
+				ReplaceChange{
+					Path:     cty.IndexPath(cty.StringVal("k")),
+					OldValue: cty.NullVal(cty.Number),
+					NewValue: cty.NumberIntVal(1),
+				},
+			},
+			`[{"op":"add","path":"/k","value":1}]`,
+		},
+		{
+			"Delete",
+			Diff{
+				DeleteChange{
+					Path:     cty.GetAttrPath("list").Index(cty.NumberIntVal(2)),
+					OldValue: cty.StringVal("A"),
+				},
+			},
+			`[{"op":"remove","path":"/list/2"}]`,
+		},
+		{
+			"Insert",
+			Diff{
+				InsertChange{
+					Path:        cty.GetAttrPath("list").Index(cty.NumberIntVal(0)),
+					NewValue:    cty.StringVal("A"),
+					BeforeValue: cty.StringVal("B"),
+					ElementPath: true,
+				},
+				InsertChange{
+					Path:        cty.GetAttrPath("list"),
+					NewValue:    cty.StringVal("C"),
+					BeforeValue: cty.NullVal(cty.String),
+				},
+			},
+			`[{"op":"add","path":"/list/0","value":"A"},{"op":"add","path":"/list/-","value":"C"}]`,
+		},
+		{
+			"Context",
+			Diff{
+				Context{
+					Path:      cty.GetAttrPath("a"),
+					WantValue: cty.ListVal([]cty.Value{cty.StringVal("A")}),
+				},
+			},
+			`[{"op":"test","path":"/a","value":["A"]}]`,
+		},
+		{
+			"Nested",
+			Diff{
+				NestedDiff{
+					Path:     cty.GetAttrPath("a"),
+					OldValue: cty.MapVal(map[string]cty.Value{"b": cty.StringVal("B")}),
+					Diff: Diff{
+						DeleteChange{
+							Path:     cty.IndexPath(cty.StringVal("b")),
+							OldValue: cty.StringVal("B"),
+						},
+					},
+				},
+			},
+			`[{"op":"test","path":"/a","value":{"b":"B"}},{"op":"remove","path":"/a/b"}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.diff.ToJSONPatch()
+			if err != nil {
+				t.Fatalf("ToJSONPatch() err = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("wrong result\ngot:  %s\nwant: %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiff_ToJSONPatchErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		diff Diff
+	}{
+		{
+			"Add",
+			Diff{
+				AddChange{
+					Path:     cty.GetAttrPath("set"),
+					NewValue: cty.StringVal("A"),
+				},
+			},
+		},
+		{
+			"Remove",
+			Diff{
+				RemoveChange{
+					Path:     cty.GetAttrPath("set"),
+					OldValue: cty.StringVal("A"),
+				},
+			},
+		},
+		{
+			"SetElementPath",
+			Diff{
+				ReplaceChange{
+					Path:     cty.IndexPath(cty.True),
+					OldValue: cty.True,
+					NewValue: cty.False,
+				},
+			},
+		},
+		{
+			"InsertBeforeValue",
+			Diff{
+				InsertChange{
+					Path:        cty.GetAttrPath("list"),
+					NewValue:    cty.StringVal("A"),
+					BeforeValue: cty.StringVal("B"),
+				},
+			},
+		},
+		{
+			"Unknown",
+			Diff{
+				ReplaceChange{
+					Path:     cty.GetAttrPath("a"),
+					OldValue: cty.StringVal("A"),
+					NewValue: cty.UnknownVal(cty.String),
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.diff.ToJSONPatch()
+			if err == nil {
+				t.Fatalf("ToJSONPatch() succeeded; want error\nGot %s", got)
+			}
+		})
+	}
+}
+
+func TestFromJSONPatch(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"name": cty.String,
+		"list": cty.List(cty.String),
+		"tags": cty.Map(cty.String),
+		"any":  cty.DynamicPseudoType,
+	})
+	source := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("a"),
+		"list": cty.ListVal([]cty.Value{cty.StringVal("x"), cty.StringVal("y")}),
+		"tags": cty.MapVal(map[string]cty.Value{"env": cty.StringVal("dev")}),
+		"any":  cty.NullVal(cty.DynamicPseudoType),
+	})
+	patch := `[
+		{"op": "test", "path": "/name", "value": "a"},
+		{"op": "replace", "path": "/name", "value": "b"},
+		{"op": "add", "path": "/list/0", "value": "w"},
+		{"op": "add", "path": "/list/-", "value": "z"},
+		{"op": "remove", "path": "/list/2"},
+		{"op": "add", "path": "/tags/team", "value": "core"},
+		{"op": "remove", "path": "/tags/env"},
+		{"op": "add", "path": "/any", "value": ["a", true]}
+	]`
+
+	got, err := FromJSONPatch(ty, []byte(patch))
+	if err != nil {
+		t.Fatalf("FromJSONPatch() err = %v", err)
+	}
+	want := Diff{
+		Context{
+			Path:      cty.GetAttrPath("name"),
+			WantValue: cty.StringVal("a"),
+		},
+		ReplaceChange{
+			Path:     cty.GetAttrPath("name"),
+			NewValue: cty.StringVal("b"),
+		},
+		InsertChange{
+			Path:        cty.GetAttrPath("list").Index(cty.NumberIntVal(0)),
+			NewValue:    cty.StringVal("w"),
+			ElementPath: true,
+		},
+		InsertChange{
+			Path:     cty.GetAttrPath("list"),
+			NewValue: cty.StringVal("z"),
+		},
+		DeleteChange{
+			Path: cty.GetAttrPath("list").Index(cty.NumberIntVal(2)),
+		},
+		ReplaceChange{
+			Path:     cty.GetAttrPath("tags").Index(cty.StringVal("team")),
+			OldValue: cty.NullVal(cty.String),
+			NewValue: cty.StringVal("core"),
+		},
+		DeleteChange{
+			Path: cty.GetAttrPath("tags").Index(cty.StringVal("env")),
+		},
+		ReplaceChange{
+			Path:     cty.GetAttrPath("any"),
+			NewValue: cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.True}),
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong result\n%s", prettyDiff.Compare(want, got))
+	}
+
+	applied, err := got.Apply(source)
+	if err != nil {
+		t.Fatalf("Apply() err = %v", err)
+	}
+	wantValue := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("b"),
+		"list": cty.ListVal([]cty.Value{cty.StringVal("w"), cty.StringVal("x"), cty.StringVal("z")}),
+		"tags": cty.MapVal(map[string]cty.Value{"team": cty.StringVal("core")}),
+		"any":  cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.True}),
+	})
+	if !applied.RawEquals(wantValue) {
+		t.Errorf("Apply\nGot\n%#v\nWant\n%#v", applied, wantValue)
+	}
+}
+
+func TestFromJSONPatch_roundTrip(t *testing.T) {
+	source := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("a"),
+		"list": cty.ListVal([]cty.Value{cty.StringVal("x"), cty.StringVal("y")}),
+		"tags": cty.MapVal(map[string]cty.Value{"env": cty.StringVal("dev")}),
+	})
+	target := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("b"),
+		"list": cty.ListVal([]cty.Value{cty.StringVal("y"), cty.StringVal("z")}),
+		"tags": cty.MapVal(map[string]cty.Value{"team": cty.StringVal("core")}),
+	})
+
+	patch, err := NewDiff(source, target).ToJSONPatch()
+	if err != nil {
+		t.Fatalf("ToJSONPatch() err = %v", err)
+	}
+	diff, err := FromJSONPatch(source.Type(), patch)
+	if err != nil {
+		t.Fatalf("FromJSONPatch() err = %v", err)
+	}
+	got, err := diff.Apply(source)
+	if err != nil {
+		t.Fatalf("Apply() err = %v", err)
+	}
+	if !got.RawEquals(target) {
+		t.Errorf("Apply\nGot\n%#v\nWant\n%#v", got, target)
+	}
+}
+
+func TestFromJSONPatchErrors(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"name":   cty.String,
+		"set":    cty.Set(cty.String),
+		"tuple":  cty.Tuple([]cty.Type{cty.String}),
+		"any":    cty.DynamicPseudoType,
+		"nested": cty.List(cty.List(cty.String)),
+	})
+	tests := []struct {
+		name  string
+		patch string
+	}{
+		{"InvalidJSON", `{`},
+		{"UnknownOp", `[{"op": "frob", "path": "/name"}]`},
+		{"Move", `[{"op": "move", "from": "/name", "path": "/name"}]`},
+		{"MissingValue", `[{"op": "replace", "path": "/name"}]`},
+		{"WrongType", `[{"op": "replace", "path": "/name", "value": {}}]`},
+		{"RelativePointer", `[{"op": "replace", "path": "name", "value": "a"}]`},
+		{"NoAttribute", `[{"op": "replace", "path": "/nope", "value": "a"}]`},
+		{"SetElement", `[{"op": "remove", "path": "/set/0"}]`},
+		{"DynamicElement", `[{"op": "remove", "path": "/any/0"}]`},
+		{"TupleAdd", `[{"op": "add", "path": "/tuple/0", "value": "a"}]`},
+		{"TupleOutOfRange", `[{"op": "replace", "path": "/tuple/1", "value": "a"}]`},
+		{"LeadingZero", `[{"op": "remove", "path": "/nested/01"}]`},
+		{"EndReplace", `[{"op": "replace", "path": "/nested/-", "value": []}]`},
+		{"EndNested", `[{"op": "add", "path": "/nested/0/-", "value": "a"}]`},
+		{"RemoveRoot", `[{"op": "remove", "path": ""}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromJSONPatch(ty, []byte(tt.patch))
+			if err == nil {
+				t.Fatalf("FromJSONPatch() succeeded; want error\n%s", prettyDiff.Sprint(got))
+			}
+		})
+	}
+}
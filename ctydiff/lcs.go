@@ -4,12 +4,20 @@ import (
 	"github.com/zclconf/go-cty/cty"
 )
 
+// lcsTableMaxCells is the largest number of cells, one for each pair of
+// elements, for which longestCommonSubsequence uses a full table. Each cell
+// takes an int and a bool, so this limits the table to a few megabytes.
+const lcsTableMaxCells = 1 << 20
+
 // longestCommonSubsequence finds a sequence of values that are common to both
 // x and y, with the same relative ordering as in both collections.
 //
 // The approached used here is a "naive" one, assuming that both x and y will
-// generally be small in the use-cases cty is aimed at. For larger lists
-// the time/space usage may be sub-optimal.
+// generally be small in the use-cases cty is aimed at. Its table takes
+// space proportional to len(xs)*len(ys), so for larger lists we instead use
+// longestCommonSubsequenceLinear, which takes space proportional only to
+// the length of the shorter list at the expense of comparing each pair of
+// elements more than once.
 //
 // A pair of lists may have multiple longest common subsequences. In that
 // case, the one selected by this function is undefined.
@@ -17,6 +25,16 @@ func longestCommonSubsequence(xs, ys []cty.Value) []cty.Value {
 	if len(xs) == 0 || len(ys) == 0 {
 		return make([]cty.Value, 0)
 	}
+	if len(xs)*len(ys) > lcsTableMaxCells {
+		return longestCommonSubsequenceLinear(xs, ys)
+	}
+	return longestCommonSubsequenceTable(xs, ys)
+}
+
+// longestCommonSubsequenceTable is the implementation of
+// longestCommonSubsequence for lists that are small enough for a full table
+// of subsequence lengths.
+func longestCommonSubsequenceTable(xs, ys []cty.Value) []cty.Value {
 
 	c := make([]int, len(xs)*len(ys))
 	eqs := make([]bool, len(xs)*len(ys))
@@ -100,3 +118,134 @@ func longestCommonSubsequence(xs, ys []cty.Value) []cty.Value {
 
 	return seq
 }
+
+// longestCommonSubsequenceLinear is a variant of longestCommonSubsequence
+// that uses Hirschberg's algorithm, which takes space proportional to the
+// length of the shorter of the two lists.
+//
+// Rather than keeping the full table of subsequence lengths, the algorithm
+// finds where an optimal subsequence crosses the middle of the longer list
+// by computing only the last row of the table for each half, one forwards
+// and one backwards, and then recurses into the two halves.
+func longestCommonSubsequenceLinear(xs, ys []cty.Value) []cty.Value {
+	seq := make([]cty.Value, 0)
+	if len(xs) >= len(ys) {
+		h := newHirschberg(len(ys), func(x, y int) bool {
+			return lcsEqual(xs[x], ys[y])
+		})
+		h.run(0, len(xs), 0, len(ys), func(x, y int) {
+			seq = append(seq, xs[x])
+		})
+	} else {
+		h := newHirschberg(len(xs), func(y, x int) bool {
+			return lcsEqual(xs[x], ys[y])
+		})
+		h.run(0, len(ys), 0, len(xs), func(y, x int) {
+			seq = append(seq, xs[x])
+		})
+	}
+	return seq
+}
+
+func lcsEqual(x, y cty.Value) bool {
+	eq := x.Equals(y)
+	return eq.IsKnown() && eq.True()
+}
+
+// hirschberg holds the state for longestCommonSubsequenceLinear, in terms
+// of indices into two lists a and b, where b is no longer than a.
+type hirschberg struct {
+	eq func(i, j int) bool
+
+	// Rows of subsequence lengths, each with one more element than b. We
+	// reuse them throughout, since each call to run is done with them
+	// before it recurses.
+	fwd, fwdPrev, bwd, bwdPrev []int
+}
+
+func newHirschberg(n int, eq func(i, j int) bool) *hirschberg {
+	return &hirschberg{
+		eq:      eq,
+		fwd:     make([]int, n+1),
+		fwdPrev: make([]int, n+1),
+		bwd:     make([]int, n+1),
+		bwdPrev: make([]int, n+1),
+	}
+}
+
+// run calls emit, in order, with the indices of each pair of equal elements
+// in a longest common subsequence of a[aLo:aHi] and b[bLo:bHi].
+func (h *hirschberg) run(aLo, aHi, bLo, bHi int, emit func(i, j int)) {
+	n, m := aHi-aLo, bHi-bLo
+	switch {
+	case n == 0 || m == 0:
+		return
+	case n == 1:
+		for j := bLo; j < bHi; j++ {
+			if h.eq(aLo, j) {
+				emit(aLo, j)
+				return
+			}
+		}
+		return
+	}
+
+	aMid := aLo + n/2
+
+	// fwd[j] is the length of a longest common subsequence of
+	// a[aLo:aMid] and b[bLo:bLo+j].
+	fwd, prev := h.fwd[:m+1], h.fwdPrev[:m+1]
+	for j := range prev {
+		prev[j] = 0
+	}
+	for i := aLo; i < aMid; i++ {
+		fwd[0] = 0
+		for j := 1; j <= m; j++ {
+			if h.eq(i, bLo+j-1) {
+				fwd[j] = prev[j-1] + 1
+			} else {
+				fwd[j] = maxInt(prev[j], fwd[j-1])
+			}
+		}
+		fwd, prev = prev, fwd
+	}
+	fwd = prev
+
+	// bwd[j] is the length of a longest common subsequence of
+	// a[aMid:aHi] and b[bHi-j:bHi].
+	bwd, prev := h.bwd[:m+1], h.bwdPrev[:m+1]
+	for j := range prev {
+		prev[j] = 0
+	}
+	for i := aHi - 1; i >= aMid; i-- {
+		bwd[0] = 0
+		for j := 1; j <= m; j++ {
+			if h.eq(i, bHi-j) {
+				bwd[j] = prev[j-1] + 1
+			} else {
+				bwd[j] = maxInt(prev[j], bwd[j-1])
+			}
+		}
+		bwd, prev = prev, bwd
+	}
+	bwd = prev
+
+	// An optimal subsequence pairs a[aLo:aMid] with b[bLo:bLo+k] and
+	// a[aMid:aHi] with b[bLo+k:bHi] for the k with the longest total.
+	k, best := 0, -1
+	for j := 0; j <= m; j++ {
+		if l := fwd[j] + bwd[m-j]; l > best {
+			k, best = j, l
+		}
+	}
+
+	h.run(aLo, aMid, bLo, bLo+k, emit)
+	h.run(aMid, aHi, bLo+k, bHi, emit)
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
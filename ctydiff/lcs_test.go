@@ -2,6 +2,7 @@ package ctydiff
 
 import (
 	"fmt"
+	"math/rand"
 	"testing"
 
 	"github.com/zclconf/go-cty/cty"
@@ -98,3 +99,91 @@ func TestLongestCommonSubsequence(t *testing.T) {
 		})
 	}
 }
+
+func TestLongestCommonSubsequenceLinear(t *testing.T) {
+	// The linear-space variant may select a different subsequence from the
+	// table-based one where there are several, so we check only that its
+	// result is a common subsequence of the same length.
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		xs := testLCSList(rnd, rnd.Intn(30), 1+rnd.Intn(8))
+		ys := testLCSList(rnd, rnd.Intn(30), 1+rnd.Intn(8))
+		if i%10 == 0 {
+			ys = append(ys, cty.UnknownVal(cty.Number))
+		}
+
+		want := longestCommonSubsequence(xs, ys)
+		got := longestCommonSubsequenceLinear(xs, ys)
+		if len(got) != len(want) {
+			t.Fatalf("wrong length %d; want %d\nX: %#v\nY: %#v\ngot: %#v", len(got), len(want), xs, ys, got)
+		}
+		if !isSubsequence(got, xs) || !isSubsequence(got, ys) {
+			t.Fatalf("not a common subsequence\nX: %#v\nY: %#v\ngot: %#v", xs, ys, got)
+		}
+	}
+}
+
+func TestLongestCommonSubsequence_large(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping large input in short mode")
+	}
+
+	// These lists are large enough that longestCommonSubsequence uses the
+	// linear-space variant rather than a table.
+	xs := make([]cty.Value, 1100)
+	for i := range xs {
+		xs[i] = cty.NumberIntVal(int64(i))
+	}
+	ys := append([]cty.Value{cty.NumberIntVal(-1)}, xs[:1000]...)
+	ys[500] = cty.NumberIntVal(-2)
+
+	got := longestCommonSubsequence(xs, ys)
+	if len(got) != 999 {
+		t.Fatalf("wrong length %d; want 999", len(got))
+	}
+	if !isSubsequence(got, xs) || !isSubsequence(got, ys) {
+		t.Fatalf("not a common subsequence")
+	}
+}
+
+func BenchmarkLongestCommonSubsequence(b *testing.B) {
+	for _, n := range []int{100, 1000, 2000} {
+		rnd := rand.New(rand.NewSource(1))
+		xs := testLCSList(rnd, n, n/4)
+		ys := testLCSList(rnd, n, n/4)
+
+		b.Run(fmt.Sprintf("Table/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				longestCommonSubsequenceTable(xs, ys)
+			}
+		})
+		b.Run(fmt.Sprintf("Linear/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				longestCommonSubsequenceLinear(xs, ys)
+			}
+		})
+	}
+}
+
+// testLCSList returns a list of n numbers chosen at random from k values.
+func testLCSList(rnd *rand.Rand, n, k int) []cty.Value {
+	ret := make([]cty.Value, n)
+	for i := range ret {
+		ret[i] = cty.NumberIntVal(int64(rnd.Intn(k)))
+	}
+	return ret
+}
+
+// isSubsequence returns true if the elements of sub appear in the same
+// order in seq.
+func isSubsequence(sub, seq []cty.Value) bool {
+	i := 0
+	for _, v := range seq {
+		if i < len(sub) && lcsEqual(sub[i], v) {
+			i++
+		}
+	}
+	return i == len(sub)
+}
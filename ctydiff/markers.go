@@ -0,0 +1,247 @@
+package ctydiff
+
+import (
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// ConflictMarkerAttr is the name of the attribute that identifies a
+// conflict marker, as returned by ConflictMarker.
+const ConflictMarkerAttr = "__ctydiff_conflict__"
+
+// ConflictMarker returns a value that represents the given conflict within
+// a merged value, for later resolution, in the same way that git embeds
+// conflict markers in a file whose merge has conflicts.
+//
+// A conflict marker is an object with the following attributes:
+//
+//	__ctydiff_conflict__  true
+//	path                  the path of the conflict, as for Diff.ToValue
+//	base                  the base value, as a tuple
+//	ours                  our side's value, as a tuple
+//	theirs                their side's value, as a tuple
+//
+// Each tuple has the corresponding value as its only element, or is empty
+// if the value is cty.NilVal, representing an absent map element.
+//
+// The path is recorded because it may differ from the path of the marker
+// itself: a map that contains a marker must be represented as an object,
+// whose elements have attribute steps where the map's had index steps.
+func ConflictMarker(c MergeConflict) cty.Value {
+	side := func(v cty.Value) cty.Value {
+		if v == cty.NilVal {
+			return cty.EmptyTupleVal
+		}
+		return cty.TupleVal([]cty.Value{v})
+	}
+	return cty.ObjectVal(map[string]cty.Value{
+		ConflictMarkerAttr: cty.True,
+		"path":             pathToValue(c.Path),
+		"base":             side(c.Base),
+		"ours":             side(c.Ours),
+		"theirs":           side(c.Theirs),
+	})
+}
+
+// IsConflictMarker returns true if the given value is a conflict marker, as
+// returned by ConflictMarker.
+func IsConflictMarker(val cty.Value) bool {
+	_, ok := conflictFromMarker(val)
+	return ok
+}
+
+// conflictFromMarker returns the conflict that the given value represents,
+// if it is a conflict marker.
+func conflictFromMarker(val cty.Value) (MergeConflict, bool) {
+	if requireKnown(val) != nil || !val.Type().IsObjectType() {
+		return MergeConflict{}, false
+	}
+	atys := val.Type().AttributeTypes()
+	if len(atys) != 5 {
+		return MergeConflict{}, false
+	}
+	flag, ok := atys[ConflictMarkerAttr]
+	if !ok || !flag.Equals(cty.Bool) || !val.GetAttr(ConflictMarkerAttr).RawEquals(cty.True) {
+		return MergeConflict{}, false
+	}
+	if _, ok := atys["path"]; !ok {
+		return MergeConflict{}, false
+	}
+	path, err := pathFromValue(val.GetAttr("path"), nil)
+	if err != nil {
+		return MergeConflict{}, false
+	}
+	var sides [3]cty.Value
+	for i, name := range []string{"base", "ours", "theirs"} {
+		ty, ok := atys[name]
+		if !ok || !ty.IsTupleType() || len(ty.TupleElementTypes()) > 1 {
+			return MergeConflict{}, false
+		}
+		side := val.GetAttr(name)
+		if requireKnown(side) != nil {
+			return MergeConflict{}, false
+		}
+		if side.LengthInt() == 1 {
+			sides[i] = side.Index(cty.NumberIntVal(0))
+		}
+	}
+	return MergeConflict{
+		Path:   path,
+		Base:   sides[0],
+		Ours:   sides[1],
+		Theirs: sides[2],
+	}, true
+}
+
+// ConflictMarkers returns the conflicts represented by the conflict markers
+// in the given value, in the order that cty.Walk visits them. Each conflict
+// has the path recorded in its marker, which is the same as in the
+// *MergeError returned along with the markers.
+func ConflictMarkers(val cty.Value) []MergeConflict {
+	var ret []MergeConflict
+	cty.Walk(val, func(path cty.Path, v cty.Value) (bool, error) {
+		c, ok := conflictFromMarker(v)
+		if !ok {
+			return true, nil
+		}
+		ret = append(ret, c)
+		return false, nil
+	})
+	return ret
+}
+
+// ResolveConflictMarkers replaces the conflict markers in the given value,
+// such as one returned by MergeWithOptions with the Markers option, using
+// the given resolvers in the same way as MergeWithOptions. Each conflict
+// passed to the resolvers has the path recorded in its marker, and the root
+// conflict has the given value with each conflict marker replaced by the
+// corresponding side of its conflict, converted to the given type if
+// possible so that those paths apply to it.
+//
+// A map that contains a conflict marker must be represented as an object,
+// since its elements are no longer all of the same type, so once all of the
+// markers are resolved ResolveConflictMarkers converts the result to the
+// given type, which is usually the type of the values that were merged. If
+// the type is cty.NilType then the result is not converted.
+//
+// If any markers remain unresolved then ResolveConflictMarkers returns a
+// *MergeError describing them, along with the value with the remaining
+// markers still in place. Callers can also resolve markers by replacing
+// them with the chosen values in some other way, such as by having a user
+// edit a document, and then call ResolveConflictMarkers with no resolvers
+// in order to check for remaining markers and convert the result.
+func ResolveConflictMarkers(val cty.Value, ty cty.Type, resolvers ...ConflictResolver) (cty.Value, []MergeResolution, error) {
+	root := func(side func(MergeConflict) cty.Value) cty.Value {
+		v := replaceConflictMarkers(val, func(c MergeConflict) (cty.Value, bool) { return side(c), true })
+		if ty == cty.NilType {
+			return v
+		}
+		if converted, err := convert.Convert(v, ty); err == nil {
+			return converted
+		}
+		return v
+	}
+	m := &merger{
+		opts: MergeOptions{Resolvers: resolvers},
+		root: MergeConflict{
+			Base:   root(func(c MergeConflict) cty.Value { return c.Base }),
+			Ours:   root(func(c MergeConflict) cty.Value { return c.Ours }),
+			Theirs: root(func(c MergeConflict) cty.Value { return c.Theirs }),
+		},
+	}
+	ret := replaceConflictMarkers(val, func(c MergeConflict) (cty.Value, bool) {
+		if v, ok := m.resolve(c); ok {
+			return v, true
+		}
+		m.conflicts = append(m.conflicts, c)
+		return cty.NilVal, false
+	})
+	if len(m.conflicts) > 0 {
+		return ret, m.resolutions, &MergeError{Conflicts: m.conflicts}
+	}
+	if ty == cty.NilType {
+		return ret, m.resolutions, nil
+	}
+	converted, err := convert.Convert(ret, ty)
+	if err != nil {
+		return cty.NilVal, m.resolutions, err
+	}
+	return converted, m.resolutions, nil
+}
+
+// replaceConflictMarkers returns a copy of the given value with each
+// conflict marker replaced by the value returned by the given function for
+// its conflict. If the function returns false then the marker is retained,
+// and if it returns cty.NilVal for a map element then the element is
+// removed.
+//
+// Conflict markers are found only within objects, maps and tuples, which
+// are the values that Merge merges element by element. The function is
+// called for the markers in the order of their paths.
+func replaceConflictMarkers(val cty.Value, fn func(MergeConflict) (cty.Value, bool)) cty.Value {
+	if c, ok := conflictFromMarker(val); ok {
+		if v, ok := fn(c); ok {
+			return v
+		}
+		return val
+	}
+	if requireKnown(val) != nil {
+		return val
+	}
+
+	ty := val.Type()
+	switch {
+	case ty.IsObjectType():
+		if len(ty.AttributeTypes()) == 0 {
+			return val
+		}
+		// An object may be a map that contains a conflict marker, so an
+		// attribute may be removed in the same way as a map element.
+		attrs := make(map[string]cty.Value)
+		for it := val.ElementIterator(); it.Next(); {
+			k, v := it.Element()
+			if v = replaceConflictMarkers(v, fn); v != cty.NilVal {
+				attrs[k.AsString()] = v
+			}
+		}
+		return cty.ObjectVal(attrs)
+	case ty.IsMapType():
+		elems := make(map[string]cty.Value)
+		for it := val.ElementIterator(); it.Next(); {
+			k, v := it.Element()
+			if v = replaceConflictMarkers(v, fn); v != cty.NilVal {
+				elems[k.AsString()] = v
+			}
+		}
+		return mapOrObjectVal(elems, ty.ElementType())
+	case ty.IsTupleType():
+		elems := val.AsValueSlice()
+		for i, v := range elems {
+			elems[i] = replaceConflictMarkers(v, fn)
+		}
+		if len(elems) == 0 {
+			return val
+		}
+		return cty.TupleVal(elems)
+	}
+	return val
+}
+
+// mapOrObjectVal returns a map value with the given elements if they all
+// have the same type, or an object value otherwise. The given element type
+// is used for an empty map.
+func mapOrObjectVal(elems map[string]cty.Value, ety cty.Type) cty.Value {
+	if len(elems) == 0 {
+		return cty.MapValEmpty(ety)
+	}
+	var ty cty.Type
+	for _, v := range elems {
+		if ty == cty.NilType {
+			ty = v.Type()
+		}
+		if !v.Type().Equals(ty) {
+			return cty.ObjectVal(elems)
+		}
+	}
+	return cty.MapVal(elems)
+}
@@ -0,0 +1,257 @@
+package ctydiff
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestMergeWithOptions_markers(t *testing.T) {
+	base := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("a"),
+		"tags": cty.MapVal(map[string]cty.Value{
+			"env":  cty.StringVal("dev"),
+			"team": cty.StringVal("x"),
+		}),
+	})
+	ours := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("b"),
+		"tags": cty.MapVal(map[string]cty.Value{
+			"team": cty.StringVal("y"),
+		}),
+	})
+	theirs := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("c"),
+		"tags": cty.MapVal(map[string]cty.Value{
+			"env":  cty.StringVal("prod"),
+			"team": cty.StringVal("x"),
+		}),
+	})
+
+	got, _, err := MergeWithOptions(base, ours, theirs, MergeOptions{Markers: true})
+	mergeErr, ok := err.(*MergeError)
+	if !ok {
+		t.Fatalf("MergeWithOptions() err = %#v; want *MergeError", err)
+	}
+	want := cty.ObjectVal(map[string]cty.Value{
+		"name": ConflictMarker(MergeConflict{
+			Path:   cty.GetAttrPath("name"),
+			Base:   cty.StringVal("a"),
+			Ours:   cty.StringVal("b"),
+			Theirs: cty.StringVal("c"),
+		}),
+		"tags": cty.ObjectVal(map[string]cty.Value{
+			"env": ConflictMarker(MergeConflict{
+				Path:   cty.GetAttrPath("tags").Index(cty.StringVal("env")),
+				Base:   cty.StringVal("dev"),
+				Theirs: cty.StringVal("prod"),
+			}),
+			"team": cty.StringVal("y"),
+		}),
+	})
+	if !got.RawEquals(want) {
+		t.Fatalf("MergeWithOptions\nGot\n%#v\nWant\n%#v", got, want)
+	}
+
+	// The markers are listed with the same paths as the conflicts in the
+	// error, even though the map containing one of them has become an
+	// object.
+	markers := ConflictMarkers(got)
+	if !reflect.DeepEqual(markers, mergeErr.Conflicts) {
+		t.Errorf("wrong markers\n%s", prettyDiff.Compare(mergeErr.Conflicts, markers))
+	}
+
+	// Resolving only some of the markers leaves the others in place. The
+	// resolvers see the recorded paths, which apply to the root values.
+	var paths []cty.Path
+	partial, resolutions, err := ResolveConflictMarkers(got, base.Type(), ConflictResolver{
+		Name: "names",
+		Resolve: func(conflict, root MergeConflict) (cty.Value, bool) {
+			if _, err := applyPath(root.Base, conflict.Path); err != nil {
+				t.Errorf("conflict path does not apply to root: %s", err)
+			}
+			paths = append(paths, conflict.Path)
+			if conflict.Ours == cty.NilVal || !conflict.Ours.Type().Equals(cty.String) || len(conflict.Path) != 1 {
+				return cty.NilVal, false
+			}
+			return cty.StringVal(conflict.Ours.AsString() + conflict.Theirs.AsString()), true
+		},
+	})
+	mergeErr, ok = err.(*MergeError)
+	if !ok {
+		t.Fatalf("ResolveConflictMarkers() err = %#v; want *MergeError", err)
+	}
+	if got, want := len(mergeErr.Conflicts), 1; got != want {
+		t.Errorf("got %d conflicts; want %d", got, want)
+	}
+	if got, want := len(resolutions), 1; got != want {
+		t.Errorf("got %d resolutions; want %d", got, want)
+	}
+	if got, want := partial.GetAttr("name"), cty.StringVal("bc"); !got.RawEquals(want) {
+		t.Errorf("wrong name %#v; want %#v", got, want)
+	}
+	if !IsConflictMarker(partial.GetAttr("tags").GetAttr("env")) {
+		t.Errorf("tags.env is not a conflict marker")
+	}
+	if got, want := paths, []cty.Path{markers[0].Path, markers[1].Path}; !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong paths\n%s", prettyDiff.Compare(want, got))
+	}
+
+	// Once all of the markers are resolved, the result is converted back
+	// to the original type.
+	resolved, _, err := ResolveConflictMarkers(partial, base.Type(), ResolveOurs)
+	if err != nil {
+		t.Fatalf("ResolveConflictMarkers() err = %v", err)
+	}
+	wantResolved := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("bc"),
+		"tags": cty.MapVal(map[string]cty.Value{
+			"team": cty.StringVal("y"),
+		}),
+	})
+	if !resolved.RawEquals(wantResolved) {
+		t.Errorf("ResolveConflictMarkers\nGot\n%#v\nWant\n%#v", resolved, wantResolved)
+	}
+}
+
+func TestResolveConflictMarkers_root(t *testing.T) {
+	marker := func(attr string) cty.Value {
+		return ConflictMarker(MergeConflict{
+			Path:   cty.IndexPath(cty.NumberIntVal(0)).GetAttr(attr),
+			Base:   cty.NumberIntVal(1),
+			Ours:   cty.NumberIntVal(2),
+			Theirs: cty.NumberIntVal(3),
+		})
+	}
+	val := cty.TupleVal([]cty.Value{
+		cty.ObjectVal(map[string]cty.Value{
+			"size":       marker("size"),
+			"updated_at": marker("updated_at"),
+		}),
+	})
+
+	var roots []MergeConflict
+	record := ConflictResolver{
+		Name: "record",
+		Resolve: func(conflict, root MergeConflict) (cty.Value, bool) {
+			roots = append(roots, root)
+			return cty.NilVal, false
+		},
+	}
+	got, resolutions, err := ResolveConflictMarkers(val, cty.NilType, record, ResolveNewer("updated_at"))
+	if err != nil {
+		t.Fatalf("ResolveConflictMarkers() err = %v", err)
+	}
+	want := cty.TupleVal([]cty.Value{
+		cty.ObjectVal(map[string]cty.Value{
+			"size":       cty.NumberIntVal(3),
+			"updated_at": cty.NumberIntVal(3),
+		}),
+	})
+	if !got.RawEquals(want) {
+		t.Errorf("ResolveConflictMarkers\nGot\n%#v\nWant\n%#v", got, want)
+	}
+	for _, r := range resolutions {
+		if r.Strategy != "newer" {
+			t.Errorf("conflict at %s resolved by %q; want \"newer\"", FormatPath(r.Conflict.Path), r.Strategy)
+		}
+	}
+
+	wantOurs := cty.TupleVal([]cty.Value{
+		cty.ObjectVal(map[string]cty.Value{
+			"size":       cty.NumberIntVal(2),
+			"updated_at": cty.NumberIntVal(2),
+		}),
+	})
+	if len(roots) == 0 || !roots[0].Ours.RawEquals(wantOurs) {
+		t.Errorf("wrong root values %#v", roots)
+	}
+}
+
+func TestIsConflictMarker(t *testing.T) {
+	marker := ConflictMarker(MergeConflict{
+		Base:   cty.StringVal("a"),
+		Ours:   cty.StringVal("b"),
+		Theirs: cty.StringVal("c"),
+	})
+	tests := []struct {
+		name string
+		val  cty.Value
+		want bool
+	}{
+		{
+			"Marker",
+			marker,
+			true,
+		},
+		{
+			"Absent",
+			ConflictMarker(MergeConflict{Ours: cty.StringVal("b")}),
+			true,
+		},
+		{
+			"False",
+			cty.ObjectVal(map[string]cty.Value{
+				ConflictMarkerAttr: cty.False,
+				"path":             cty.EmptyTupleVal,
+				"base":             cty.EmptyTupleVal,
+				"ours":             cty.EmptyTupleVal,
+				"theirs":           cty.EmptyTupleVal,
+			}),
+			false,
+		},
+		{
+			"ExtraAttr",
+			cty.ObjectVal(map[string]cty.Value{
+				ConflictMarkerAttr: cty.True,
+				"path":             cty.EmptyTupleVal,
+				"base":             cty.EmptyTupleVal,
+				"ours":             cty.EmptyTupleVal,
+				"theirs":           cty.EmptyTupleVal,
+				"other":            cty.EmptyTupleVal,
+			}),
+			false,
+		},
+		{
+			"List",
+			cty.ObjectVal(map[string]cty.Value{
+				ConflictMarkerAttr: cty.True,
+				"path":             cty.EmptyTupleVal,
+				"base":             cty.ListValEmpty(cty.String),
+				"ours":             cty.EmptyTupleVal,
+				"theirs":           cty.EmptyTupleVal,
+			}),
+			false,
+		},
+		{
+			"BadPath",
+			cty.ObjectVal(map[string]cty.Value{
+				ConflictMarkerAttr: cty.True,
+				"path":             cty.StringVal(".a"),
+				"base":             cty.EmptyTupleVal,
+				"ours":             cty.EmptyTupleVal,
+				"theirs":           cty.EmptyTupleVal,
+			}),
+			false,
+		},
+		{
+			"Unknown",
+			cty.UnknownVal(marker.Type()),
+			false,
+		},
+		{
+			"String",
+			cty.StringVal("a"),
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsConflictMarker(tt.val); got != tt.want {
+				t.Errorf("IsConflictMarker() = %t; want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package ctydiff
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// MergeConflict describes a value that was changed in different ways on
+// the two sides of a three-way merge.
+//
+// Any of the values may be cty.NilVal, representing a map element that
+// does not exist in the corresponding input.
+type MergeConflict struct {
+	Path   cty.Path
+	Base   cty.Value
+	Ours   cty.Value
+	Theirs cty.Value
+}
+
+// MergeError is the error returned by Merge when the inputs have
+// conflicting changes.
+type MergeError struct {
+	Conflicts []MergeConflict
+}
+
+func (e *MergeError) Error() string {
+	if len(e.Conflicts) == 1 {
+		return "merge has 1 conflict"
+	}
+	return fmt.Sprintf("merge has %d conflicts", len(e.Conflicts))
+}
+
+// Merge performs a three-way merge, combining the changes made to the given
+// base value in ours with those made in theirs.
+//
+// Where only one side changed a value, or both sides made the same change,
+// the result has the changed value. Objects, maps and tuples of the same
+// type on all three sides are merged element by element, and sets are
+// merged by membership, so changes to different elements do not conflict.
+// Any other value that was changed differently on the two sides is a
+// conflict, including any two different changes to a list, although the
+// ResolveLists resolver can merge lists whose changes do not overlap.
+//
+// If there are conflicts then Merge returns a *MergeError describing them,
+// along with a merged value that has our side's value at each conflict.
+func Merge(base, ours, theirs cty.Value) (cty.Value, error) {
+	ret, _, err := MergeWithOptions(base, ours, theirs, MergeOptions{})
+	return ret, err
+}
+
+// MergeOptions represents options for customizing the behavior of
+// MergeWithOptions. The zero value of MergeOptions gives the same behavior
+// as Merge.
+type MergeOptions struct {
+	// Resolvers are the strategies for resolving conflicts. For each
+	// conflict, the resolvers are consulted in order until one of them
+	// resolves it. Conflicts that none of the resolvers resolve are
+	// reported as for Merge.
+	Resolvers []ConflictResolver
+
+	// Markers causes each conflict that is not resolved to be represented
+	// in the merged value by a conflict marker, as returned by
+	// ConflictMarker, rather than by our side's value. The conflicts are
+	// still reported in a *MergeError, and can be resolved later using
+	// ResolveConflictMarkers.
+	//
+	// Since a conflict marker is an object, a map that has a conflict
+	// marker as an element is represented as an object instead, and so is
+	// any map that contains such a value.
+	Markers bool
+}
+
+// MergeResolution describes a conflict that was resolved by one of the
+// resolvers given in MergeOptions.
+type MergeResolution struct {
+	Conflict MergeConflict
+
+	// Strategy is the name of the resolver that resolved the conflict, and
+	// Value is the value it chose, which is cty.NilVal if it chose to omit
+	// a map element.
+	Strategy string
+	Value    cty.Value
+}
+
+// MergeWithOptions is like Merge but allows the merge to be customized,
+// such as by resolving conflicts automatically. Along with the merged
+// value, it returns a MergeResolution for each conflict that was resolved,
+// in the same order as the conflicts reported by Merge.
+func MergeWithOptions(base, ours, theirs cty.Value, opts MergeOptions) (cty.Value, []MergeResolution, error) {
+	m := &merger{
+		opts: opts,
+		root: MergeConflict{
+			Base:   base,
+			Ours:   ours,
+			Theirs: theirs,
+		},
+	}
+	ret := m.mergeValues(base, ours, theirs, cty.Path{})
+	if len(m.conflicts) > 0 {
+		return ret, m.resolutions, &MergeError{Conflicts: m.conflicts}
+	}
+	return ret, m.resolutions, nil
+}
+
+// merger holds the state for a single call to MergeWithOptions.
+type merger struct {
+	opts MergeOptions
+
+	// root holds the values being merged, for the benefit of resolvers.
+	root MergeConflict
+
+	conflicts   []MergeConflict
+	resolutions []MergeResolution
+}
+
+// mergeValues returns the result of merging the given values, which are at
+// the given path. Any of the values may be cty.NilVal, to represent an
+// absent map element, in which case the result may also be cty.NilVal.
+func (m *merger) mergeValues(base, ours, theirs cty.Value, path cty.Path) cty.Value {
+	switch {
+	case rawEquals(ours, theirs):
+		return ours
+	case rawEquals(base, ours):
+		return theirs
+	case rawEquals(base, theirs):
+		return ours
+	}
+
+	if kind := mergeKind(base, ours, theirs); kind != "" {
+		switch kind {
+		case "object":
+			return m.mergeObjects(base, ours, theirs, path)
+		case "map":
+			return m.mergeMaps(base, ours, theirs, path)
+		case "tuple":
+			return m.mergeTuples(base, ours, theirs, path)
+		case "set":
+			return mergeSets(base, ours, theirs)
+		}
+	}
+
+	conflict := MergeConflict{
+		Path:   path.Copy(),
+		Base:   base,
+		Ours:   ours,
+		Theirs: theirs,
+	}
+	if v, ok := m.resolve(conflict); ok {
+		return v
+	}
+	m.conflicts = append(m.conflicts, conflict)
+	if m.opts.Markers {
+		return ConflictMarker(conflict)
+	}
+	return ours
+}
+
+// resolve consults the resolvers in turn for the given conflict, returning
+// the value chosen by the first that resolves it.
+//
+// A resolver's value is used only if it is of the same type as one of the
+// sides, so that it can take the place of the conflicting values, and it
+// may be cty.NilVal only for a map element.
+func (m *merger) resolve(conflict MergeConflict) (cty.Value, bool) {
+	for _, r := range m.opts.Resolvers {
+		if r.Resolve == nil {
+			continue
+		}
+		v, ok := r.Resolve(conflict, m.root)
+		if !ok {
+			continue
+		}
+		if v == cty.NilVal {
+			if !isMapElementConflict(conflict) {
+				continue
+			}
+		} else if !hasTypeOf(v, conflict.Ours) && !hasTypeOf(v, conflict.Theirs) {
+			continue
+		}
+		m.resolutions = append(m.resolutions, MergeResolution{
+			Conflict: conflict,
+			Strategy: r.Name,
+			Value:    v,
+		})
+		return v, true
+	}
+	return cty.NilVal, false
+}
+
+// isMapElementConflict returns true if the given conflict is for a map
+// element, since only map elements can be absent from one of the sides.
+func isMapElementConflict(c MergeConflict) bool {
+	return c.Base == cty.NilVal || c.Ours == cty.NilVal || c.Theirs == cty.NilVal
+}
+
+// hasTypeOf returns true if the given values have the same type. Either
+// may be cty.NilVal, which has no type.
+func hasTypeOf(v, other cty.Value) bool {
+	return v != cty.NilVal && other != cty.NilVal && v.Type().Equals(other.Type())
+}
+
+// mergeKind returns the kind of value that the given values all are, if
+// they are all known, non-null values of the same type that can be merged
+// element by element, or an empty string otherwise.
+func mergeKind(base, ours, theirs cty.Value) string {
+	for _, v := range []cty.Value{base, ours, theirs} {
+		if requireKnown(v) != nil || !v.Type().Equals(base.Type()) {
+			return ""
+		}
+	}
+	ty := base.Type()
+	switch {
+	case ty.IsObjectType():
+		return "object"
+	case ty.IsMapType():
+		return "map"
+	case ty.IsTupleType():
+		return "tuple"
+	case ty.IsSetType() && base.IsWhollyKnown() && ours.IsWhollyKnown() && theirs.IsWhollyKnown():
+		return "set"
+	}
+	return ""
+}
+
+func (m *merger) mergeObjects(base, ours, theirs cty.Value, path cty.Path) cty.Value {
+	atys := base.Type().AttributeTypes()
+	names := make([]string, 0, len(atys))
+	for name := range atys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	attrs := make(map[string]cty.Value, len(names))
+	for _, name := range names {
+		attrs[name] = m.mergeValues(base.GetAttr(name), ours.GetAttr(name), theirs.GetAttr(name), path.GetAttr(name))
+	}
+	return cty.ObjectVal(attrs)
+}
+
+func (m *merger) mergeMaps(base, ours, theirs cty.Value, path cty.Path) cty.Value {
+	baseMap := base.AsValueMap()
+	oursMap := ours.AsValueMap()
+	theirsMap := theirs.AsValueMap()
+
+	keySet := make(map[string]struct{})
+	for _, kv := range []map[string]cty.Value{baseMap, oursMap, theirsMap} {
+		for k := range kv {
+			keySet[k] = struct{}{}
+		}
+	}
+	keys := make([]string, 0, len(keySet))
+	for k := range keySet {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	elems := make(map[string]cty.Value, len(keys))
+	for _, k := range keys {
+		// Absent elements are represented by cty.NilVal, which is equal
+		// only to itself.
+		v := m.mergeValues(baseMap[k], oursMap[k], theirsMap[k], path.Index(cty.StringVal(k)))
+		if v != cty.NilVal {
+			elems[k] = v
+		}
+	}
+	// The elements may not all have the same type if some of them are
+	// conflict markers, in which case we must return an object instead.
+	return mapOrObjectVal(elems, base.Type().ElementType())
+}
+
+func (m *merger) mergeTuples(base, ours, theirs cty.Value, path cty.Path) cty.Value {
+	n := base.LengthInt()
+	elems := make([]cty.Value, n)
+	for i := 0; i < n; i++ {
+		idx := cty.NumberIntVal(int64(i))
+		elems[i] = m.mergeValues(base.Index(idx), ours.Index(idx), theirs.Index(idx), path.Index(idx))
+	}
+	return cty.TupleVal(elems)
+}
+
+// mergeSets merges the given sets by membership: the result contains the
+// elements that are in both sides, along with those that either side added.
+func mergeSets(base, ours, theirs cty.Value) cty.Value {
+	baseSet := base.AsValueSet()
+	oursSet := ours.AsValueSet()
+	theirsSet := theirs.AsValueSet()
+
+	ret := oursSet.Intersection(theirsSet)
+	for _, v := range oursSet.Values() {
+		if !baseSet.Has(v) {
+			ret.Add(v)
+		}
+	}
+	for _, v := range theirsSet.Values() {
+		if !baseSet.Has(v) {
+			ret.Add(v)
+		}
+	}
+	return cty.SetValFromValueSet(ret)
+}
@@ -0,0 +1,248 @@
+package ctydiff
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name   string
+		base   cty.Value
+		ours   cty.Value
+		theirs cty.Value
+		want   cty.Value
+	}{
+		{
+			"OursOnly",
+			cty.StringVal("a"),
+			cty.StringVal("b"),
+			cty.StringVal("a"),
+			cty.StringVal("b"),
+		},
+		{
+			"TheirsOnly",
+			cty.StringVal("a"),
+			cty.StringVal("a"),
+			cty.StringVal("c"),
+			cty.StringVal("c"),
+		},
+		{
+			"SameChange",
+			cty.StringVal("a"),
+			cty.StringVal("b"),
+			cty.StringVal("b"),
+			cty.StringVal("b"),
+		},
+		{
+			"Object",
+			cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("a"), "b": cty.StringVal("b")}),
+			cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("x"), "b": cty.StringVal("b")}),
+			cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("a"), "b": cty.StringVal("y")}),
+			cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("x"), "b": cty.StringVal("y")}),
+		},
+		{
+			"Map",
+			cty.MapVal(map[string]cty.Value{"a": cty.StringVal("a"), "b": cty.StringVal("b"), "c": cty.StringVal("c")}),
+			cty.MapVal(map[string]cty.Value{"a": cty.StringVal("a"), "c": cty.StringVal("c"), "d": cty.StringVal("d")}),
+			cty.MapVal(map[string]cty.Value{"a": cty.StringVal("a"), "b": cty.StringVal("b"), "c": cty.StringVal("z")}),
+			cty.MapVal(map[string]cty.Value{"a": cty.StringVal("a"), "c": cty.StringVal("z"), "d": cty.StringVal("d")}),
+		},
+		{
+			"MapDeleteAll",
+			cty.MapVal(map[string]cty.Value{"a": cty.StringVal("a"), "b": cty.StringVal("b")}),
+			cty.MapVal(map[string]cty.Value{"b": cty.StringVal("b")}),
+			cty.MapVal(map[string]cty.Value{"a": cty.StringVal("a")}),
+			cty.MapValEmpty(cty.String),
+		},
+		{
+			"Tuple",
+			cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.True}),
+			cty.TupleVal([]cty.Value{cty.StringVal("b"), cty.True}),
+			cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.False}),
+			cty.TupleVal([]cty.Value{cty.StringVal("b"), cty.False}),
+		},
+		{
+			"Set",
+			cty.SetVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b"), cty.StringVal("c")}),
+			cty.SetVal([]cty.Value{cty.StringVal("b"), cty.StringVal("c"), cty.StringVal("d")}),
+			cty.SetVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b"), cty.StringVal("e")}),
+			cty.SetVal([]cty.Value{cty.StringVal("b"), cty.StringVal("d"), cty.StringVal("e")}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Merge(tt.base, tt.ours, tt.theirs)
+			if err != nil {
+				t.Fatalf("Merge() err = %v", err)
+			}
+			if !got.RawEquals(tt.want) {
+				t.Errorf("Merge\nGot\n%#v\nWant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMerge_conflicts(t *testing.T) {
+	base := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("a"),
+		"list": cty.ListVal([]cty.Value{cty.StringVal("x")}),
+		"tags": cty.MapVal(map[string]cty.Value{"env": cty.StringVal("dev")}),
+		"size": cty.NumberIntVal(1),
+	})
+	ours := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("b"),
+		"list": cty.ListVal([]cty.Value{cty.StringVal("x"), cty.StringVal("y")}),
+		"tags": cty.MapValEmpty(cty.String),
+		"size": cty.NumberIntVal(2),
+	})
+	theirs := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("c"),
+		"list": cty.ListVal([]cty.Value{cty.StringVal("z"), cty.StringVal("x")}),
+		"tags": cty.MapVal(map[string]cty.Value{"env": cty.StringVal("prod")}),
+		"size": cty.NumberIntVal(1),
+	})
+
+	got, err := Merge(base, ours, theirs)
+	mergeErr, ok := err.(*MergeError)
+	if !ok {
+		t.Fatalf("Merge() err = %#v; want *MergeError", err)
+	}
+	want := []MergeConflict{
+		{
+			Path:   cty.GetAttrPath("list"),
+			Base:   base.GetAttr("list"),
+			Ours:   ours.GetAttr("list"),
+			Theirs: theirs.GetAttr("list"),
+		},
+		{
+			Path:   cty.GetAttrPath("name"),
+			Base:   cty.StringVal("a"),
+			Ours:   cty.StringVal("b"),
+			Theirs: cty.StringVal("c"),
+		},
+		{
+			Path:   cty.GetAttrPath("tags").Index(cty.StringVal("env")),
+			Base:   cty.StringVal("dev"),
+			Ours:   cty.NilVal,
+			Theirs: cty.StringVal("prod"),
+		},
+	}
+	if !reflect.DeepEqual(mergeErr.Conflicts, want) {
+		t.Errorf("wrong conflicts\n%s", prettyDiff.Compare(want, mergeErr.Conflicts))
+	}
+	if got, want := err.Error(), "merge has 3 conflicts"; got != want {
+		t.Errorf("wrong error %q; want %q", got, want)
+	}
+
+	wantValue := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("b"),
+		"list": cty.ListVal([]cty.Value{cty.StringVal("x"), cty.StringVal("y")}),
+		"tags": cty.MapValEmpty(cty.String),
+		"size": cty.NumberIntVal(2),
+	})
+	if !got.RawEquals(wantValue) {
+		t.Errorf("Merge\nGot\n%#v\nWant\n%#v", got, wantValue)
+	}
+}
+
+func TestMergeWithOptions(t *testing.T) {
+	base := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("a"),
+		"tags": cty.MapVal(map[string]cty.Value{"env": cty.StringVal("dev")}),
+		"size": cty.NumberIntVal(1),
+	})
+	ours := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("b"),
+		"tags": cty.MapValEmpty(cty.String),
+		"size": cty.NumberIntVal(2),
+	})
+	theirs := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("c"),
+		"tags": cty.MapVal(map[string]cty.Value{"env": cty.StringVal("prod")}),
+		"size": cty.NumberIntVal(3),
+	})
+	larger := ConflictResolver{
+		Name: "larger",
+		Resolve: func(conflict, root MergeConflict) (cty.Value, bool) {
+			if conflict.Ours == cty.NilVal || !conflict.Ours.Type().Equals(cty.Number) {
+				return cty.NilVal, false
+			}
+			if conflict.Ours.GreaterThan(conflict.Theirs).True() {
+				return conflict.Ours, true
+			}
+			return conflict.Theirs, true
+		},
+	}
+	wrongType := ConflictResolver{
+		Name: "wrong",
+		Resolve: func(conflict, root MergeConflict) (cty.Value, bool) {
+			return cty.True, true
+		},
+	}
+	opts := MergeOptions{
+		Resolvers: []ConflictResolver{wrongType, larger, ResolveTheirs},
+	}
+
+	got, resolutions, err := MergeWithOptions(base, ours, theirs, opts)
+	if err != nil {
+		t.Fatalf("MergeWithOptions() err = %v", err)
+	}
+	want := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("c"),
+		"tags": cty.MapVal(map[string]cty.Value{"env": cty.StringVal("prod")}),
+		"size": cty.NumberIntVal(3),
+	})
+	if !got.RawEquals(want) {
+		t.Errorf("MergeWithOptions\nGot\n%#v\nWant\n%#v", got, want)
+	}
+	wantResolutions := []MergeResolution{
+		{
+			Conflict: MergeConflict{
+				Path:   cty.GetAttrPath("name"),
+				Base:   cty.StringVal("a"),
+				Ours:   cty.StringVal("b"),
+				Theirs: cty.StringVal("c"),
+			},
+			Strategy: "theirs",
+			Value:    cty.StringVal("c"),
+		},
+		{
+			Conflict: MergeConflict{
+				Path:   cty.GetAttrPath("size"),
+				Base:   cty.NumberIntVal(1),
+				Ours:   cty.NumberIntVal(2),
+				Theirs: cty.NumberIntVal(3),
+			},
+			Strategy: "larger",
+			Value:    cty.NumberIntVal(3),
+		},
+		{
+			Conflict: MergeConflict{
+				Path:   cty.GetAttrPath("tags").Index(cty.StringVal("env")),
+				Base:   cty.StringVal("dev"),
+				Ours:   cty.NilVal,
+				Theirs: cty.StringVal("prod"),
+			},
+			Strategy: "theirs",
+			Value:    cty.StringVal("prod"),
+		},
+	}
+	if !reflect.DeepEqual(resolutions, wantResolutions) {
+		t.Errorf("wrong resolutions\n%s", prettyDiff.Compare(wantResolutions, resolutions))
+	}
+
+	// Omitting a map element is a valid resolution.
+	got, _, err = MergeWithOptions(base, ours, theirs, MergeOptions{
+		Resolvers: []ConflictResolver{ResolveOurs},
+	})
+	if err != nil {
+		t.Fatalf("MergeWithOptions() err = %v", err)
+	}
+	if !got.RawEquals(ours) {
+		t.Errorf("MergeWithOptions\nGot\n%#v\nWant\n%#v", got, ours)
+	}
+}
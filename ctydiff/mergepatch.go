@@ -0,0 +1,267 @@
+package ctydiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// ToMergePatch returns a JSON Merge Patch document, as defined in RFC 7386,
+// that transforms the JSON encoding of the given source value into that of
+// the result of applying the receiver to it. The source value must be a
+// known, non-null object or map.
+//
+// A merge patch can only describe changes to the members of objects, so it
+// is lossy in comparison to a diff:
+//
+//   - Any change within a list, set or tuple causes the whole collection to
+//     be replaced.
+//   - A null in a merge patch means that a member is to be deleted, so
+//     setting an object attribute or map element to null is presented as
+//     deleting it. For object attributes this round-trips through
+//     FromMergePatch, since a deleted attribute becomes null, but map
+//     elements with null values cannot be represented.
+//
+// Unknown values cannot be represented and cause an error.
+func (d Diff) ToMergePatch(source cty.Value) ([]byte, error) {
+	if err := requireMergeable(source); err != nil {
+		return nil, err
+	}
+	target, err := d.Apply(source)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireMergeable(target); err != nil {
+		return nil, err
+	}
+	return mergePatch(source, target, cty.Path{})
+}
+
+// mergePatch returns the JSON merge patch that transforms old into new,
+// where both values are at the given path.
+func mergePatch(old, new cty.Value, path cty.Path) (json.RawMessage, error) {
+	if !isMergeable(old) || !isMergeable(new) {
+		raw, err := ctyjson.Marshal(new, new.Type())
+		if err != nil {
+			return nil, path.NewError(err)
+		}
+		return raw, nil
+	}
+
+	oldMap := old.AsValueMap()
+	newMap := new.AsValueMap()
+	members := make(map[string]json.RawMessage)
+	for k, ov := range oldMap {
+		if nv, exists := newMap[k]; !exists || nv.IsNull() {
+			if !ov.IsNull() {
+				members[k] = json.RawMessage("null")
+			}
+		}
+	}
+	for k, nv := range newMap {
+		if nv.IsNull() {
+			continue
+		}
+		ov, exists := oldMap[k]
+		if exists && ov.IsWhollyKnown() && nv.IsWhollyKnown() && ov.RawEquals(nv) {
+			continue
+		}
+		elemPath := path.Index(cty.StringVal(k))
+		if new.Type().IsObjectType() {
+			elemPath = path.GetAttr(k)
+		}
+		if !exists {
+			ov = cty.NullVal(nv.Type())
+		}
+		raw, err := mergePatch(ov, nv, elemPath)
+		if err != nil {
+			return nil, err
+		}
+		members[k] = raw
+	}
+	return json.Marshal(members)
+}
+
+// isMergeable returns true if the given value is a known, non-null object
+// or map, whose members can be changed individually by a merge patch.
+func isMergeable(val cty.Value) bool {
+	return requireMergeable(val) == nil
+}
+
+func requireMergeable(val cty.Value) error {
+	if err := requireKnown(val); err != nil {
+		return err
+	}
+	if ty := val.Type(); !ty.IsObjectType() && !ty.IsMapType() {
+		return fmt.Errorf("merge patch requires an object or map, not a %s", ty.FriendlyName())
+	}
+	return nil
+}
+
+// FromMergePatch applies the given JSON Merge Patch document, as defined in
+// RFC 7386, to the given source value and returns a diff that makes the
+// same changes.
+//
+// The source value provides the types used to interpret the patch. Members
+// of the patch that are not objects replace the corresponding values
+// entirely, so lists are always replaced as a whole. A null member deletes
+// a map element, while for an object attribute, which cannot be deleted, it
+// sets the attribute to null instead. A patch that adds attributes that the
+// source value's object type does not have is an error.
+func FromMergePatch(source cty.Value, patch []byte) (Diff, error) {
+	if !json.Valid(patch) {
+		return nil, errors.New("invalid JSON Merge Patch document")
+	}
+	target, err := mergeValue(source, patch, cty.Path{})
+	if err != nil {
+		return nil, err
+	}
+	return NewDiff(source, target), nil
+}
+
+// mergeValue returns the result of merging the given patch into the given
+// value, which is at the given path.
+func mergeValue(val cty.Value, patch json.RawMessage, path cty.Path) (cty.Value, error) {
+	ty := val.Type()
+	members, isObject := jsonObjectMembers(patch)
+	if ty.Equals(cty.DynamicPseudoType) {
+		// With no type information, the result is the patch itself with
+		// its null members removed, as if it were merged into an empty
+		// object.
+		stripped, err := stripJSONNulls(patch)
+		if err != nil {
+			return cty.NilVal, path.NewError(err)
+		}
+		implied, err := ctyjson.ImpliedType(stripped)
+		if err != nil {
+			return cty.NilVal, path.NewError(err)
+		}
+		return unmarshalMergeValue(stripped, implied, path)
+	}
+	if !isObject {
+		return unmarshalMergeValue(patch, ty, path)
+	}
+
+	if !val.IsKnown() {
+		return cty.NilVal, path.NewErrorf("cannot merge into an unknown value")
+	}
+	switch {
+	case ty.IsObjectType():
+		atys := ty.AttributeTypes()
+		attrs := make(map[string]cty.Value, len(atys))
+		for name, aty := range atys {
+			if val.IsNull() {
+				attrs[name] = cty.NullVal(aty)
+			} else {
+				attrs[name] = val.GetAttr(name)
+			}
+		}
+		for _, k := range sortedKeys(members) {
+			attrPath := path.GetAttr(k)
+			current, exists := attrs[k]
+			if !exists {
+				return cty.NilVal, attrPath.NewErrorf("object has no attribute %q", k)
+			}
+			if isJSONNull(members[k]) {
+				attrs[k] = cty.NullVal(atys[k])
+				continue
+			}
+			merged, err := mergeValue(current, members[k], attrPath)
+			if err != nil {
+				return cty.NilVal, err
+			}
+			attrs[k] = merged
+		}
+		return cty.ObjectVal(attrs), nil
+	case ty.IsMapType():
+		ety := ty.ElementType()
+		elems := make(map[string]cty.Value)
+		if !val.IsNull() {
+			elems = val.AsValueMap()
+			if elems == nil {
+				elems = make(map[string]cty.Value)
+			}
+		}
+		for _, k := range sortedKeys(members) {
+			if isJSONNull(members[k]) {
+				delete(elems, k)
+				continue
+			}
+			current, exists := elems[k]
+			if !exists {
+				current = cty.NullVal(ety)
+			}
+			merged, err := mergeValue(current, members[k], path.Index(cty.StringVal(k)))
+			if err != nil {
+				return cty.NilVal, err
+			}
+			elems[k] = merged
+		}
+		if len(elems) == 0 {
+			return cty.MapValEmpty(ety), nil
+		}
+		return cty.MapVal(elems), nil
+	default:
+		return cty.NilVal, path.NewErrorf("cannot merge an object into a value of type %s", ty.FriendlyName())
+	}
+}
+
+func unmarshalMergeValue(raw json.RawMessage, ty cty.Type, path cty.Path) (cty.Value, error) {
+	ret, err := ctyjson.Unmarshal(raw, ty)
+	if err != nil {
+		return cty.NilVal, path.NewError(err)
+	}
+	return ret, nil
+}
+
+// jsonObjectMembers returns the members of the given JSON value and true if
+// it is an object, or false otherwise.
+func jsonObjectMembers(raw json.RawMessage) (map[string]json.RawMessage, bool) {
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil, false
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &members); err != nil {
+		return nil, false
+	}
+	return members, true
+}
+
+func isJSONNull(raw json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
+
+// stripJSONNulls returns the given JSON value with all of the null members
+// of its objects removed, recursively.
+func stripJSONNulls(raw json.RawMessage) (json.RawMessage, error) {
+	members, isObject := jsonObjectMembers(raw)
+	if !isObject {
+		return raw, nil
+	}
+	for k, v := range members {
+		if isJSONNull(v) {
+			delete(members, k)
+			continue
+		}
+		stripped, err := stripJSONNulls(v)
+		if err != nil {
+			return nil, err
+		}
+		members[k] = stripped
+	}
+	return json.Marshal(members)
+}
+
+func sortedKeys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,234 @@
+package ctydiff
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestDiff_ToMergePatch(t *testing.T) {
+	source := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("a"),
+		"size": cty.NumberIntVal(1),
+		"list": cty.ListVal([]cty.Value{cty.StringVal("x"), cty.StringVal("y")}),
+		"tags": cty.MapVal(map[string]cty.Value{
+			"env":  cty.StringVal("dev"),
+			"team": cty.StringVal("core"),
+		}),
+		"network": cty.ObjectVal(map[string]cty.Value{
+			"cidr": cty.StringVal("10.0.0.0/16"),
+			"name": cty.StringVal("main"),
+		}),
+	})
+
+	tests := []struct {
+		name   string
+		target cty.Value
+		want   string
+	}{
+		{
+			"Equal",
+			source,
+			`{}`,
+		},
+		{
+			"Changes",
+			cty.ObjectVal(map[string]cty.Value{
+				"name": cty.StringVal("b"),
+				"size": cty.NullVal(cty.Number),
+				"list": cty.ListVal([]cty.Value{cty.StringVal("x"), cty.StringVal("z")}),
+				"tags": cty.MapVal(map[string]cty.Value{
+					"env":   cty.StringVal("prod"),
+					"owner": cty.StringVal("me"),
+				}),
+				"network": cty.ObjectVal(map[string]cty.Value{
+					"cidr": cty.StringVal("10.0.0.0/16"),
+					"name": cty.StringVal("other"),
+				}),
+			}),
+			`{"list":["x","z"],"name":"b","network":{"name":"other"},"size":null,"tags":{"env":"prod","owner":"me","team":null}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewDiff(source, tt.target).ToMergePatch(source)
+			if err != nil {
+				t.Fatalf("ToMergePatch() err = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("wrong result\ngot:  %s\nwant: %s", got, tt.want)
+			}
+
+			diff, err := FromMergePatch(source, got)
+			if err != nil {
+				t.Fatalf("FromMergePatch() err = %v", err)
+			}
+			applied, err := diff.Apply(source)
+			if err != nil {
+				t.Fatalf("Apply() err = %v", err)
+			}
+			if !applied.RawEquals(tt.target) {
+				t.Errorf("round trip\nGot\n%#v\nWant\n%#v", applied, tt.target)
+			}
+		})
+	}
+}
+
+func TestDiff_ToMergePatchErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source cty.Value
+		target cty.Value
+	}{
+		{
+			"NotObject",
+			cty.StringVal("a"),
+			cty.StringVal("b"),
+		},
+		{
+			"TargetNotObject",
+			cty.EmptyObjectVal,
+			cty.StringVal("b"),
+		},
+		{
+			"Unknown",
+			cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("a")}),
+			cty.ObjectVal(map[string]cty.Value{"a": cty.UnknownVal(cty.String)}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := Diff{
+				ReplaceChange{
+					Path:     cty.Path{},
+					OldValue: tt.source,
+					NewValue: tt.target,
+				},
+			}
+			got, err := diff.ToMergePatch(tt.source)
+			if err == nil {
+				t.Fatalf("ToMergePatch() succeeded; want error\nGot %s", got)
+			}
+		})
+	}
+}
+
+func TestFromMergePatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		source cty.Value
+		patch  string
+		want   cty.Value
+	}{
+		{
+			"Empty",
+			cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("a")}),
+			`{}`,
+			cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("a")}),
+		},
+		{
+			"ObjectAttributes",
+			cty.ObjectVal(map[string]cty.Value{
+				"a": cty.StringVal("a"),
+				"b": cty.StringVal("b"),
+				"c": cty.ListVal([]cty.Value{cty.StringVal("c")}),
+			}),
+			`{"a": "z", "b": null, "c": ["d", "e"]}`,
+			cty.ObjectVal(map[string]cty.Value{
+				"a": cty.StringVal("z"),
+				"b": cty.NullVal(cty.String),
+				"c": cty.ListVal([]cty.Value{cty.StringVal("d"), cty.StringVal("e")}),
+			}),
+		},
+		{
+			"NestedNullObject",
+			cty.ObjectVal(map[string]cty.Value{
+				"o": cty.NullVal(cty.Object(map[string]cty.Type{
+					"a": cty.String,
+					"b": cty.String,
+				})),
+			}),
+			`{"o": {"a": "a"}}`,
+			cty.ObjectVal(map[string]cty.Value{
+				"o": cty.ObjectVal(map[string]cty.Value{
+					"a": cty.StringVal("a"),
+					"b": cty.NullVal(cty.String),
+				}),
+			}),
+		},
+		{
+			"Map",
+			cty.MapVal(map[string]cty.Value{
+				"a": cty.MapVal(map[string]cty.Value{"x": cty.StringVal("x")}),
+				"b": cty.MapVal(map[string]cty.Value{"y": cty.StringVal("y")}),
+			}),
+			`{"a": {"x": null, "z": "z"}, "b": null, "c": {"w": "w", "v": null}}`,
+			cty.MapVal(map[string]cty.Value{
+				"a": cty.MapVal(map[string]cty.Value{"z": cty.StringVal("z")}),
+				"c": cty.MapVal(map[string]cty.Value{"w": cty.StringVal("w")}),
+			}),
+		},
+		{
+			"MapDeleteAll",
+			cty.MapVal(map[string]cty.Value{"a": cty.StringVal("a")}),
+			`{"a": null}`,
+			cty.MapValEmpty(cty.String),
+		},
+		{
+			"Dynamic",
+			cty.ObjectVal(map[string]cty.Value{
+				"d": cty.NullVal(cty.DynamicPseudoType),
+			}),
+			`{"d": {"a": true, "b": null}}`,
+			cty.ObjectVal(map[string]cty.Value{
+				"d": cty.ObjectVal(map[string]cty.Value{"a": cty.True}),
+			}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff, err := FromMergePatch(tt.source, []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("FromMergePatch() err = %v", err)
+			}
+			got, err := diff.Apply(tt.source)
+			if err != nil {
+				t.Fatalf("Apply() err = %v", err)
+			}
+			if !got.RawEquals(tt.want) {
+				t.Errorf("Apply\nGot\n%#v\nWant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromMergePatchErrors(t *testing.T) {
+	source := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("a"),
+		"list": cty.ListValEmpty(cty.String),
+		"u":    cty.UnknownVal(cty.Map(cty.String)),
+	})
+	tests := []struct {
+		name  string
+		patch string
+	}{
+		{"InvalidJSON", `{`},
+		{"NoAttribute", `{"nope": "a"}`},
+		{"WrongType", `{"name": ["a"]}`},
+		{"ObjectIntoList", `{"list": {"a": "b"}}`},
+		{"Unknown", `{"u": {"a": "b"}}`},
+		{"ReplaceRoot", `"a"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromMergePatch(source, []byte(tt.patch))
+			if err == nil {
+				t.Fatalf("FromMergePatch() succeeded; want error\n%s", prettyDiff.Sprint(got))
+			}
+		})
+	}
+}
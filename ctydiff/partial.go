@@ -0,0 +1,132 @@
+package ctydiff
+
+import (
+	"fmt"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ChangeStatus is the outcome of a single change, as reported by
+// ApplyPartial.
+type ChangeStatus string
+
+const (
+	// ChangeApplied is the status of a change that was applied.
+	ChangeApplied ChangeStatus = "applied"
+
+	// ChangeFailed is the status of a change that could not be applied,
+	// and so was skipped.
+	ChangeFailed ChangeStatus = "failed"
+
+	// ChangeSkipped is the status of a change inside a NestedDiff that
+	// failed, which was therefore not attempted.
+	ChangeSkipped ChangeStatus = "skipped"
+)
+
+// ChangeResult describes the outcome of a single change, as reported by
+// ApplyPartial.
+type ChangeResult struct {
+	// Change is the change itself, and Path is its absolute path.
+	Change Change
+	Path   cty.Path
+
+	Status ChangeStatus
+
+	// Err is the reason for a ChangeFailed status, and is nil otherwise.
+	// Any cty.PathError it contains has an absolute path.
+	Err error
+}
+
+// ApplyPartial is like Apply except that rather than failing altogether
+// when a change fails, it skips that change and continues with the rest.
+//
+// ApplyPartial returns the value produced by the changes that succeeded,
+// along with a ChangeResult for each change, in the order that Walk would
+// visit them. A NestedDiff is applied only if the value at its path matches
+// its OldValue, in which case its nested changes are each applied or
+// skipped in the same way as those of the receiver; if it fails then each
+// of its nested changes has the ChangeSkipped status.
+//
+// Since later changes in a diff may depend on earlier ones, in particular
+// the indices of list elements, the changes that follow a failed change can
+// have unintended effects. Callers should generally use ApplyPartial only
+// to report on which parts of a diff can be applied, or for diffs whose
+// changes are independent of one another.
+func (d Diff) ApplyPartial(source cty.Value) (cty.Value, []ChangeResult) {
+	var results []ChangeResult
+	ret := d.applyPartial(source, nil, &results)
+	return ret, results
+}
+
+func (d Diff) applyPartial(val cty.Value, prefix cty.Path, results *[]ChangeResult) cty.Value {
+	for i, c := range d {
+		if c == nil {
+			*results = append(*results, ChangeResult{
+				Status: ChangeFailed,
+				Err:    prefixError(prefix, fmt.Errorf("change %d is nil", i)),
+			})
+			continue
+		}
+		absPath := joinPath(prefix, c.path())
+		nested, ok := c.(NestedDiff)
+		if !ok {
+			v, err := c.apply(val)
+			if err != nil {
+				*results = append(*results, ChangeResult{
+					Change: c,
+					Path:   absPath,
+					Status: ChangeFailed,
+					Err:    prefixError(prefix, err),
+				})
+				continue
+			}
+			*results = append(*results, ChangeResult{
+				Change: c,
+				Path:   absPath,
+				Status: ChangeApplied,
+			})
+			val = v
+			continue
+		}
+
+		// The nested results follow the result for the NestedDiff itself,
+		// which we don't know until they have been collected.
+		idx := len(*results)
+		*results = append(*results, ChangeResult{
+			Change: c,
+			Path:   absPath,
+			Status: ChangeApplied,
+		})
+		v, err := transformPath(val, nested.Path, func(existing cty.Value) (cty.Value, error) {
+			if !rawEquals(existing, nested.OldValue) {
+				return cty.NilVal, nested.Path.NewErrorf("existing value does not match")
+			}
+			return nested.Diff.applyPartial(existing, absPath, results), nil
+		})
+		if err != nil {
+			(*results)[idx].Status = ChangeFailed
+			(*results)[idx].Err = prefixError(prefix, err)
+			*results = (*results)[:idx+1]
+			nested.Diff.walk(absPath, func(path cty.Path, c Change) error {
+				*results = append(*results, ChangeResult{
+					Change: c,
+					Path:   path,
+					Status: ChangeSkipped,
+				})
+				return nil
+			})
+			continue
+		}
+		val = v
+	}
+	return val
+}
+
+// prefixError returns the given error with the given path prepended to its
+// own path, if it has one.
+func prefixError(prefix cty.Path, err error) error {
+	if len(prefix) == 0 {
+		return err
+	}
+	return prefix.NewError(err)
+}
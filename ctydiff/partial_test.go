@@ -0,0 +1,135 @@
+package ctydiff
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestDiffApplyPartial(t *testing.T) {
+	member := cty.ObjectVal(map[string]cty.Value{
+		"id":    cty.StringVal("1"),
+		"value": cty.StringVal("p"),
+	})
+	obj := cty.ObjectVal(map[string]cty.Value{
+		"c": cty.StringVal("c"),
+		"d": cty.StringVal("d"),
+	})
+	source := cty.ObjectVal(map[string]cty.Value{
+		"a":    cty.StringVal("a"),
+		"b":    cty.StringVal("b"),
+		"list": cty.ListVal([]cty.Value{cty.StringVal("x"), cty.StringVal("y")}),
+		"set":  cty.SetVal([]cty.Value{member}),
+		"obj":  obj,
+	})
+	diff := Diff{
+		ReplaceChange{
+			Path:     cty.GetAttrPath("a"),
+			OldValue: cty.StringVal("a"),
+			NewValue: cty.StringVal("A"),
+		},
+		ReplaceChange{
+			Path:     cty.GetAttrPath("b"),
+			OldValue: cty.StringVal("z"),
+			NewValue: cty.StringVal("B"),
+		},
+		NestedDiff{
+			Path:     cty.GetAttrPath("set").Index(member),
+			OldValue: member,
+			Diff: Diff{
+				ReplaceChange{
+					Path:     cty.GetAttrPath("value"),
+					OldValue: cty.StringVal("p"),
+					NewValue: cty.StringVal("q"),
+				},
+			},
+		},
+		NestedDiff{
+			Path:     cty.GetAttrPath("list").Index(cty.NumberIntVal(0)),
+			OldValue: cty.StringVal("w"),
+			Diff: Diff{
+				ReplaceChange{
+					OldValue: cty.StringVal("w"),
+					NewValue: cty.StringVal("z"),
+				},
+			},
+		},
+		NestedDiff{
+			Path:     cty.GetAttrPath("obj"),
+			OldValue: obj,
+			Diff: Diff{
+				ReplaceChange{
+					Path:     cty.GetAttrPath("c"),
+					OldValue: cty.StringVal("c"),
+					NewValue: cty.StringVal("C"),
+				},
+				ReplaceChange{
+					Path:     cty.GetAttrPath("d"),
+					OldValue: cty.StringVal("x"),
+					NewValue: cty.StringVal("D"),
+				},
+			},
+		},
+		nil,
+	}
+
+	got, results := diff.ApplyPartial(source)
+	want := cty.ObjectVal(map[string]cty.Value{
+		"a":    cty.StringVal("A"),
+		"b":    cty.StringVal("b"),
+		"list": cty.ListVal([]cty.Value{cty.StringVal("x"), cty.StringVal("y")}),
+		"set": cty.SetVal([]cty.Value{
+			cty.ObjectVal(map[string]cty.Value{
+				"id":    cty.StringVal("1"),
+				"value": cty.StringVal("q"),
+			}),
+		}),
+		"obj": cty.ObjectVal(map[string]cty.Value{
+			"c": cty.StringVal("C"),
+			"d": cty.StringVal("d"),
+		}),
+	})
+	if !got.RawEquals(want) {
+		t.Errorf("ApplyPartial\nGot\n%#v\nWant\n%#v", got, want)
+	}
+
+	wantResults := []struct {
+		path    cty.Path
+		status  ChangeStatus
+		err     string
+		errPath cty.Path
+	}{
+		{cty.GetAttrPath("a"), ChangeApplied, "", nil},
+		{cty.GetAttrPath("b"), ChangeFailed, "existing value does not match", cty.GetAttrPath("b")},
+		{cty.GetAttrPath("set").Index(member), ChangeApplied, "", nil},
+		{cty.GetAttrPath("set").Index(member).GetAttr("value"), ChangeApplied, "", nil},
+		{cty.GetAttrPath("list").Index(cty.NumberIntVal(0)), ChangeFailed, "existing value does not match", cty.GetAttrPath("list").Index(cty.NumberIntVal(0))},
+		{cty.GetAttrPath("list").Index(cty.NumberIntVal(0)), ChangeSkipped, "", nil},
+		{cty.GetAttrPath("obj"), ChangeApplied, "", nil},
+		{cty.GetAttrPath("obj").GetAttr("c"), ChangeApplied, "", nil},
+		{cty.GetAttrPath("obj").GetAttr("d"), ChangeFailed, "existing value does not match", cty.GetAttrPath("obj").GetAttr("d")},
+		{nil, ChangeFailed, "change 5 is nil", nil},
+	}
+	if len(results) != len(wantResults) {
+		t.Fatalf("got %d results; want %d", len(results), len(wantResults))
+	}
+	for i, want := range wantResults {
+		got := results[i]
+		if !pathsEqual(got.Path, want.path) {
+			t.Errorf("result %d has path %s; want %s", i, FormatPath(got.Path), FormatPath(want.path))
+		}
+		if got.Status != want.status {
+			t.Errorf("result %d has status %s; want %s", i, got.Status, want.status)
+		}
+		switch {
+		case got.Err == nil && want.err != "":
+			t.Errorf("result %d has no error; want %s", i, want.err)
+		case got.Err != nil && got.Err.Error() != want.err:
+			t.Errorf("result %d has error %q; want %q", i, got.Err, want.err)
+		case got.Err != nil && want.errPath != nil:
+			if pathErr, ok := got.Err.(cty.PathError); !ok || !pathsEqual(pathErr.Path, want.errPath) {
+				t.Errorf("result %d has error %#v; want error at %s", i, got.Err, FormatPath(want.errPath))
+			}
+		}
+	}
+}
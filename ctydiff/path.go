@@ -0,0 +1,340 @@
+package ctydiff
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// applyPath is a variant of cty.Path.Apply that returns an error, rather
+// than panicking, for any path that cannot be traversed in the given value.
+//
+// Since diffs are often constructed from external input, the changes in a
+// diff may refer to paths that are not valid for the value they are applied
+// to, and so all path traversal within this package should be done via this
+// function and its friends.
+func applyPath(val cty.Value, path cty.Path) (cty.Value, error) {
+	for i, step := range path {
+		next, err := applyStep(val, step)
+		if err != nil {
+			return cty.NilVal, path[:i+1].NewError(err)
+		}
+		val = next
+	}
+	return val, nil
+}
+
+// transformPath rebuilds the given value with the value at the given path
+// replaced by the result of passing its current value to the given function.
+//
+// Any errors returned by the function are returned verbatim, so the function
+// is responsible for annotating them with a suitable path.
+func transformPath(val cty.Value, path cty.Path, fn func(cty.Value) (cty.Value, error)) (cty.Value, error) {
+	return transformPathFrom(val, path, 0, fn)
+}
+
+func transformPathFrom(val cty.Value, path cty.Path, i int, fn func(cty.Value) (cty.Value, error)) (cty.Value, error) {
+	if i == len(path) {
+		return fn(val)
+	}
+	child, err := applyStep(val, path[i])
+	if err != nil {
+		return cty.NilVal, path[:i+1].NewError(err)
+	}
+	newChild, err := transformPathFrom(child, path, i+1, fn)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	ret, err := replaceStep(val, path[i], newChild)
+	if err != nil {
+		return cty.NilVal, path[:i+1].NewError(err)
+	}
+	return ret, nil
+}
+
+// applyStep returns the value that the given step selects from the given
+// value, or an error if the step cannot be applied.
+func applyStep(val cty.Value, step cty.PathStep) (cty.Value, error) {
+	if err := requireKnown(val); err != nil {
+		return cty.NilVal, err
+	}
+	ty := val.Type()
+
+	switch step := step.(type) {
+	case cty.GetAttrStep:
+		if !ty.IsObjectType() {
+			return cty.NilVal, fmt.Errorf("cannot access attribute %q on a value of type %s", step.Name, ty.FriendlyName())
+		}
+		if !ty.HasAttribute(step.Name) {
+			return cty.NilVal, fmt.Errorf("object has no attribute %q", step.Name)
+		}
+		return val.GetAttr(step.Name), nil
+	case cty.IndexStep:
+		switch {
+		case ty.IsListType() || ty.IsTupleType():
+			idx, err := listIndex(step.Key, val.LengthInt())
+			if err != nil {
+				return cty.NilVal, err
+			}
+			return val.Index(cty.NumberIntVal(int64(idx))), nil
+		case ty.IsMapType():
+			key, err := mapKey(step.Key)
+			if err != nil {
+				return cty.NilVal, err
+			}
+			if !val.HasIndex(cty.StringVal(key)).True() {
+				return cty.NilVal, fmt.Errorf("map has no element with key %q", key)
+			}
+			return val.Index(cty.StringVal(key)), nil
+		case ty.IsSetType():
+			// Set elements are addressed by their own value.
+			if err := requireElementType(ty, step.Key); err != nil {
+				return cty.NilVal, fmt.Errorf("invalid set element: %s", err)
+			}
+			if !val.AsValueSet().Has(step.Key) {
+				return cty.NilVal, errors.New("set does not contain the given element")
+			}
+			return step.Key, nil
+		default:
+			return cty.NilVal, fmt.Errorf("cannot index a value of type %s", ty.FriendlyName())
+		}
+	default:
+		return cty.NilVal, fmt.Errorf("unsupported path step %T", step)
+	}
+}
+
+// replaceStep returns a copy of the given parent value with the element
+// selected by the given step replaced with the given new value.
+//
+// When the parent is a map, the key given in the step need not already
+// exist, in which case the new element is added. When the parent is a set,
+// the step's key is the element to be replaced.
+func replaceStep(parent cty.Value, step cty.PathStep, new cty.Value) (cty.Value, error) {
+	if err := requireKnown(parent); err != nil {
+		return cty.NilVal, err
+	}
+	if new == cty.NilVal {
+		return cty.NilVal, errors.New("new value is missing")
+	}
+	ty := parent.Type()
+
+	switch {
+	case ty.IsObjectType():
+		attr, ok := step.(cty.GetAttrStep)
+		if !ok {
+			return cty.NilVal, errors.New("object attributes must be selected by name")
+		}
+		kv := parent.AsValueMap()
+		if kv == nil {
+			kv = make(map[string]cty.Value)
+		}
+		kv[attr.Name] = new
+		return cty.ObjectVal(kv), nil
+	case ty.IsMapType():
+		index, ok := step.(cty.IndexStep)
+		if !ok {
+			return cty.NilVal, errors.New("map elements must be selected by key")
+		}
+		key, err := mapKey(index.Key)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		if err := requireElementType(ty, new); err != nil {
+			return cty.NilVal, err
+		}
+		kv := parent.AsValueMap()
+		if kv == nil {
+			kv = make(map[string]cty.Value)
+		}
+		kv[key] = new
+		return cty.MapVal(kv), nil
+	case ty.IsListType() || ty.IsTupleType():
+		index, ok := step.(cty.IndexStep)
+		if !ok {
+			return cty.NilVal, errors.New("list elements must be selected by index")
+		}
+		idx, err := listIndex(index.Key, parent.LengthInt())
+		if err != nil {
+			return cty.NilVal, err
+		}
+		if err := requireElementType(ty, new); err != nil {
+			return cty.NilVal, err
+		}
+		vv := parent.AsValueSlice()
+		vv[idx] = new
+		return sequenceVal(ty, vv), nil
+	case ty.IsSetType():
+		index, ok := step.(cty.IndexStep)
+		if !ok {
+			return cty.NilVal, errors.New("set elements must be selected by value")
+		}
+		if err := requireElementType(ty, index.Key); err != nil {
+			return cty.NilVal, fmt.Errorf("invalid set element: %s", err)
+		}
+		if err := requireElementType(ty, new); err != nil {
+			return cty.NilVal, err
+		}
+		s := parent.AsValueSet()
+		if !s.Has(index.Key) {
+			return cty.NilVal, errors.New("set does not contain the given element")
+		}
+		s.Remove(index.Key)
+		s.Add(new)
+		return cty.SetValFromValueSet(s), nil
+	}
+	return cty.NilVal, fmt.Errorf("cannot replace an element of a value of type %s", ty.FriendlyName())
+}
+
+// deleteStep returns a copy of the given parent value with the element
+// selected by the given step removed.
+func deleteStep(parent cty.Value, step cty.PathStep) (cty.Value, error) {
+	if err := requireKnown(parent); err != nil {
+		return cty.NilVal, err
+	}
+	ty := parent.Type()
+
+	switch {
+	case ty.IsObjectType():
+		attr, ok := step.(cty.GetAttrStep)
+		if !ok {
+			return cty.NilVal, errors.New("object attributes must be selected by name")
+		}
+		kv := parent.AsValueMap()
+		delete(kv, attr.Name)
+		return cty.ObjectVal(kv), nil
+	case ty.IsMapType():
+		index, ok := step.(cty.IndexStep)
+		if !ok {
+			return cty.NilVal, errors.New("map elements must be selected by key")
+		}
+		key, err := mapKey(index.Key)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		kv := parent.AsValueMap()
+		delete(kv, key)
+		if len(kv) == 0 {
+			return cty.MapValEmpty(ty.ElementType()), nil
+		}
+		return cty.MapVal(kv), nil
+	case ty.IsListType() || ty.IsTupleType():
+		index, ok := step.(cty.IndexStep)
+		if !ok {
+			return cty.NilVal, errors.New("list elements must be selected by index")
+		}
+		idx, err := listIndex(index.Key, parent.LengthInt())
+		if err != nil {
+			return cty.NilVal, err
+		}
+		vv := parent.AsValueSlice()
+		vv = append(vv[:idx], vv[idx+1:]...)
+		return sequenceVal(ty, vv), nil
+	}
+	return cty.NilVal, errors.New("value is not indexable")
+}
+
+// sequenceVal constructs a list or tuple value of the same kind as the given
+// type from the given elements, which must already have been checked for
+// conformance with the list element type, if any.
+func sequenceVal(ty cty.Type, vals []cty.Value) cty.Value {
+	if ty.IsTupleType() {
+		return cty.TupleVal(vals)
+	}
+	if len(vals) == 0 {
+		return cty.ListValEmpty(ty.ElementType())
+	}
+	return cty.ListVal(vals)
+}
+
+// isKnownSequence returns true if the given value is a known, non-null list
+// or tuple.
+func isKnownSequence(val cty.Value) bool {
+	if requireKnown(val) != nil {
+		return false
+	}
+	ty := val.Type()
+	return ty.IsListType() || ty.IsTupleType()
+}
+
+// requireKnown returns an error if the given value is not a known, non-null
+// value, and thus cannot be traversed or rebuilt.
+func requireKnown(val cty.Value) error {
+	switch {
+	case val == cty.NilVal:
+		return errors.New("value is missing")
+	case val.IsNull():
+		return errors.New("value is null")
+	case !val.IsKnown():
+		return errors.New("value is unknown")
+	}
+	return nil
+}
+
+// requireElementType returns an error if the given value cannot be used as
+// an element of a collection of the given type. Structural types accept
+// elements of any type, since their types are rebuilt along with them.
+func requireElementType(ty cty.Type, val cty.Value) error {
+	if val == cty.NilVal {
+		return errors.New("value is missing")
+	}
+	if !ty.IsCollectionType() {
+		return nil
+	}
+	if ety := ty.ElementType(); !val.Type().Equals(ety) {
+		return fmt.Errorf("value must be a %s, not a %s", ety.FriendlyName(), val.Type().FriendlyName())
+	}
+	return nil
+}
+
+// listIndex converts the given key to an index into a sequence of the given
+// length, or returns an error if it is not a valid index.
+func listIndex(key cty.Value, length int) (int, error) {
+	if key == cty.NilVal || !key.Type().Equals(cty.Number) {
+		return 0, errors.New("list index must be a number")
+	}
+	if key.IsNull() || !key.IsKnown() {
+		return 0, errors.New("list index must be known and not null")
+	}
+	idx, acc := key.AsBigFloat().Int64()
+	if acc != big.Exact {
+		return 0, fmt.Errorf("list index %s is not an integer", key.AsBigFloat().Text('f', -1))
+	}
+	if idx < 0 || idx >= int64(length) {
+		return 0, fmt.Errorf("list index %d is out of range", idx)
+	}
+	return int(idx), nil
+}
+
+// stepIndex returns the list index selected by the given step, if it is an
+// IndexStep whose key is a non-negative integer.
+func stepIndex(step cty.PathStep) (int, bool) {
+	index, ok := step.(cty.IndexStep)
+	if !ok {
+		return 0, false
+	}
+	idx, err := listIndex(index.Key, int(^uint(0)>>1))
+	return idx, err == nil
+}
+
+// mapKey converts the given key to a map key string, or returns an error
+// if it is not a valid map key.
+func mapKey(key cty.Value) (string, error) {
+	if key == cty.NilVal || !key.Type().Equals(cty.String) {
+		return "", errors.New("map key must be a string")
+	}
+	if key.IsNull() || !key.IsKnown() {
+		return "", errors.New("map key must be known and not null")
+	}
+	return key.AsString(), nil
+}
+
+// rawEquals is like cty.Value.RawEquals except that it tolerates cty.NilVal
+// on either side, which is equal only to itself.
+func rawEquals(a, b cty.Value) bool {
+	if a == cty.NilVal || b == cty.NilVal {
+		return a == cty.NilVal && b == cty.NilVal
+	}
+	return a.RawEquals(b)
+}
@@ -0,0 +1,236 @@
+package ctydiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// FormatPath returns a string representation of the given path, in a
+// syntax similar to an HCL traversal, that ParsePath can convert back into
+// an equal path.
+//
+// Each attribute step is written as a period followed by the attribute
+// name, which is quoted as a JSON string if it is not a valid identifier:
+//
+//	.name
+//	."not an identifier"
+//
+// Each index step is written as its key in brackets. A known, non-null key
+// that is a string, number or bool is written as a JSON value, which covers
+// list indices and map keys along with set elements of those types:
+//
+//	[0]
+//	["key"]
+//	[true]
+//
+// Any other key, which can only address a set element, is written as a JSON
+// object with "value" and "type" properties, as produced by ctyjson.Marshal
+// for cty.DynamicPseudoType, so that its type is retained:
+//
+//	[{"value":{"id":"a"},"type":["object",{"id":"string"}]}]
+//
+// The empty path, which refers to the root value, is written as an empty
+// string. An unknown key is written as "[?]", which ParsePath does not
+// accept, since there is no way to represent an unknown value exactly.
+// ParsePath also does not accept infinite numbers, or numbers with
+// exponents of extreme magnitude, which are impractical to format.
+func FormatPath(path cty.Path) string {
+	var buf strings.Builder
+	for _, step := range path {
+		switch step := step.(type) {
+		case cty.GetAttrStep:
+			buf.WriteByte('.')
+			if isIdentifier(step.Name) {
+				buf.WriteString(step.Name)
+			} else {
+				buf.Write(marshalPathJSON(step.Name))
+			}
+		case cty.IndexStep:
+			buf.WriteByte('[')
+			buf.WriteString(formatPathKey(step.Key))
+			buf.WriteByte(']')
+		}
+	}
+	return buf.String()
+}
+
+func formatPathKey(key cty.Value) string {
+	if key == cty.NilVal || !key.IsWhollyKnown() {
+		return "?"
+	}
+	if !key.IsNull() {
+		switch key.Type() {
+		case cty.String:
+			return string(marshalPathJSON(key.AsString()))
+		case cty.Number:
+			// Integers, such as list indices, are always written in plain
+			// decimal, rather than as 1e+06 for 1000000.
+			f := key.AsBigFloat()
+			if f.IsInt() {
+				return f.Text('f', -1)
+			}
+			return f.Text('g', -1)
+		case cty.Bool:
+			return fmt.Sprint(key.True())
+		}
+	}
+	raw, err := ctyjson.Marshal(key, cty.DynamicPseudoType)
+	if err != nil {
+		// Should never happen, since any known value can be marshaled
+		// along with its type.
+		return "?"
+	}
+	return string(raw)
+}
+
+// marshalPathJSON returns the given string as a JSON string, without the
+// escaping of HTML characters that json.Marshal does.
+func marshalPathJSON(s string) []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// isIdentifier returns true if the given string is a valid HCL identifier.
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case unicode.IsLetter(r) || r == '_':
+		case i > 0 && (unicode.IsDigit(r) || r == '-'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ParsePath parses a path in the syntax returned by FormatPath. The empty
+// string is parsed as the empty path, which refers to the root value.
+func ParsePath(s string) (cty.Path, error) {
+	var path cty.Path
+	for pos := 0; pos < len(s); {
+		switch s[pos] {
+		case '.':
+			pos++
+			if pos < len(s) && s[pos] == '"' {
+				var name string
+				n, err := decodePathJSON(s[pos:], &name)
+				if err != nil {
+					return nil, fmt.Errorf("invalid attribute name at offset %d: %s", pos, err)
+				}
+				path = path.GetAttr(name)
+				pos += n
+				continue
+			}
+			end := pos
+			for end < len(s) && s[end] != '.' && s[end] != '[' {
+				end++
+			}
+			name := s[pos:end]
+			if !isIdentifier(name) {
+				return nil, fmt.Errorf("invalid attribute name at offset %d: must be an identifier or a quoted string", pos)
+			}
+			path = path.GetAttr(name)
+			pos = end
+		case '[':
+			pos++
+			key, n, err := parsePathKey(s[pos:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid index at offset %d: %s", pos, err)
+			}
+			pos += n
+			if pos >= len(s) || s[pos] != ']' {
+				return nil, fmt.Errorf("missing closing bracket at offset %d", pos)
+			}
+			path = path.Index(key)
+			pos++
+		default:
+			r, _ := utf8.DecodeRuneInString(s[pos:])
+			return nil, fmt.Errorf("unexpected %q at offset %d: each step must start with a period or a bracket", r, pos)
+		}
+	}
+	return path, nil
+}
+
+// parsePathKey parses an index key in the syntax returned by formatPathKey
+// from the start of the given string, returning the key and the number of
+// bytes consumed.
+func parsePathKey(s string) (cty.Value, int, error) {
+	var raw json.RawMessage
+	n, err := decodePathJSON(s, &raw)
+	if err != nil {
+		return cty.NilVal, 0, err
+	}
+	switch raw[0] {
+	case '{':
+		key, err := ctyjson.Unmarshal(raw, cty.DynamicPseudoType)
+		if err != nil {
+			return cty.NilVal, 0, err
+		}
+		return key, n, cty.Walk(key, func(_ cty.Path, v cty.Value) (bool, error) {
+			return true, checkPathNumber(v)
+		})
+	case '"':
+		var str string
+		json.Unmarshal(raw, &str)
+		return cty.StringVal(str), n, nil
+	case 't', 'f':
+		return cty.BoolVal(raw[0] == 't'), n, nil
+	case 'n':
+		return cty.NilVal, 0, fmt.Errorf("null is not a valid key")
+	case '[':
+		return cty.NilVal, 0, fmt.Errorf("a key that is not a string, number or bool must have its type given")
+	default:
+		key, err := cty.ParseNumberVal(string(raw))
+		if err != nil {
+			return cty.NilVal, 0, err
+		}
+		return key, n, checkPathNumber(key)
+	}
+}
+
+// maxPathExponent is the largest magnitude of binary exponent that
+// ParsePath accepts for a number, since formatting numbers with much larger
+// exponents as decimal is slow.
+const maxPathExponent = 4096
+
+// checkPathNumber returns an error if the given value is a number that
+// ParsePath does not accept.
+func checkPathNumber(v cty.Value) error {
+	if v.IsNull() || !v.Type().Equals(cty.Number) {
+		return nil
+	}
+	f := v.AsBigFloat()
+	if f.IsInf() {
+		return fmt.Errorf("number is infinite")
+	}
+	if exp := f.MantExp(nil); exp > maxPathExponent || exp < -maxPathExponent {
+		return fmt.Errorf("number is out of range")
+	}
+	return nil
+}
+
+// decodePathJSON decodes the JSON value at the start of the given string
+// into the given target, returning the number of bytes that it occupies.
+func decodePathJSON(s string, target interface{}) (int, error) {
+	if s == "" || s[0] == ' ' || s[0] == '\t' || s[0] == '\n' || s[0] == '\r' {
+		return 0, fmt.Errorf("expected a JSON value")
+	}
+	dec := json.NewDecoder(strings.NewReader(s))
+	if err := dec.Decode(target); err != nil {
+		return 0, err
+	}
+	return int(dec.InputOffset()), nil
+}
@@ -0,0 +1,225 @@
+package ctydiff
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestFormatPath(t *testing.T) {
+	tests := []struct {
+		name string
+		path cty.Path
+		want string
+	}{
+		{
+			"Root",
+			nil,
+			"",
+		},
+		{
+			"Attr",
+			cty.GetAttrPath("a").GetAttr("b_c-d"),
+			".a.b_c-d",
+		},
+		{
+			"QuotedAttr",
+			cty.GetAttrPath("a b").GetAttr("0").GetAttr("<x>"),
+			`."a b"."0"."<x>"`,
+		},
+		{
+			"ListIndex",
+			cty.GetAttrPath("list").Index(cty.NumberIntVal(12)),
+			".list[12]",
+		},
+		{
+			"LargeIndex",
+			cty.IndexPath(cty.NumberIntVal(1000000)).Index(cty.NumberIntVal(1234567)),
+			"[1000000][1234567]",
+		},
+		{
+			"MapKey",
+			cty.IndexPath(cty.StringVal(`a "b"`)).Index(cty.StringVal("")),
+			`["a \"b\""][""]`,
+		},
+		{
+			"SetMemberPrimitive",
+			cty.IndexPath(cty.True).Index(cty.NumberFloatVal(1.5)),
+			"[true][1.5]",
+		},
+		{
+			"SetMemberObject",
+			cty.GetAttrPath("set").Index(cty.ObjectVal(map[string]cty.Value{"id": cty.StringVal("a")})),
+			`.set[{"value":{"id":"a"},"type":["object",{"id":"string"}]}]`,
+		},
+		{
+			"SetMemberNull",
+			cty.IndexPath(cty.NullVal(cty.String)),
+			`[{"value":null,"type":"string"}]`,
+		},
+		{
+			"Unknown",
+			cty.IndexPath(cty.UnknownVal(cty.Number)).GetAttr("a"),
+			"[?].a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatPath(tt.path); got != tt.want {
+				t.Errorf("FormatPath() = %s; want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want cty.Path
+	}{
+		{
+			"Root",
+			"",
+			nil,
+		},
+		{
+			"Attr",
+			".a.b",
+			cty.GetAttrPath("a").GetAttr("b"),
+		},
+		{
+			"QuotedAttr",
+			`."a.b"[0]`,
+			cty.GetAttrPath("a.b").Index(cty.NumberIntVal(0)),
+		},
+		{
+			"Index",
+			`["a]"][-2][1e3][false]`,
+			cty.IndexPath(cty.StringVal("a]")).
+				Index(cty.NumberIntVal(-2)).
+				Index(cty.NumberIntVal(1000)).
+				Index(cty.False),
+		},
+		{
+			"SetMember",
+			`[{"value":["a"],"type":["set","string"]}].x`,
+			cty.IndexPath(cty.SetVal([]cty.Value{cty.StringVal("a")})).GetAttr("x"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePath(tt.s)
+			if err != nil {
+				t.Fatalf("ParsePath() err = %v", err)
+			}
+			if !pathsEqual(got, tt.want) {
+				t.Errorf("ParsePath() = %#v; want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePath_roundTrip(t *testing.T) {
+	paths := []cty.Path{
+		cty.GetAttrPath("a").Index(cty.StringVal("k")).Index(cty.NumberIntVal(3)),
+		cty.GetAttrPath("").GetAttr("日本").GetAttr("é-1"),
+		cty.IndexPath(cty.StringVal("\x00\n ")),
+		cty.IndexPath(cty.MustParseNumberVal("3.14159265358979323846264338327950288")),
+		cty.IndexPath(cty.ListVal([]cty.Value{cty.NullVal(cty.Bool)})),
+		cty.IndexPath(cty.MapVal(map[string]cty.Value{"a": cty.NumberIntVal(1)})),
+		cty.IndexPath(cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.NumberIntVal(1)})),
+	}
+
+	for _, path := range paths {
+		s := FormatPath(path)
+		got, err := ParsePath(s)
+		if err != nil {
+			t.Errorf("ParsePath(%s) err = %v", s, err)
+			continue
+		}
+		if !pathsEqual(got, path) {
+			t.Errorf("ParsePath(%s) = %#v; want %#v", s, got, path)
+		}
+	}
+}
+
+func TestParsePath_errors(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{
+			"a",
+			`unexpected 'a' at offset 0: each step must start with a period or a bracket`,
+		},
+		{
+			".a..b",
+			"invalid attribute name at offset 3: must be an identifier or a quoted string",
+		},
+		{
+			`."a`,
+			"invalid attribute name at offset 1: unexpected EOF",
+		},
+		{
+			"[1",
+			"missing closing bracket at offset 2",
+		},
+		{
+			"[ 1]",
+			"invalid index at offset 1: expected a JSON value",
+		},
+		{
+			"[?]",
+			"invalid index at offset 1: invalid character '?' looking for beginning of value",
+		},
+		{
+			"[null]",
+			"invalid index at offset 1: null is not a valid key",
+		},
+		{
+			"[1e999999999]",
+			"invalid index at offset 1: number is infinite",
+		},
+		{
+			"[1e-9999]",
+			"invalid index at offset 1: number is out of range",
+		},
+		{
+			`[{"value":[1e9999],"type":["list","number"]}]`,
+			"invalid index at offset 1: number is out of range",
+		},
+		{
+			"[[1]]",
+			"invalid index at offset 1: a key that is not a string, number or bool must have its type given",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			_, err := ParsePath(tt.s)
+			if err == nil {
+				t.Fatal("ParsePath() succeeded; want error")
+			}
+			if got := err.Error(); got != tt.want {
+				t.Errorf("wrong error\ngot:  %s\nwant: %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// pathsEqual returns true if the given paths have the same steps, with
+// index keys compared using RawEquals.
+func pathsEqual(a, b cty.Path) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !PathPatternFromPath(a[i : i+1]).Match(b[i : i+1]) {
+			return false
+		}
+	}
+	return true
+}
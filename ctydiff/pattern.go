@@ -0,0 +1,174 @@
+package ctydiff
+
+import (
+	"github.com/zclconf/go-cty/cty"
+)
+
+// PathPattern is a sequence of steps that can match a cty.Path, where each
+// step is either an exact path step or a wildcard.
+//
+// A pattern matches a path if each of its steps matches the corresponding
+// step of the path. Patterns are anchored at the root of a value, and a
+// pattern that matches a prefix of a path also matches the path itself, so
+// the pattern for an attribute also matches everything nested inside it.
+//
+// PathPattern has some convenience methods for gradually constructing a
+// pattern in the same way as for cty.Path, but callers can also construct
+// a []PatternStep directly.
+type PathPattern []PatternStep
+
+// PatternStep represents a single step in a PathPattern. PatternStep is a
+// closed interface, meaning that the only permitted implementations are
+// those within this package.
+type PatternStep interface {
+	patternStepSigil() patternStepImpl
+	matchStep(step cty.PathStep) bool
+}
+
+// Embed patternStepImpl into a struct to make it a PatternStep implementation
+type patternStepImpl struct{}
+
+func (s patternStepImpl) patternStepSigil() patternStepImpl {
+	return s
+}
+
+// ExactStep is a PatternStep that matches only a path step equal to the
+// given step.
+type ExactStep struct {
+	patternStepImpl
+	Step cty.PathStep
+}
+
+func (s ExactStep) matchStep(step cty.PathStep) bool {
+	switch want := s.Step.(type) {
+	case cty.GetAttrStep:
+		got, ok := step.(cty.GetAttrStep)
+		return ok && got.Name == want.Name
+	case cty.IndexStep:
+		got, ok := step.(cty.IndexStep)
+		return ok && rawEquals(got.Key, want.Key)
+	}
+	return false
+}
+
+// AnyAttrStep is a PatternStep that matches any attribute of an object.
+type AnyAttrStep struct {
+	patternStepImpl
+}
+
+func (s AnyAttrStep) matchStep(step cty.PathStep) bool {
+	_, ok := step.(cty.GetAttrStep)
+	return ok
+}
+
+// AnyIndexStep is a PatternStep that matches any index of a list or tuple
+// and any key of a map; that is, any IndexStep whose key is a number or a
+// string.
+type AnyIndexStep struct {
+	patternStepImpl
+}
+
+func (s AnyIndexStep) matchStep(step cty.PathStep) bool {
+	index, ok := step.(cty.IndexStep)
+	if !ok || index.Key == cty.NilVal {
+		return false
+	}
+	ty := index.Key.Type()
+	return ty.Equals(cty.Number) || ty.Equals(cty.String)
+}
+
+// AnySetMemberStep is a PatternStep that matches any element of a set.
+//
+// Since set elements are addressed by an IndexStep whose key is the element
+// itself, and so can be of any type, AnySetMemberStep matches any IndexStep.
+type AnySetMemberStep struct {
+	patternStepImpl
+}
+
+func (s AnySetMemberStep) matchStep(step cty.PathStep) bool {
+	_, ok := step.(cty.IndexStep)
+	return ok
+}
+
+// PathPatternFromPath returns a pattern that matches exactly the given path
+// and anything nested inside it.
+func PathPatternFromPath(path cty.Path) PathPattern {
+	ret := make(PathPattern, len(path))
+	for i, step := range path {
+		ret[i] = ExactStep{Step: step}
+	}
+	return ret
+}
+
+// GetAttrPattern is a convenience function to start a new PathPattern with
+// an exact attribute step.
+func GetAttrPattern(name string) PathPattern {
+	return PathPattern{}.GetAttr(name)
+}
+
+// IndexPattern is a convenience function to start a new PathPattern with
+// an exact index step.
+func IndexPattern(key cty.Value) PathPattern {
+	return PathPattern{}.Index(key)
+}
+
+// GetAttr returns a new PathPattern that is the receiver with an exact
+// attribute step appended to the end.
+func (p PathPattern) GetAttr(name string) PathPattern {
+	return p.append(ExactStep{Step: cty.GetAttrStep{Name: name}})
+}
+
+// Index returns a new PathPattern that is the receiver with an exact index
+// step appended to the end.
+func (p PathPattern) Index(key cty.Value) PathPattern {
+	return p.append(ExactStep{Step: cty.IndexStep{Key: key}})
+}
+
+// AnyAttr returns a new PathPattern that is the receiver with an
+// AnyAttrStep appended to the end.
+func (p PathPattern) AnyAttr() PathPattern {
+	return p.append(AnyAttrStep{})
+}
+
+// AnyIndex returns a new PathPattern that is the receiver with an
+// AnyIndexStep appended to the end.
+func (p PathPattern) AnyIndex() PathPattern {
+	return p.append(AnyIndexStep{})
+}
+
+// AnySetMember returns a new PathPattern that is the receiver with an
+// AnySetMemberStep appended to the end.
+func (p PathPattern) AnySetMember() PathPattern {
+	return p.append(AnySetMemberStep{})
+}
+
+// Match returns true if the receiver matches the given path or any of the
+// path's ancestors.
+func (p PathPattern) Match(path cty.Path) bool {
+	if len(p) > len(path) {
+		return false
+	}
+	for i, step := range p {
+		if step == nil || !step.matchStep(path[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p PathPattern) append(step PatternStep) PathPattern {
+	ret := make(PathPattern, len(p)+1)
+	copy(ret, p)
+	ret[len(p)] = step
+	return ret
+}
+
+// matchAny returns true if any of the given patterns match the given path.
+func matchAny(patterns []PathPattern, path cty.Path) bool {
+	for _, p := range patterns {
+		if p.Match(path) {
+			return true
+		}
+	}
+	return false
+}
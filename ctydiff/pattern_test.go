@@ -0,0 +1,46 @@
+package ctydiff
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestPathPattern_Match(t *testing.T) {
+	setElem := cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("A")})
+	tests := []struct {
+		pattern PathPattern
+		path    cty.Path
+		want    bool
+	}{
+		{PathPattern{}, cty.Path{}, true},
+		{PathPattern{}, cty.GetAttrPath("a"), true},
+		{GetAttrPattern("a"), cty.Path{}, false},
+		{GetAttrPattern("a"), cty.GetAttrPath("a"), true},
+		{GetAttrPattern("a"), cty.GetAttrPath("a").GetAttr("b"), true},
+		{GetAttrPattern("a"), cty.GetAttrPath("b"), false},
+		{GetAttrPattern("a"), cty.IndexPath(cty.StringVal("a")), false},
+		{IndexPattern(cty.StringVal("a")), cty.IndexPath(cty.StringVal("a")), true},
+		{IndexPattern(cty.NumberIntVal(1)), cty.IndexPath(cty.NumberIntVal(2)), false},
+		{PathPattern{}.AnyAttr(), cty.GetAttrPath("b"), true},
+		{PathPattern{}.AnyAttr(), cty.IndexPath(cty.StringVal("b")), false},
+		{PathPattern{}.AnyIndex().GetAttr("etag"), cty.IndexPath(cty.NumberIntVal(3)).GetAttr("etag"), true},
+		{PathPattern{}.AnyIndex().GetAttr("etag"), cty.IndexPath(cty.StringVal("k")).GetAttr("etag"), true},
+		{PathPattern{}.AnyIndex().GetAttr("etag"), cty.IndexPath(cty.NumberIntVal(3)).GetAttr("name"), false},
+		{PathPattern{}.AnyIndex(), cty.IndexPath(setElem), false},
+		{PathPattern{}.AnySetMember(), cty.IndexPath(setElem), true},
+		{PathPattern{}.AnySetMember(), cty.GetAttrPath("a"), false},
+		{PathPatternFromPath(cty.GetAttrPath("a").Index(setElem)), cty.GetAttrPath("a").Index(setElem), true},
+		{PathPattern{nil}, cty.GetAttrPath("a"), false},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%#v,%#v", test.pattern, test.path), func(t *testing.T) {
+			got := test.pattern.Match(test.path)
+			if got != test.want {
+				t.Errorf("wrong result %t; want %t", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package ctydiff
+
+import (
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Prefix returns a copy of the receiver with the given path prepended to the
+// path of each of its changes, so that a diff of a value can be applied to
+// a larger value that contains it at that path.
+//
+// The changes within a NestedDiff are relative to the NestedDiff itself,
+// so only the paths of the top-level changes are altered.
+func (d Diff) Prefix(path cty.Path) Diff {
+	var ret Diff
+	for _, c := range d {
+		if c == nil {
+			continue
+		}
+		ret = append(ret, c.withPath(joinPath(path, c.path())))
+	}
+	return ret
+}
+
+// Relative returns the changes in the receiver that affect the value at the
+// given path, with their paths made relative to it, so that a diff of a
+// larger value can be applied to the value at that path alone. It is the
+// inverse of Prefix.
+//
+// Changes outside of the value at the given path are discarded. A
+// NestedDiff whose path is an ancestor of the given path contributes the
+// changes in its nested diff that are within the value, preceded by a
+// Context change for the old value, and a ReplaceChange or Context change
+// for an ancestor is narrowed to the value.
+//
+// Relative returns an error if any change deletes, removes or moves the
+// value at the given path, or replaces an ancestor with a value that does
+// not contain it, since that cannot be represented by a diff of the value
+// alone.
+//
+// As for Filter, discarding changes to other elements of a list that
+// contains the value may cause the remaining changes to be applied in a
+// different position than intended, since changes are selected by their
+// paths alone.
+func (d Diff) Relative(prefix cty.Path) (Diff, error) {
+	return d.relative(nil, prefix)
+}
+
+// relative is the implementation of Relative for a diff whose changes are
+// relative to the given path, which is an ancestor of the given prefix.
+func (d Diff) relative(parent, prefix cty.Path) (Diff, error) {
+	var ret Diff
+	for _, c := range d {
+		if c == nil {
+			continue
+		}
+		path := c.path()
+		absPath := joinPath(parent, path)
+
+		if hasPathPrefix(path, prefix) {
+			if insert, ok := c.(InsertChange); ok && len(path) == len(prefix) && insert.ElementPath {
+				return nil, absPath.NewErrorf("change moves the value at the prefix")
+			}
+			if _, ok := c.(DeleteChange); ok && len(path) == len(prefix) {
+				return nil, absPath.NewErrorf("change deletes the value at the prefix")
+			}
+			ret = append(ret, c.withPath(path[len(prefix):].Copy()))
+			continue
+		}
+		if !hasPathPrefix(prefix, path) {
+			continue
+		}
+
+		// The change is to an ancestor of the value at the prefix, so we
+		// can keep only its effect on the value.
+		rest := prefix[len(path):]
+		switch c := c.(type) {
+		case NestedDiff:
+			if old, ok := subValue(c.OldValue, rest); ok {
+				ret = append(ret, Context{WantValue: old})
+			}
+			nested, err := c.Diff.relative(absPath, rest)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, nested...)
+		case Context:
+			if want, ok := subValue(c.WantValue, rest); ok {
+				ret = append(ret, Context{WantValue: want})
+			}
+		case ReplaceChange:
+			new, ok := subValue(c.NewValue, rest)
+			if !ok {
+				return nil, absPath.NewErrorf("change replaces the value at the prefix with one that does not contain it")
+			}
+			// The old value is left unchecked if it did not contain the
+			// value at the prefix.
+			old, ok := subValue(c.OldValue, rest)
+			if ok && rawEquals(old, new) {
+				ret = append(ret, Context{WantValue: old})
+			} else {
+				ret = append(ret, ReplaceChange{OldValue: old, NewValue: new})
+			}
+		case AddChange:
+			// Adding an element to a set does not affect the existing
+			// elements.
+		case RemoveChange:
+			if index, ok := rest[0].(cty.IndexStep); ok && rawEquals(index.Key, c.OldValue) {
+				return nil, absPath.NewErrorf("change removes the value at the prefix")
+			}
+		case DeleteChange:
+			return nil, absPath.NewErrorf("change deletes the value at the prefix")
+		case InsertChange:
+			return nil, absPath.NewErrorf("change moves the value at the prefix")
+		}
+	}
+	return ret, nil
+}
+
+// subValue returns the value at the given path within the given value, or
+// false if the value is cty.NilVal or has no value at the path.
+func subValue(val cty.Value, path cty.Path) (cty.Value, bool) {
+	if val == cty.NilVal {
+		return cty.NilVal, false
+	}
+	ret, err := applyPath(val, path)
+	if err != nil {
+		return cty.NilVal, false
+	}
+	return ret, true
+}
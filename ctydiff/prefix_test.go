@@ -0,0 +1,253 @@
+package ctydiff
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestDiffPrefix(t *testing.T) {
+	resource := func(name string, tags ...string) cty.Value {
+		vals := make([]cty.Value, len(tags))
+		for i, tag := range tags {
+			vals[i] = cty.StringVal(tag)
+		}
+		return cty.ObjectVal(map[string]cty.Value{
+			"name": cty.StringVal(name),
+			"tags": cty.ListVal(vals),
+		})
+	}
+	doc := func(web cty.Value) cty.Value {
+		return cty.ObjectVal(map[string]cty.Value{
+			"resources": cty.MapVal(map[string]cty.Value{
+				"db":  resource("db", "a"),
+				"web": web,
+			}),
+		})
+	}
+	prefix := cty.GetAttrPath("resources").Index(cty.StringVal("web"))
+
+	d := NewDiff(resource("web", "a", "b"), resource("www", "a", "c", "b"))
+	got, err := d.Prefix(prefix).Apply(doc(resource("web", "a", "b")))
+	if err != nil {
+		t.Fatalf("Apply() err = %v", err)
+	}
+	if want := doc(resource("www", "a", "c", "b")); !got.RawEquals(want) {
+		t.Errorf("Apply\nGot\n%#v\nWant\n%#v", got, want)
+	}
+
+	// The prefix applies only to the paths of the top-level changes.
+	nested := Diff{
+		NestedDiff{
+			Path:     cty.GetAttrPath("tags"),
+			OldValue: resource("", "a").GetAttr("tags"),
+			Diff: Diff{
+				ReplaceChange{
+					Path:     cty.IndexPath(cty.NumberIntVal(0)),
+					OldValue: cty.StringVal("a"),
+					NewValue: cty.StringVal("z"),
+				},
+			},
+		},
+	}
+	want := Diff{
+		NestedDiff{
+			Path:     prefix.GetAttr("tags"),
+			OldValue: nested[0].(NestedDiff).OldValue,
+			Diff:     nested[0].(NestedDiff).Diff,
+		},
+	}
+	if got := nested.Prefix(prefix); !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong result\n%s", prettyDiff.Compare(want, got))
+	}
+}
+
+func TestDiffRelative(t *testing.T) {
+	resource := func(name string, size int64) cty.Value {
+		return cty.ObjectVal(map[string]cty.Value{
+			"name": cty.StringVal(name),
+			"size": cty.NumberIntVal(size),
+		})
+	}
+	doc := func(title string, web, db cty.Value) cty.Value {
+		return cty.ObjectVal(map[string]cty.Value{
+			"title": cty.StringVal(title),
+			"resources": cty.ObjectVal(map[string]cty.Value{
+				"web": web,
+				"db":  db,
+			}),
+		})
+	}
+	prefix := cty.GetAttrPath("resources").GetAttr("web")
+
+	old := doc("a", resource("web", 1), resource("db", 1))
+	new := doc("b", resource("www", 2), resource("db", 3))
+	got, err := NewDiff(old, new).Relative(prefix)
+	if err != nil {
+		t.Fatalf("Relative() err = %v", err)
+	}
+	want := NewDiff(resource("web", 1), resource("www", 2))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong result\n%s", prettyDiff.Compare(want, got))
+	}
+
+	// Relative is the inverse of Prefix.
+	got, err = want.Prefix(prefix).Relative(prefix)
+	if err != nil {
+		t.Fatalf("Relative() err = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong result\n%s", prettyDiff.Compare(want, got))
+	}
+
+	// A replacement of an ancestor is narrowed to the value at the prefix.
+	replace := Diff{
+		ReplaceChange{
+			Path:     cty.GetAttrPath("resources"),
+			OldValue: old.GetAttr("resources"),
+			NewValue: new.GetAttr("resources"),
+		},
+	}
+	got, err = replace.Relative(prefix)
+	if err != nil {
+		t.Fatalf("Relative() err = %v", err)
+	}
+	want = Diff{
+		ReplaceChange{
+			OldValue: resource("web", 1),
+			NewValue: resource("www", 2),
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong result\n%s", prettyDiff.Compare(want, got))
+	}
+}
+
+func TestDiffRelative_nested(t *testing.T) {
+	member := func(id, value string) cty.Value {
+		return cty.ObjectVal(map[string]cty.Value{
+			"id": cty.StringVal(id),
+			"config": cty.ObjectVal(map[string]cty.Value{
+				"value": cty.StringVal(value),
+			}),
+		})
+	}
+	d := Diff{
+		NestedDiff{
+			Path:     cty.GetAttrPath("members").Index(member("1", "p")),
+			OldValue: member("1", "p"),
+			Diff: Diff{
+				ReplaceChange{
+					Path:     cty.GetAttrPath("config").GetAttr("value"),
+					OldValue: cty.StringVal("p"),
+					NewValue: cty.StringVal("q"),
+				},
+				ReplaceChange{
+					Path:     cty.GetAttrPath("id"),
+					OldValue: cty.StringVal("1"),
+					NewValue: cty.StringVal("2"),
+				},
+			},
+		},
+	}
+
+	got, err := d.Relative(cty.GetAttrPath("members").Index(member("1", "p")).GetAttr("config"))
+	if err != nil {
+		t.Fatalf("Relative() err = %v", err)
+	}
+	want := Diff{
+		Context{
+			WantValue: member("1", "p").GetAttr("config"),
+		},
+		ReplaceChange{
+			Path:     cty.GetAttrPath("value"),
+			OldValue: cty.StringVal("p"),
+			NewValue: cty.StringVal("q"),
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong result\n%s", prettyDiff.Compare(want, got))
+	}
+
+	// The nested diff is retained when the prefix is the NestedDiff's own
+	// path.
+	got, err = d.Relative(cty.GetAttrPath("members"))
+	if err != nil {
+		t.Fatalf("Relative() err = %v", err)
+	}
+	want = Diff{
+		NestedDiff{
+			Path:     cty.IndexPath(member("1", "p")),
+			OldValue: member("1", "p"),
+			Diff:     d[0].(NestedDiff).Diff,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong result\n%s", prettyDiff.Compare(want, got))
+	}
+}
+
+func TestDiffRelative_errors(t *testing.T) {
+	list := cty.GetAttrPath("list")
+	index := func(i int64) cty.Path {
+		return list.Index(cty.NumberIntVal(i))
+	}
+	set := cty.GetAttrPath("set")
+	obj := cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("x")})
+
+	tests := []struct {
+		name   string
+		diff   Diff
+		prefix cty.Path
+		want   string
+	}{
+		{
+			"Delete",
+			Diff{DeleteChange{Path: index(1), OldValue: obj}},
+			index(1).GetAttr("a"),
+			".list[1]: change deletes the value at the prefix",
+		},
+		{
+			"Insert",
+			Diff{InsertChange{Path: index(1), NewValue: obj, BeforeValue: obj, ElementPath: true}},
+			index(1),
+			".list[1]: change moves the value at the prefix",
+		},
+		{
+			"Remove",
+			Diff{RemoveChange{Path: set, OldValue: obj}},
+			set.Index(obj).GetAttr("a"),
+			".set: change removes the value at the prefix",
+		},
+		{
+			"ReplaceWithNull",
+			Diff{ReplaceChange{Path: list, OldValue: cty.ListVal([]cty.Value{obj}), NewValue: cty.NullVal(cty.List(obj.Type()))}},
+			index(0),
+			".list: change replaces the value at the prefix with one that does not contain it",
+		},
+		{
+			"Nested",
+			Diff{
+				NestedDiff{
+					Path: set.Index(obj),
+					Diff: Diff{DeleteChange{Path: cty.GetAttrPath("a")}},
+				},
+			},
+			set.Index(obj).GetAttr("a"),
+			`.set[{"value":{"a":"x"},"type":["object",{"a":"string"}]}].a: change deletes the value at the prefix`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.diff.Relative(tt.prefix)
+			if err == nil {
+				t.Fatalf("Relative() succeeded; want error")
+			}
+			if got := FormatPath(err.(cty.PathError).Path) + ": " + err.Error(); got != tt.want {
+				t.Errorf("wrong error\ngot:  %s\nwant: %s", got, tt.want)
+			}
+		})
+	}
+}
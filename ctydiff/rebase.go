@@ -0,0 +1,496 @@
+package ctydiff
+
+import (
+	"fmt"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// RebaseConflict describes a change that Rebase could not transform,
+// because the diff being rebased onto made an incompatible change.
+type RebaseConflict struct {
+	// Change is the change being rebased, and Onto is the change that it
+	// conflicts with. Both have absolute paths, as in the flattened forms
+	// of their diffs.
+	Change Change
+	Onto   Change
+}
+
+// RebaseError is the error returned by Rebase when the diffs have
+// conflicting changes.
+type RebaseError struct {
+	Conflicts []RebaseConflict
+}
+
+func (e *RebaseError) Error() string {
+	if len(e.Conflicts) == 1 {
+		return "rebase has 1 conflict"
+	}
+	return fmt.Sprintf("rebase has %d conflicts", len(e.Conflicts))
+}
+
+// Rebase transforms the diff d, which applies to the given base value, so
+// that it instead applies to the result of applying the diff onto to the
+// base value. This is the transform operation of operational
+// transformation, which allows concurrent edits to the same value to be
+// combined in either order.
+//
+// The indices of list elements in d are adjusted for the elements that
+// onto inserts and deletes. Elements that both diffs insert at the same
+// index are ordered by value, so that applying onto and then the result
+// gives the same value as applying d and then the result of rebasing onto
+// onto d. Changes in d that onto has already made, such as deleting the
+// same element, inserting the same element at the same index, or replacing
+// a value with the same new value, are dropped. The values of Context
+// changes and the BeforeValue of InsertChanges in the result are those
+// found in the rebased-onto value, and Context changes for values that
+// onto deleted are dropped.
+//
+// Any other pair of changes that affect the same value, or where one
+// affects a value nested inside the other's, is a conflict. If there are
+// conflicts then Rebase returns a *RebaseError describing them. Rebase also
+// returns an error if either diff does not apply to the base value.
+//
+// The result is flattened, as by Flatten. NestedDiff changes that remain
+// after flattening, which address set elements, are rebased recursively
+// when both diffs change the same element.
+func Rebase(d, onto Diff, base cty.Value) (Diff, error) {
+	ours, _, err := normalizeRebaseDiff(d, base)
+	if err != nil {
+		return nil, fmt.Errorf("diff does not apply to the base value: %s", err)
+	}
+	theirs, mid, err := normalizeRebaseDiff(onto, base)
+	if err != nil {
+		return nil, fmt.Errorf("diff to rebase onto does not apply to the base value: %s", err)
+	}
+
+	// Context changes in the diff we are rebasing onto don't change
+	// anything, and so have no effect on the changes being rebased.
+	var changes Diff
+	for _, c := range theirs {
+		if _, ok := c.(Context); !ok {
+			changes = append(changes, c)
+		}
+	}
+
+	rebased, conflicts := rebaseChanges(ours, changes)
+	if len(conflicts) > 0 {
+		return nil, &RebaseError{Conflicts: conflicts}
+	}
+	return refreshRebased(rebased, mid)
+}
+
+// normalizeRebaseDiff returns the flattened form of the given diff, with
+// any InsertChange whose ElementPath is false converted to the form whose
+// path is to the index of the new element, along with the result of applying
+// the diff to the given value.
+func normalizeRebaseDiff(d Diff, val cty.Value) (Diff, cty.Value, error) {
+	if _, err := d.Apply(val); err != nil {
+		return nil, cty.NilVal, err
+	}
+	var ret Diff
+	for _, c := range d.Flatten() {
+		if insert, ok := c.(InsertChange); ok && !insert.ElementPath {
+			c = normalizeInsert(insert, val)
+		}
+		v, err := c.apply(val)
+		if err != nil {
+			return nil, cty.NilVal, err
+		}
+		ret = append(ret, c)
+		val = v
+	}
+	return ret, val, nil
+}
+
+// normalizeInsert returns the given change, whose ElementPath is false,
+// with its path replaced by the path to the index of the new element.
+func normalizeInsert(c InsertChange, val cty.Value) Change {
+	list, err := applyPath(val, c.Path)
+	if err != nil || !isKnownSequence(list) {
+		return c
+	}
+	vals := list.AsValueSlice()
+	idx := len(vals)
+	if c.BeforeValue != cty.NilVal && len(vals) > 0 {
+		idx = -1
+		for i, v := range vals {
+			if v.RawEquals(c.BeforeValue) {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return c
+		}
+	}
+	c.Path = c.Path.Index(cty.NumberIntVal(int64(idx)))
+	c.ElementPath = true
+	return c
+}
+
+// rebaseChanges transforms each of the changes in ours, which are
+// flattened and normalized, past all of the changes in theirs.
+//
+// Each change in ours applies to the value produced by the changes before
+// it, so once it has been transformed past theirs, theirs is in turn
+// transformed past it in order to transform the next change.
+func rebaseChanges(ours, theirs Diff) (Diff, []RebaseConflict) {
+	orig := theirs
+	var ret Diff
+	var conflicts []RebaseConflict
+	for _, c := range ours {
+		a := c
+		next := make(Diff, 0, len(theirs))
+		nextOrig := make(Diff, 0, len(theirs))
+		var conflicted bool
+		for i, b := range theirs {
+			if a == nil {
+				next = append(next, theirs[i:]...)
+				nextOrig = append(nextOrig, orig[i:]...)
+				break
+			}
+			newA, newB, nested, ok := rebaseChange(a, b)
+			if !ok {
+				if nested == nil {
+					nested = []RebaseConflict{{Change: c, Onto: orig[i]}}
+				}
+				conflicts = append(conflicts, nested...)
+				conflicted = true
+				break
+			}
+			a = newA
+			if newB != nil {
+				next = append(next, newB)
+				nextOrig = append(nextOrig, orig[i])
+			}
+		}
+		if conflicted {
+			continue
+		}
+		if a != nil {
+			ret = append(ret, a)
+		}
+		theirs, orig = next, nextOrig
+	}
+	return ret, conflicts
+}
+
+// rebaseChange transforms the change a past the change b, both of which
+// apply to the same value, returning a version of a that applies after b
+// and a version of b that applies after a. Either result is nil if the
+// change has no effect after the other.
+//
+// If the changes conflict then rebaseChange returns false, along with any
+// conflicts found in rebasing the nested diffs of NestedDiff changes.
+func rebaseChange(a, b Change) (Change, Change, []RebaseConflict, bool) {
+	aList, aIdx, aIsList := listElementChange(a)
+	bList, bIdx, bIsList := listElementChange(b)
+	_, aInsert := a.(InsertChange)
+	_, bInsert := b.(InsertChange)
+
+	// First we move a to account for an element that b inserts into or
+	// deletes from a list that a refers to. If a refers to an element after
+	// the one that b inserts or deletes then the two changes cannot
+	// overlap, and since newA's path is then relative to the list after b
+	// it must not be compared with b's.
+	newA := a
+	overlaps := true
+	if i, ok := indexUnder(a.path(), bList); ok && bIsList {
+		isElement := aIsList && len(a.path()) == len(bList)+1
+		switch {
+		case bInsert && i == bIdx && isElement && aInsert && sameInsert(a, b):
+			// Both diffs insert the same element at the same index.
+			return nil, nil, nil, true
+		case bInsert && i == bIdx && isElement && aInsert && insertsFirst(a, b):
+			// Both diffs insert at the same index, and a's element goes
+			// first, so b is moved below instead.
+			overlaps = false
+		case bInsert && i >= bIdx:
+			newA = withIndex(a, len(bList), i+1)
+			overlaps = false
+		case !bInsert && i > bIdx:
+			newA = withIndex(a, len(bList), i-1)
+			overlaps = false
+		case !bInsert && i == bIdx:
+			switch {
+			case isElement && aInsert:
+				// Inserting before the deleted element is the same as
+				// inserting before the one that follows it.
+				overlaps = false
+			case isElement:
+				// Both diffs delete the same element.
+				return nil, nil, nil, true
+			default:
+				if _, ok := a.(Context); ok {
+					return nil, b, nil, true
+				}
+				return nil, nil, nil, false
+			}
+		}
+	}
+
+	if _, ok := a.(Context); !ok && overlaps {
+		pa, pb := newA.path(), b.path()
+		switch {
+		case len(pa) == len(pb) && hasPathPrefix(pa, pb):
+			return rebaseSamePath(newA, b)
+		case hasPathPrefix(pa, pb):
+			if !containsCompatible(b, pa) {
+				return nil, nil, nil, false
+			}
+		case hasPathPrefix(pb, pa):
+			if !containsCompatible(newA, pb) {
+				return nil, nil, nil, false
+			}
+		}
+	}
+
+	// Finally we move b to account for an element that a inserts or
+	// deletes, in the same way as above.
+	newB := b
+	if j, ok := indexUnder(b.path(), aList); ok && aIsList {
+		isElement := bIsList && len(b.path()) == len(aList)+1
+		switch {
+		case aInsert && (j > aIdx || j == aIdx && (!(isElement && bInsert) || insertsFirst(a, b))):
+			newB = withIndex(b, len(aList), j+1)
+		case !aInsert && j > aIdx:
+			newB = withIndex(b, len(aList), j-1)
+		}
+	}
+	return newA, newB, nil, true
+}
+
+// rebaseSamePath is the part of rebaseChange that handles changes with the
+// same path.
+func rebaseSamePath(a, b Change) (Change, Change, []RebaseConflict, bool) {
+	switch a := a.(type) {
+	case ReplaceChange:
+		if b, ok := b.(ReplaceChange); ok && rawEquals(a.NewValue, b.NewValue) {
+			return nil, nil, nil, true
+		}
+	case DeleteChange:
+		if _, ok := b.(DeleteChange); ok {
+			return nil, nil, nil, true
+		}
+	case InsertChange:
+		// Inserting before an element that b changed, which b now
+		// applies to at the next index.
+		if _, idx, ok := listElementChange(a); ok {
+			return a, withIndex(b, len(a.Path)-1, idx+1), nil, true
+		}
+	case AddChange:
+		switch b := b.(type) {
+		case AddChange:
+			if rawEquals(a.NewValue, b.NewValue) {
+				return nil, nil, nil, true
+			}
+			return a, b, nil, true
+		case RemoveChange:
+			return a, b, nil, !rawEquals(a.NewValue, b.OldValue)
+		}
+	case RemoveChange:
+		switch b := b.(type) {
+		case RemoveChange:
+			if rawEquals(a.OldValue, b.OldValue) {
+				return nil, nil, nil, true
+			}
+			return a, b, nil, true
+		case AddChange:
+			return a, b, nil, !rawEquals(a.OldValue, b.NewValue)
+		}
+	case NestedDiff:
+		if b, ok := b.(NestedDiff); ok {
+			return rebaseNested(a, b)
+		}
+	}
+	return nil, nil, nil, false
+}
+
+// rebaseNested rebases one NestedDiff onto another with the same path.
+func rebaseNested(a, b NestedDiff) (Change, Change, []RebaseConflict, bool) {
+	diff, err := Rebase(a.Diff, b.Diff, a.OldValue)
+	if err != nil {
+		rebaseErr, ok := err.(*RebaseError)
+		if !ok {
+			return nil, nil, nil, false
+		}
+		conflicts := make([]RebaseConflict, len(rebaseErr.Conflicts))
+		for i, conflict := range rebaseErr.Conflicts {
+			conflicts[i] = RebaseConflict{
+				Change: conflict.Change.withPath(joinPath(a.Path, conflict.Change.path())),
+				Onto:   conflict.Onto.withPath(joinPath(b.Path, conflict.Onto.path())),
+			}
+		}
+		return nil, nil, conflicts, false
+	}
+	// Both nested diffs applied to their old value in order for Rebase to
+	// get this far, so we can disregard errors here.
+	aNew, _ := a.Diff.Apply(a.OldValue)
+	bNew, _ := b.Diff.Apply(b.OldValue)
+	bDiff, err := Rebase(b.Diff, a.Diff, b.OldValue)
+	if err != nil {
+		bDiff = b.Diff
+	}
+
+	var newA, newB Change
+	if len(diff) > 0 {
+		newA = NestedDiff{
+			Path:     rekeyElementPath(a.Path, a.OldValue, bNew),
+			OldValue: bNew,
+			Diff:     diff,
+		}
+	}
+	if len(bDiff) > 0 {
+		newB = NestedDiff{
+			Path:     rekeyElementPath(b.Path, b.OldValue, aNew),
+			OldValue: aNew,
+			Diff:     bDiff,
+		}
+	}
+	return newA, newB, nil, true
+}
+
+// sameInsert returns true if the given changes are both InsertChanges that
+// insert the same value.
+func sameInsert(a, b Change) bool {
+	aInsert, aOk := a.(InsertChange)
+	bInsert, bOk := b.(InsertChange)
+	return aOk && bOk && rawEquals(aInsert.NewValue, bInsert.NewValue)
+}
+
+// insertsFirst returns true if the given changes are both InsertChanges and
+// the element that a inserts is to be placed before the one that b inserts
+// when they insert at the same index. The order is that of the GoString
+// representations of the new values, which is arbitrary but does not
+// depend on which of the changes is being rebased, so that rebasing each of
+// two diffs onto the other gives the same result.
+func insertsFirst(a, b Change) bool {
+	aInsert, aOk := a.(InsertChange)
+	bInsert, bOk := b.(InsertChange)
+	return aOk && bOk && aInsert.NewValue.GoString() < bInsert.NewValue.GoString()
+}
+
+// containsCompatible returns true if the given change, whose path is an
+// ancestor of the given path, leaves the value at that path in place.
+func containsCompatible(c Change, path cty.Path) bool {
+	switch c := c.(type) {
+	case AddChange, InsertChange, Context:
+		return true
+	case RemoveChange:
+		index, ok := path[len(c.Path)].(cty.IndexStep)
+		return !ok || !rawEquals(index.Key, c.OldValue)
+	}
+	return false
+}
+
+// refreshRebased applies the given rebased diff to the given value, which
+// is the value it is to apply to, replacing the values recorded in its
+// Context changes and the BeforeValue of its InsertChanges with those found
+// in the value. Context changes whose paths no longer exist are dropped.
+func refreshRebased(d Diff, val cty.Value) (Diff, error) {
+	var ret Diff
+	for _, c := range d {
+		switch tc := c.(type) {
+		case Context:
+			existing, err := applyPath(val, tc.Path)
+			if err != nil {
+				continue
+			}
+			tc.WantValue = existing
+			c = tc
+		case InsertChange:
+			if tc.BeforeValue == cty.NilVal {
+				break
+			}
+			list, err := applyPath(val, tc.Path[:len(tc.Path)-1])
+			_, idx, ok := listElementChange(tc)
+			if err != nil || !ok || !isKnownSequence(list) {
+				break
+			}
+			switch vals := list.AsValueSlice(); {
+			case idx < len(vals):
+				tc.BeforeValue = vals[idx]
+			case !tc.BeforeValue.IsNull():
+				ety := cty.DynamicPseudoType
+				if list.Type().IsListType() {
+					ety = list.Type().ElementType()
+				}
+				tc.BeforeValue = cty.NullVal(ety)
+			}
+			c = tc
+		}
+		v, err := c.apply(val)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, c)
+		val = v
+	}
+	return ret, nil
+}
+
+// listElementChange returns the path of the list and the index of the
+// element that the given change inserts or deletes, if it is an
+// InsertChange or DeleteChange whose path is to a list element.
+func listElementChange(c Change) (cty.Path, int, bool) {
+	switch c := c.(type) {
+	case InsertChange:
+		if !c.ElementPath {
+			return nil, 0, false
+		}
+	case DeleteChange:
+	default:
+		return nil, 0, false
+	}
+	path := c.path()
+	if len(path) == 0 {
+		return nil, 0, false
+	}
+	// Map keys are strings, and DeleteChange doesn't apply to sets, so a
+	// numeric key must be a list index.
+	idx, ok := stepIndex(path[len(path)-1])
+	if !ok {
+		return nil, 0, false
+	}
+	return path[:len(path)-1], idx, true
+}
+
+// indexUnder returns the index of the element of the list at the given
+// list path that the given path refers to or is nested within, if any.
+func indexUnder(path, list cty.Path) (int, bool) {
+	if len(path) <= len(list) || !hasPathPrefix(path, list) {
+		return 0, false
+	}
+	return stepIndex(path[len(list)])
+}
+
+// withIndex returns a copy of the given change with the step at the given
+// position in its path replaced by the given list index.
+func withIndex(c Change, pos, idx int) Change {
+	path := c.path().Copy()
+	path[pos] = cty.IndexStep{Key: cty.NumberIntVal(int64(idx))}
+	return c.withPath(path)
+}
+
+// rekeyElementPath returns the given path with its final step replaced by
+// one for the given new value, if it is a step that selects a set element
+// by the given old value.
+func rekeyElementPath(path cty.Path, old, new cty.Value) cty.Path {
+	if len(path) == 0 {
+		return path
+	}
+	index, ok := path[len(path)-1].(cty.IndexStep)
+	if !ok || !rawEquals(index.Key, old) {
+		return path
+	}
+	ret := path.Copy()
+	ret[len(ret)-1] = cty.IndexStep{Key: new}
+	return ret
+}
+
+// hasPathPrefix returns true if the given prefix is equal to the given
+// path or to one of its ancestors.
+func hasPathPrefix(path, prefix cty.Path) bool {
+	return PathPatternFromPath(prefix).Match(path)
+}
@@ -0,0 +1,369 @@
+package ctydiff
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestRebase(t *testing.T) {
+	strs := func(ss ...string) cty.Value {
+		vals := make([]cty.Value, len(ss))
+		for i, s := range ss {
+			vals[i] = cty.StringVal(s)
+		}
+		return cty.ListVal(vals)
+	}
+	member := func(id, value string) cty.Value {
+		return cty.ObjectVal(map[string]cty.Value{
+			"id":    cty.StringVal(id),
+			"value": cty.StringVal(value),
+			"tags":  cty.SetVal([]cty.Value{cty.StringVal(id)}),
+		})
+	}
+	doc := func(name string, list, set cty.Value) cty.Value {
+		return cty.ObjectVal(map[string]cty.Value{
+			"name": cty.StringVal(name),
+			"list": list,
+			"set":  set,
+		})
+	}
+
+	tests := []struct {
+		name   string
+		base   cty.Value
+		ours   cty.Value
+		theirs cty.Value
+		want   cty.Value
+	}{
+		{
+			"Independent",
+			doc("a", strs("x"), cty.SetVal([]cty.Value{member("1", "p")})),
+			doc("b", strs("x"), cty.SetVal([]cty.Value{member("1", "p")})),
+			doc("a", strs("x", "y"), cty.SetVal([]cty.Value{member("1", "p"), member("2", "q")})),
+			doc("b", strs("x", "y"), cty.SetVal([]cty.Value{member("1", "p"), member("2", "q")})),
+		},
+		{
+			"SameChange",
+			doc("a", strs("x", "y"), cty.SetValEmpty(member("", "").Type())),
+			doc("b", strs("y"), cty.SetValEmpty(member("", "").Type())),
+			doc("b", strs("y"), cty.SetValEmpty(member("", "").Type())),
+			doc("b", strs("y"), cty.SetValEmpty(member("", "").Type())),
+		},
+		{
+			"InsertSameIndex",
+			strs("a", "b"),
+			strs("x", "a", "b"),
+			strs("y", "a", "b"),
+			strs("x", "y", "a", "b"),
+		},
+		{
+			"SameInsert",
+			strs("a", "b"),
+			strs("a", "x", "b"),
+			strs("a", "x", "b"),
+			strs("a", "x", "b"),
+		},
+		{
+			"InsertAndDelete",
+			strs("a", "b", "c", "d"),
+			strs("a", "x", "b", "c", "d", "z"),
+			strs("b", "d"),
+			strs("x", "b", "d", "z"),
+		},
+		{
+			"ReplaceAfterDelete",
+			strs("a", "b", "c"),
+			strs("a", "b", "x"),
+			strs("b", "c"),
+			strs("b", "x"),
+		},
+		{
+			"DeleteAfterDelete",
+			strs("a", "b"),
+			strs("a"),
+			strs("b"),
+			cty.ListValEmpty(cty.String),
+		},
+		{
+			"ReplaceAfterDeleteFirst",
+			strs("a", "b"),
+			strs("a", "B"),
+			strs("b"),
+			strs("B"),
+		},
+		{
+			"ReplaceAfterInsert",
+			cty.ListVal([]cty.Value{member("1", "p"), member("2", "q")}),
+			cty.ListVal([]cty.Value{member("1", "p"), member("2", "x")}),
+			cty.ListVal([]cty.Value{member("0", "o"), member("1", "p"), member("2", "q")}),
+			cty.ListVal([]cty.Value{member("0", "o"), member("1", "p"), member("2", "x")}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDiff(tt.base, tt.ours)
+			onto := NewDiff(tt.base, tt.theirs)
+			rebased, err := Rebase(d, onto, tt.base)
+			if err != nil {
+				t.Fatalf("Rebase() err = %v", err)
+			}
+			got, err := rebased.Apply(tt.theirs)
+			if err != nil {
+				t.Fatalf("Apply() err = %v\n%s", err, prettyDiff.Sprint(rebased))
+			}
+			if !got.RawEquals(tt.want) {
+				t.Errorf("Apply\nGot\n%#v\nWant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRebase_converge(t *testing.T) {
+	strs := func(ss ...string) cty.Value {
+		if len(ss) == 0 {
+			return cty.ListValEmpty(cty.String)
+		}
+		vals := make([]cty.Value, len(ss))
+		for i, s := range ss {
+			vals[i] = cty.StringVal(s)
+		}
+		return cty.ListVal(vals)
+	}
+
+	tests := []struct {
+		name   string
+		base   cty.Value
+		ours   cty.Value
+		theirs cty.Value
+		want   cty.Value
+	}{
+		{
+			"InsertSameIndex",
+			strs("a", "b"),
+			strs("a", "y", "b"),
+			strs("a", "x", "b"),
+			strs("a", "x", "y", "b"),
+		},
+		{
+			"InsertRunsSameIndex",
+			strs("a"),
+			strs("x", "z", "a"),
+			strs("y", "a"),
+			strs("x", "y", "z", "a"),
+		},
+		{
+			"SameInsert",
+			strs("a"),
+			strs("x", "a"),
+			strs("x", "a"),
+			strs("x", "a"),
+		},
+		{
+			"OverlappingDeletes",
+			strs("a", "b", "c", "d"),
+			strs("a", "d"),
+			strs("a", "b"),
+			strs("a"),
+		},
+		{
+			"DeleteAfterDelete",
+			strs("a", "b"),
+			strs("a"),
+			strs("b"),
+			strs(),
+		},
+		{
+			"InsertAndDelete",
+			strs("a", "b", "c"),
+			strs("a", "x", "b", "c"),
+			strs("a", "c"),
+			strs("a", "x", "c"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDiff(tt.base, tt.ours)
+			onto := NewDiff(tt.base, tt.theirs)
+
+			for _, order := range []struct {
+				name      string
+				d, onto   Diff
+				afterOnto cty.Value
+			}{
+				{"ours", d, onto, tt.theirs},
+				{"theirs", onto, d, tt.ours},
+			} {
+				rebased, err := Rebase(order.d, order.onto, tt.base)
+				if err != nil {
+					t.Fatalf("Rebase() of %s err = %v", order.name, err)
+				}
+				got, err := rebased.Apply(order.afterOnto)
+				if err != nil {
+					t.Fatalf("Apply() of %s err = %v\n%s", order.name, err, prettyDiff.Sprint(rebased))
+				}
+				if !got.RawEquals(tt.want) {
+					t.Errorf("Apply of %s\nGot\n%#v\nWant\n%#v", order.name, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRebase_result(t *testing.T) {
+	index := func(i int) cty.Path {
+		return cty.IndexPath(cty.NumberIntVal(int64(i)))
+	}
+	base := cty.ListVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b"), cty.StringVal("c")})
+	d := Diff{
+		Context{Path: index(0), WantValue: cty.StringVal("a")},
+		InsertChange{Path: index(1), NewValue: cty.StringVal("x"), BeforeValue: cty.StringVal("b"), ElementPath: true},
+		Context{Path: index(2), WantValue: cty.StringVal("b")},
+		Context{Path: index(3), WantValue: cty.StringVal("c")},
+	}
+	onto := Diff{
+		DeleteChange{Path: index(1), OldValue: cty.StringVal("b")},
+	}
+
+	got, err := Rebase(d, onto, base)
+	if err != nil {
+		t.Fatalf("Rebase() err = %v", err)
+	}
+	want := Diff{
+		Context{Path: index(0), WantValue: cty.StringVal("a")},
+		InsertChange{Path: index(1), NewValue: cty.StringVal("x"), BeforeValue: cty.StringVal("c"), ElementPath: true},
+		Context{Path: index(2), WantValue: cty.StringVal("c")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong result\n%s", prettyDiff.Compare(want, got))
+	}
+}
+
+func TestRebase_conflicts(t *testing.T) {
+	obj := func(name string, list cty.Value) cty.Value {
+		return cty.ObjectVal(map[string]cty.Value{
+			"name": cty.StringVal(name),
+			"list": list,
+		})
+	}
+	item := func(v string) cty.Value {
+		return cty.ObjectVal(map[string]cty.Value{"v": cty.StringVal(v)})
+	}
+	base := obj("a", cty.ListVal([]cty.Value{item("x"), item("y")}))
+	ours := obj("b", cty.ListVal([]cty.Value{item("x"), item("z")}))
+	theirs := obj("c", cty.ListVal([]cty.Value{item("x")}))
+
+	_, err := Rebase(NewDiff(base, ours), NewDiff(base, theirs), base)
+	rebaseErr, ok := err.(*RebaseError)
+	if !ok {
+		t.Fatalf("Rebase() err = %#v; want *RebaseError", err)
+	}
+	if got, want := rebaseErr.Error(), "rebase has 2 conflicts"; got != want {
+		t.Errorf("wrong error %q; want %q", got, want)
+	}
+	var got []string
+	for _, conflict := range rebaseErr.Conflicts {
+		got = append(got, FormatPath(conflict.Change.path())+" "+FormatPath(conflict.Onto.path()))
+	}
+	want := []string{
+		".list[1].v .list[1]",
+		".name .name",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong conflicts\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestRebase_notApplicable(t *testing.T) {
+	base := cty.StringVal("a")
+	d := Diff{ReplaceChange{OldValue: cty.StringVal("b"), NewValue: cty.StringVal("c")}}
+
+	_, err := Rebase(d, nil, base)
+	if got, want := err.Error(), "diff does not apply to the base value: existing value does not match"; got != want {
+		t.Errorf("wrong error\ngot:  %s\nwant: %s", got, want)
+	}
+	_, err = Rebase(nil, d, base)
+	if got, want := err.Error(), "diff to rebase onto does not apply to the base value: existing value does not match"; got != want {
+		t.Errorf("wrong error\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+func TestRebase_nested(t *testing.T) {
+	member := func(value string, tags ...string) cty.Value {
+		vals := make([]cty.Value, len(tags))
+		for i, tag := range tags {
+			vals[i] = cty.StringVal(tag)
+		}
+		return cty.ObjectVal(map[string]cty.Value{
+			"value": cty.StringVal(value),
+			"tags":  cty.SetVal(vals),
+		})
+	}
+	base := cty.SetVal([]cty.Value{member("p", "a")})
+	d := Diff{
+		NestedDiff{
+			Path:     cty.IndexPath(member("p", "a")),
+			OldValue: member("p", "a"),
+			Diff: Diff{
+				ReplaceChange{
+					Path:     cty.GetAttrPath("value"),
+					OldValue: cty.StringVal("p"),
+					NewValue: cty.StringVal("q"),
+				},
+			},
+		},
+	}
+	onto := Diff{
+		NestedDiff{
+			Path:     cty.IndexPath(member("p", "a")),
+			OldValue: member("p", "a"),
+			Diff: Diff{
+				AddChange{
+					Path:     cty.GetAttrPath("tags"),
+					NewValue: cty.StringVal("b"),
+				},
+			},
+		},
+	}
+
+	got, err := Rebase(d, onto, base)
+	if err != nil {
+		t.Fatalf("Rebase() err = %v", err)
+	}
+	want := Diff{
+		NestedDiff{
+			Path:     cty.IndexPath(member("p", "a", "b")),
+			OldValue: member("p", "a", "b"),
+			Diff: Diff{
+				ReplaceChange{
+					Path:     cty.GetAttrPath("value"),
+					OldValue: cty.StringVal("p"),
+					NewValue: cty.StringVal("q"),
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong result\n%s", prettyDiff.Compare(want, got))
+	}
+
+	onto[0].(NestedDiff).Diff[0] = ReplaceChange{
+		Path:     cty.GetAttrPath("value"),
+		OldValue: cty.StringVal("p"),
+		NewValue: cty.StringVal("r"),
+	}
+	_, err = Rebase(d, onto, base)
+	rebaseErr, ok := err.(*RebaseError)
+	if !ok {
+		t.Fatalf("Rebase() err = %#v; want *RebaseError", err)
+	}
+	if got, want := len(rebaseErr.Conflicts), 1; got != want {
+		t.Fatalf("got %d conflicts; want %d", got, want)
+	}
+	if got, want := FormatPath(rebaseErr.Conflicts[0].Change.path()), FormatPath(cty.IndexPath(member("p", "a")).GetAttr("value")); got != want {
+		t.Errorf("conflict has path %s; want %s", got, want)
+	}
+}
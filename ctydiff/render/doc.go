@@ -0,0 +1,14 @@
+// Package render contains a framework for presenting a ctydiff.Diff to a
+// user.
+//
+// Most languages built on cty will want to present diffs using their own
+// familiar syntax, but the work of arranging the changes in a diff into a
+// readable nested structure is the same regardless of syntax. This package
+// takes care of that part, grouping changes by their common path prefixes
+// and managing indentation, and calls a language-specific Renderer to
+// produce the text for each value, path, change and group.
+//
+// The subpackages "plan" and "hcl" contain ready-made Renderers that present
+// diffs in the style of a Terraform plan and in HCL native syntax
+// respectively.
+package render
@@ -0,0 +1,38 @@
+// Package hcl renders a ctydiff.Diff using the native syntax of HCL, so that
+// the diff resembles the configuration that a user would have written.
+//
+// Changed attributes are shown as "name = old -> new", with a leading "+",
+// "-" or "~" marker for additions, removals and updates respectively.
+// Object values, and lists and sets of objects, are presented as nested
+// blocks, while other values use the HCL expression syntax chosen by their
+// cty type: lists and sets as tuple expressions, maps as object expressions
+// and multi-line strings as heredocs.
+//
+// Since cty values carry no record of whether an object was written as a
+// block or as an attribute, objects are always presented as blocks.
+package hcl
+
+import (
+	"bytes"
+
+	"github.com/zclconf/go-cty-diff/ctydiff"
+	"github.com/zclconf/go-cty-diff/ctydiff/render"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Render returns the HCL presentation of the given diff, which applies to
+// the given source value.
+//
+// The source value provides the type information used to choose between
+// blocks, lists and maps for nested changes. It may be cty.NilVal if it is
+// not available, in which case nested changes are presented as lists or
+// maps based only on the kinds of their paths.
+func Render(d ctydiff.Diff, source cty.Value) string {
+	var buf bytes.Buffer
+	p := &render.Printer{
+		Renderer: &Renderer{},
+		Indent:   "    ",
+	}
+	p.Print(&buf, d, source) // writing to a bytes.Buffer cannot fail
+	return buf.String()
+}
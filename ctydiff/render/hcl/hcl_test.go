@@ -0,0 +1,175 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty-diff/ctydiff"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestRender(t *testing.T) {
+	rule := func(action string, port int64) cty.Value {
+		return cty.ObjectVal(map[string]cty.Value{
+			"action": cty.StringVal(action),
+			"port":   cty.NumberIntVal(port),
+		})
+	}
+	source := cty.ObjectVal(map[string]cty.Value{
+		"name":  cty.StringVal("a"),
+		"ports": cty.ListVal([]cty.Value{cty.NumberIntVal(80), cty.NumberIntVal(443)}),
+		"tags": cty.MapVal(map[string]cty.Value{
+			"env": cty.StringVal("dev"),
+		}),
+		"network": cty.ObjectVal(map[string]cty.Value{
+			"cidr": cty.StringVal("10.0.0.0/16"),
+		}),
+		"rule":   cty.ListVal([]cty.Value{rule("allow", 80), rule("deny", 22)}),
+		"script": cty.StringVal("echo hello\n"),
+	})
+	target := cty.ObjectVal(map[string]cty.Value{
+		"name":  cty.StringVal("b"),
+		"ports": cty.ListVal([]cty.Value{cty.NumberIntVal(80), cty.NumberIntVal(8443)}),
+		"tags": cty.MapVal(map[string]cty.Value{
+			"env":  cty.StringVal("dev"),
+			"team": cty.StringVal("core"),
+		}),
+		"network": cty.ObjectVal(map[string]cty.Value{
+			"cidr": cty.StringVal("10.1.0.0/16"),
+		}),
+		"rule":   cty.ListVal([]cty.Value{rule("allow", 8080), rule("deny", 22), rule("log", 0)}),
+		"script": cty.StringVal("set -e\necho hello\n"),
+	})
+
+	got := Render(ctydiff.NewDiff(source, target), source)
+	want := `~ name = "a" -> "b"
+~ network {
+    ~ cidr = "10.0.0.0/16" -> "10.1.0.0/16"
+  }
+~ ports = [
+    # (1 unchanged element hidden)
+    ~ 443 -> 8443,
+  ]
+~ rule {
+    ~ port = 80 -> 8080
+  }
+# (1 unchanged block hidden)
++ rule {
+    action = "log"
+    port   = 0
+  }
+~ script = "echo hello\n" -> <<-EOT
+    set -e
+    echo hello
+  EOT
+~ tags = {
+    + "team" = "core"
+  }
+`
+	if got != want {
+		t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRender_noSource(t *testing.T) {
+	diff := ctydiff.Diff{
+		ctydiff.InsertChange{
+			Path:        cty.GetAttrPath("list").Index(cty.NumberIntVal(0)),
+			NewValue:    cty.StringVal("a"),
+			BeforeValue: cty.NullVal(cty.String),
+			ElementPath: true,
+		},
+		ctydiff.DeleteChange{
+			Path:     cty.GetAttrPath("map").Index(cty.StringVal("k")),
+			OldValue: cty.ListVal([]cty.Value{cty.True}),
+		},
+		ctydiff.AddChange{
+			Path:     cty.GetAttrPath("set"),
+			NewValue: cty.StringVal("s"),
+		},
+	}
+
+	got := Render(diff, cty.NilVal)
+	want := `~ list = [
+    + "a",
+  ]
+~ map = {
+    - "k" = [
+        true,
+      ]
+  }
+~ set = [
+    + "s",
+  ]
+`
+	if got != want {
+		t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRender_heredoc(t *testing.T) {
+	script := cty.StringVal("set -e\necho hello\n")
+	diff := ctydiff.Diff{
+		ctydiff.ReplaceChange{
+			Path:     cty.GetAttrPath("script"),
+			OldValue: script,
+			NewValue: cty.StringVal("echo hello"),
+		},
+		ctydiff.InsertChange{
+			Path:        cty.GetAttrPath("scripts").Index(cty.NumberIntVal(0)),
+			NewValue:    script,
+			BeforeValue: cty.NullVal(cty.String),
+			ElementPath: true,
+		},
+	}
+
+	got := Render(diff, cty.NilVal)
+	want := `~ script = <<-EOT
+    set -e
+    echo hello
+  EOT
+  -> "echo hello"
+~ scripts = [
+    + <<-EOT
+        set -e
+        echo hello
+      EOT
+      ,
+  ]
+`
+	if got != want {
+		t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		val  cty.Value
+		want string
+	}{
+		{cty.StringVal("a\"b"), `"a\"b"`},
+		{cty.StringVal("${a} %{b} $c"), `"$${a} %%{b} $c"`},
+		{cty.StringVal("a\nb"), `"a\nb"`},
+		{cty.StringVal("a\nEOT\n"), "<<-EOT1\n  a\n  EOT\nEOT1"},
+		{cty.StringVal("  a\n\n\tb\n"), `"  a\n\n\tb\n"`},
+		{cty.StringVal("  a\nb\n"), "<<-EOT\n    a\n  b\nEOT"},
+		{cty.TupleVal([]cty.Value{cty.StringVal("a\nb\n")}), "[\n  <<-EOT\n    a\n    b\n  EOT\n  ,\n]"},
+		{cty.NumberFloatVal(1.5), `1.5`},
+		{cty.NullVal(cty.String), `null`},
+		{cty.UnknownVal(cty.String), `(known after apply)`},
+		{cty.ListValEmpty(cty.String), `[]`},
+		{cty.SetVal([]cty.Value{cty.NumberIntVal(1)}), "[\n  1,\n]"},
+		{cty.MapValEmpty(cty.String), `{}`},
+		{
+			cty.ObjectVal(map[string]cty.Value{"bb": cty.True, "a": cty.False, "c d": cty.True}),
+			"{\n  a     = false\n  bb    = true\n  \"c d\" = true\n}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := formatValue(tt.val); got != tt.want {
+				t.Errorf("formatValue(%#v)\ngot:\n%s\nwant:\n%s", tt.val, got, tt.want)
+			}
+		})
+	}
+}
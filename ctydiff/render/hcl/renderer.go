@@ -0,0 +1,214 @@
+package hcl
+
+import (
+	"fmt"
+
+	"github.com/zclconf/go-cty-diff/ctydiff"
+	"github.com/zclconf/go-cty-diff/ctydiff/render"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Renderer is the render.Renderer that presents changes in HCL native
+// syntax, as used by Render. Callers can use it with their own
+// render.Printer to choose a different indentation or writer.
+//
+// Renderer tracks the groups that it is rendering in order to present the
+// elements of lists and sets of objects as blocks, and so a Renderer must
+// be used for only one diff at a time. The zero value is ready to use.
+type Renderer struct {
+	groups []groupKind
+}
+
+var _ render.ContextRunRenderer = (*Renderer)(nil)
+
+// groupKind describes how the items within a group are presented.
+type groupKind struct {
+	// blocks is the block type name for groups representing lists or sets
+	// of objects, whose elements are presented as blocks of that type.
+	blocks string
+
+	// sequence is true for groups representing lists, sets and tuples that
+	// are presented as tuple expressions.
+	sequence bool
+}
+
+// Value implements render.Renderer.
+func (r *Renderer) Value(val cty.Value) string {
+	return formatValue(val)
+}
+
+// Path returns the label for the given relative path as it would appear
+// on the left of an HCL attribute assignment. An attribute name that is not
+// a valid identifier is quoted, as is a map key. List elements and set
+// members are unlabelled, since they are written as items of a tuple
+// expression or as repeated blocks.
+func (r *Renderer) Path(path cty.Path) string {
+	if len(path) == 1 {
+		if step, ok := path[0].(cty.GetAttrStep); ok {
+			return formatName(step.Name)
+		}
+	}
+	return render.PathLabel(path, quoteString, formatValue)
+}
+
+// Replace implements render.Renderer.
+func (r *Renderer) Replace(c ctydiff.ReplaceChange, path, old, new string) string {
+	switch {
+	case render.IsNull(c.OldValue):
+		if block, ok := r.block("+", path, c.NewValue); ok {
+			return block
+		}
+		return r.line("+", path, new)
+	case render.IsNull(c.NewValue):
+		if block, ok := r.block("-", path, c.OldValue); ok {
+			return block
+		}
+		return r.line("-", path, followHeredoc(old, " -> null"))
+	default:
+		return r.line("~", path, followHeredoc(old, " -> "+new))
+	}
+}
+
+// Delete implements render.Renderer.
+func (r *Renderer) Delete(c ctydiff.DeleteChange, path, old string) string {
+	if block, ok := r.block("-", path, c.OldValue); ok {
+		return block
+	}
+	return r.line("-", path, old)
+}
+
+// Insert implements render.Renderer.
+func (r *Renderer) Insert(c ctydiff.InsertChange, path, new string) string {
+	if block, ok := r.block("+", path, c.NewValue); ok {
+		return block
+	}
+	return r.line("+", path, new)
+}
+
+// Add implements render.Renderer. Set additions are within the group for
+// the set, and so the new element is presented as a member of its tuple
+// expression unless it is a block.
+func (r *Renderer) Add(c ctydiff.AddChange, path, new string) string {
+	if block, ok := r.block("+", path, c.NewValue); ok {
+		return block
+	}
+	return r.line("+", path, new)
+}
+
+// Remove implements render.Renderer.
+func (r *Renderer) Remove(c ctydiff.RemoveChange, path, old string) string {
+	if block, ok := r.block("-", path, c.OldValue); ok {
+		return block
+	}
+	return r.line("-", path, old)
+}
+
+// Context implements render.Renderer.
+func (r *Renderer) Context(c ctydiff.Context, path, want string) string {
+	return r.line(" ", path, want)
+}
+
+// ContextRun implements render.ContextRunRenderer, summarizing the run as
+// a comment giving the number of hidden unchanged elements.
+func (r *Renderer) ContextRun(items []render.Item) string {
+	noun := "element"
+	if r.current().blocks != "" {
+		noun = "block"
+	}
+	if len(items) != 1 {
+		noun += "s"
+	}
+	return fmt.Sprintf("# (%d unchanged %s hidden)", len(items), noun)
+}
+
+// BeginGroup implements render.Renderer. Groups for lists and sets of
+// objects have no presentation of their own, since each of their elements
+// is presented as a separate block.
+func (r *Renderer) BeginGroup(g *render.Group, path string) string {
+	parent := r.current()
+	kind := groupKind{}
+	var begin string
+	switch {
+	case isIdentifier(path) && isBlockList(g.Value):
+		kind.blocks = path
+	case path == "" && parent.blocks != "":
+		begin = "~ " + parent.blocks + " {"
+	case isIdentifier(path) && isObject(g.Value):
+		begin = "~ " + path + " {"
+	case render.IsSequence(g):
+		kind.sequence = true
+		begin = r.line("~", path, "[")
+	default:
+		begin = r.line("~", path, "{")
+	}
+	r.groups = append(r.groups, kind)
+	return begin
+}
+
+// EndGroup implements render.Renderer.
+func (r *Renderer) EndGroup(g *render.Group) string {
+	kind := r.current()
+	r.groups = r.groups[:len(r.groups)-1]
+	switch {
+	case kind.blocks != "":
+		return ""
+	case kind.sequence:
+		return "  ]"
+	default:
+		return "  }"
+	}
+}
+
+// current returns the kind of the innermost group being rendered, which is
+// the zero groupKind for the root of the diff.
+func (r *Renderer) current() groupKind {
+	if len(r.groups) == 0 {
+		return groupKind{}
+	}
+	return r.groups[len(r.groups)-1]
+}
+
+// line returns the presentation of a single change with the given marker.
+// Elements of tuple expressions are unlabelled and followed by a comma.
+func (r *Renderer) line(marker, path, text string) string {
+	if path != "" {
+		text = path + " = " + text
+	} else if r.current().sequence {
+		text = followHeredoc(text, ",")
+	}
+	return marker + " " + indent(text, "  ")[2:]
+}
+
+// block returns the presentation of the given value as a block with the
+// given marker, if the value is an object that is presented as a block at
+// the given path.
+func (r *Renderer) block(marker, path string, val cty.Value) (string, bool) {
+	if !isObject(val) || val.IsNull() || !val.IsKnown() {
+		return "", false
+	}
+	name := path
+	if name == "" {
+		name = r.current().blocks
+	}
+	if !isIdentifier(name) {
+		return "", false
+	}
+	if val.LengthInt() == 0 {
+		return marker + " " + name + " {}", true
+	}
+	return marker + " " + name + " {\n" + indent(formatBody(val), "    ") + "\n  }", true
+}
+
+func isObject(val cty.Value) bool {
+	return val != cty.NilVal && val.Type().IsObjectType()
+}
+
+// isBlockList returns true if the given value is a list or set of objects,
+// whose elements are presented as blocks.
+func isBlockList(val cty.Value) bool {
+	if val == cty.NilVal {
+		return false
+	}
+	ty := val.Type()
+	return (ty.IsListType() || ty.IsSetType()) && ty.ElementType().IsObjectType()
+}
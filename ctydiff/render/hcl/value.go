@@ -0,0 +1,221 @@
+package hcl
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// formatValue returns the HCL expression for the given value. Collections
+// and objects with elements are written over multiple lines, with nested
+// lines indented by two spaces relative to the first.
+func formatValue(val cty.Value) string {
+	switch {
+	case val == cty.NilVal:
+		return "null"
+	case !val.IsKnown():
+		return "(known after apply)"
+	case val.IsNull():
+		return "null"
+	}
+
+	ty := val.Type()
+	switch {
+	case ty == cty.String:
+		s := val.AsString()
+		if isHeredoc(s) {
+			return formatHeredoc(s)
+		}
+		return quoteString(s)
+	case ty == cty.Number:
+		return val.AsBigFloat().Text('f', -1)
+	case ty == cty.Bool:
+		if val.True() {
+			return "true"
+		}
+		return "false"
+	case ty.IsListType() || ty.IsSetType() || ty.IsTupleType():
+		if val.LengthInt() == 0 {
+			return "[]"
+		}
+		var buf strings.Builder
+		buf.WriteString("[\n")
+		for it := val.ElementIterator(); it.Next(); {
+			_, ev := it.Element()
+			buf.WriteString(indent(followHeredoc(formatValue(ev), ","), "  "))
+			buf.WriteString("\n")
+		}
+		buf.WriteString("]")
+		return buf.String()
+	case ty.IsMapType() || ty.IsObjectType():
+		if val.LengthInt() == 0 {
+			return "{}"
+		}
+		return "{\n" + indent(formatBody(val), "  ") + "\n}"
+	default:
+		return fmt.Sprintf("(%s value)", ty.FriendlyName())
+	}
+}
+
+// formatBody returns the attributes of the given known, non-null map or
+// object value, one per line in order of name, with their equals signs
+// aligned.
+func formatBody(val cty.Value) string {
+	vals := val.AsValueMap()
+	names := make([]string, 0, len(vals))
+	width := 0
+	for name := range vals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	keys := make([]string, len(names))
+	for i, name := range names {
+		if val.Type().IsObjectType() {
+			keys[i] = formatName(name)
+		} else {
+			keys[i] = quoteString(name)
+		}
+		if n := utf8.RuneCountInString(keys[i]); n > width {
+			width = n
+		}
+	}
+
+	lines := make([]string, len(names))
+	for i, name := range names {
+		pad := strings.Repeat(" ", width-utf8.RuneCountInString(keys[i]))
+		lines[i] = keys[i] + pad + " = " + formatValue(vals[name])
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatName returns the given attribute name as it would be written in
+// HCL, which is as a bare identifier if possible or as a quoted string
+// otherwise.
+func formatName(name string) string {
+	if isIdentifier(name) {
+		return name
+	}
+	return quoteString(name)
+}
+
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+		case i > 0 && (r == '-' || (r >= '0' && r <= '9')):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// quoteString returns the given string as an HCL quoted template, escaping
+// any characters that would otherwise begin a template sequence.
+func quoteString(s string) string {
+	var buf strings.Builder
+	buf.WriteByte('"')
+	for i, r := range s {
+		switch r {
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '$', '%':
+			buf.WriteRune(r)
+			if strings.HasPrefix(s[i+1:], "{") {
+				buf.WriteRune(r)
+			}
+		default:
+			if r < ' ' {
+				fmt.Fprintf(&buf, `\u%04x`, r)
+				continue
+			}
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+// isHeredoc returns true if the given string is best presented as a
+// heredoc, which is the case for strings of more than one line that end
+// with a newline, since a heredoc always ends with one.
+//
+// A flush heredoc removes the leading whitespace common to its non-empty
+// lines, so a string whose non-empty lines are all indented would lose its
+// indentation and is quoted instead.
+func isHeredoc(s string) bool {
+	if !strings.HasSuffix(s, "\n") || strings.Count(s, "\n") < 2 ||
+		strings.ContainsAny(s, "\r") || strings.Contains(s, "${") || strings.Contains(s, "%{") {
+		return false
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(s, "\n"), "\n") {
+		if line != "" && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			return true
+		}
+	}
+	return false
+}
+
+// formatHeredoc returns the given string as an indented heredoc, choosing
+// a delimiter that does not appear as a line of the string.
+func formatHeredoc(s string) string {
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	delim := "EOT"
+	for n := 1; containsLine(lines, delim); n++ {
+		delim = "EOT" + strconv.Itoa(n)
+	}
+	return "<<-" + delim + "\n" + indent(strings.Join(lines, "\n"), "  ") + "\n" + delim
+}
+
+// followHeredoc returns the given text followed by the given suffix. A
+// heredoc's terminator must be alone on its line, so if the text ends with
+// one then the suffix is moved to the next line, without its leading
+// spaces.
+func followHeredoc(text, suffix string) string {
+	i := strings.LastIndex(text, "\n")
+	if i < 0 {
+		return text + suffix
+	}
+	delim := strings.TrimSpace(text[i+1:])
+	if delim == "" || !strings.Contains(text[:i+1], "<<-"+delim+"\n") {
+		return text + suffix
+	}
+	return text + "\n" + strings.TrimLeft(suffix, " ")
+}
+
+func containsLine(lines []string, s string) bool {
+	for _, line := range lines {
+		if strings.TrimSpace(line) == s {
+			return true
+		}
+	}
+	return false
+}
+
+// indent prefixes each non-empty line of the given text with the given
+// indentation.
+func indent(text, prefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
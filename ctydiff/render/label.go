@@ -0,0 +1,69 @@
+package render
+
+import (
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// PathLabel returns a label for the given relative path in the style
+// shared by most Renderers, for use in their implementations of
+// Renderer.Path.
+//
+// A path of a single step is labelled by the attribute name or by the
+// string key quoted with the given quote function, while list elements and
+// set members are unlabelled. Longer paths are written as traversals, with
+// keys that are neither strings nor numbers presented by the given value
+// function.
+func PathLabel(path cty.Path, quote func(string) string, value func(cty.Value) string) string {
+	if len(path) == 1 {
+		switch step := path[0].(type) {
+		case cty.GetAttrStep:
+			return step.Name
+		case cty.IndexStep:
+			if IsStringKey(step.Key) {
+				return quote(step.Key.AsString())
+			}
+			return ""
+		}
+	}
+
+	var buf strings.Builder
+	for _, step := range path {
+		switch step := step.(type) {
+		case cty.GetAttrStep:
+			if buf.Len() > 0 {
+				buf.WriteString(".")
+			}
+			buf.WriteString(step.Name)
+		case cty.IndexStep:
+			switch {
+			case IsStringKey(step.Key):
+				buf.WriteString("[" + quote(step.Key.AsString()) + "]")
+			case IsNumberKey(step.Key):
+				buf.WriteString("[" + step.Key.AsBigFloat().Text('f', -1) + "]")
+			default:
+				buf.WriteString("[" + value(step.Key) + "]")
+			}
+		}
+	}
+	return buf.String()
+}
+
+// IsNull returns true if the given value is cty.NilVal or a known null
+// value, which Renderers typically present as an absent value.
+func IsNull(val cty.Value) bool {
+	return val == cty.NilVal || (val.IsKnown() && val.IsNull())
+}
+
+// IsStringKey returns true if the given index key is a known, non-null
+// string, as used for map elements.
+func IsStringKey(key cty.Value) bool {
+	return key != cty.NilVal && key.Type() == cty.String && key.IsKnown() && !key.IsNull()
+}
+
+// IsNumberKey returns true if the given index key is a known, non-null
+// number, as used for list and tuple elements.
+func IsNumberKey(key cty.Value) bool {
+	return key != cty.NilVal && key.Type() == cty.Number && key.IsKnown() && !key.IsNull()
+}
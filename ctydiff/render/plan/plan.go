@@ -0,0 +1,103 @@
+// Package plan renders a ctydiff.Diff as an indented text block in the style
+// of a Terraform plan, with each changed line marked by "+" for additions,
+// "-" for removals and "~" for in-place updates.
+//
+// The presentation uses an HCL-like syntax for values, which is suitable
+// for command line tools that want readable output without defining their
+// own presentation. Languages with their own syntax can instead implement
+// their own render.Renderer.
+package plan
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/zclconf/go-cty-diff/ctydiff"
+	"github.com/zclconf/go-cty-diff/ctydiff/render"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Options represents optional settings for Render.
+type Options struct {
+	// Color causes the change markers to be colored using ANSI terminal
+	// escape sequences.
+	Color bool
+
+	// Width, if greater than zero, is the maximum number of characters
+	// per line. Longer lines are truncated and end with "...".
+	Width int
+}
+
+// Render returns the plan-style presentation of the given diff, which
+// applies to the given source value. The source value is used to choose
+// the presentation of nested values, and may be cty.NilVal if it is not
+// available.
+//
+// Runs of Context changes are summarized as a count of the hidden unchanged
+// elements.
+func Render(d ctydiff.Diff, source cty.Value, opts Options) string {
+	var buf bytes.Buffer
+	p := &render.Printer{
+		Renderer: Renderer{},
+		Indent:   "    ",
+	}
+	p.Print(&buf, d, source) // writing to a bytes.Buffer cannot fail
+	if buf.Len() == 0 {
+		return ""
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	for i, line := range lines {
+		if opts.Width > 0 {
+			line = truncate(line, opts.Width)
+		}
+		if opts.Color {
+			line = colorize(line)
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func truncate(line string, width int) string {
+	runes := []rune(line)
+	if len(runes) <= width {
+		return line
+	}
+	if width <= 3 {
+		return string(runes[:width])
+	}
+	return string(runes[:width-3]) + "..."
+}
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiDim    = "\x1b[2m"
+)
+
+// colorize colors the change marker at the start of the given line, if any.
+// Comment lines are dimmed in their entirety.
+func colorize(line string) string {
+	content := strings.TrimLeft(line, " ")
+	indent := line[:len(line)-len(content)]
+	if content == "" {
+		return line
+	}
+	var color string
+	switch content[0] {
+	case '+':
+		color = ansiGreen
+	case '-':
+		color = ansiRed
+	case '~':
+		color = ansiYellow
+	case '#':
+		return indent + ansiDim + content + ansiReset
+	default:
+		return line
+	}
+	return indent + color + content[:1] + ansiReset + content[1:]
+}
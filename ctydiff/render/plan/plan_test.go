@@ -0,0 +1,137 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty-diff/ctydiff"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestRender(t *testing.T) {
+	source := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("a"),
+		"list": cty.ListVal([]cty.Value{
+			cty.StringVal("w"),
+			cty.StringVal("x"),
+			cty.StringVal("y"),
+		}),
+		"tags": cty.MapVal(map[string]cty.Value{
+			"env": cty.StringVal("dev"),
+		}),
+	})
+	diff := ctydiff.NewDiff(source, cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("b"),
+		"list": cty.ListVal([]cty.Value{
+			cty.StringVal("w"),
+			cty.StringVal("x"),
+			cty.StringVal("z"),
+		}),
+		"tags": cty.MapVal(map[string]cty.Value{
+			"env":  cty.StringVal("prod"),
+			"team": cty.StringVal("core"),
+		}),
+	}))
+
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{
+			"Default",
+			Options{},
+			`~ list = [
+    # (2 unchanged elements hidden)
+    ~ "y" -> "z"
+  ]
+~ name = "a" -> "b"
+~ tags = {
+    ~ "env" = "dev" -> "prod"
+    + "team" = "core"
+  }
+`,
+		},
+		{
+			"Width",
+			Options{Width: 16},
+			`~ list = [
+    # (2 unch...
+    ~ "y" -> "z"
+  ]
+~ name = "a" ...
+~ tags = {
+    ~ "env" =...
+    + "team" ...
+  }
+`,
+		},
+		{
+			"Color",
+			Options{Color: true},
+			"\x1b[33m~\x1b[0m list = [\n" +
+				"    \x1b[2m# (2 unchanged elements hidden)\x1b[0m\n" +
+				"    \x1b[33m~\x1b[0m \"y\" -> \"z\"\n" +
+				"  ]\n" +
+				"\x1b[33m~\x1b[0m name = \"a\" -> \"b\"\n" +
+				"\x1b[33m~\x1b[0m tags = {\n" +
+				"    \x1b[33m~\x1b[0m \"env\" = \"dev\" -> \"prod\"\n" +
+				"    \x1b[32m+\x1b[0m \"team\" = \"core\"\n" +
+				"  }\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Render(diff, source, tt.opts)
+			if got != tt.want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRender_set(t *testing.T) {
+	source := cty.ObjectVal(map[string]cty.Value{
+		"set": cty.SetVal([]cty.Value{cty.StringVal("s1"), cty.StringVal("s3")}),
+	})
+	diff := ctydiff.NewDiff(source, cty.ObjectVal(map[string]cty.Value{
+		"set": cty.SetVal([]cty.Value{cty.StringVal("s2"), cty.StringVal("s3")}),
+	}))
+
+	got := Render(diff, source, Options{})
+	want := `~ set = [
+    - "s1"
+    + "s2"
+  ]
+`
+	if got != want {
+		t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderer_Value(t *testing.T) {
+	tests := []struct {
+		val  cty.Value
+		want string
+	}{
+		{cty.StringVal("a\"b"), `"a\"b"`},
+		{cty.NumberFloatVal(1.5), `1.5`},
+		{cty.True, `true`},
+		{cty.NullVal(cty.String), `null`},
+		{cty.UnknownVal(cty.String), `(known after apply)`},
+		{cty.ListValEmpty(cty.String), `[]`},
+		{cty.ListVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}), `["a", "b"]`},
+		{cty.SetVal([]cty.Value{cty.NumberIntVal(1)}), `[1]`},
+		{cty.MapValEmpty(cty.String), `{}`},
+		{cty.MapVal(map[string]cty.Value{"b": cty.True, "a": cty.False}), `{ "a" = false, "b" = true }`},
+		{cty.ObjectVal(map[string]cty.Value{"b": cty.True, "a": cty.False}), `{ a = false, b = true }`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := (Renderer{}).Value(tt.val); got != tt.want {
+				t.Errorf("Value(%#v) = %s, want %s", tt.val, got, tt.want)
+			}
+		})
+	}
+}
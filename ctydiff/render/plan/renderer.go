@@ -0,0 +1,163 @@
+package plan
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zclconf/go-cty-diff/ctydiff"
+	"github.com/zclconf/go-cty-diff/ctydiff/render"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Renderer is the render.Renderer implementation used by Render, for
+// callers that want to use it with their own render.Printer.
+//
+// Renderer also implements render.ContextRunRenderer, so that runs of
+// Context changes are summarized rather than shown.
+type Renderer struct{}
+
+var _ render.ContextRunRenderer = Renderer{}
+
+// Value returns a single-line HCL-like presentation of the given value.
+func (r Renderer) Value(val cty.Value) string {
+	switch {
+	case val == cty.NilVal:
+		return "null"
+	case !val.IsKnown():
+		return "(known after apply)"
+	case val.IsNull():
+		return "null"
+	}
+
+	ty := val.Type()
+	switch {
+	case ty == cty.String:
+		return strconv.Quote(val.AsString())
+	case ty == cty.Number:
+		return val.AsBigFloat().Text('f', -1)
+	case ty == cty.Bool:
+		if val.True() {
+			return "true"
+		}
+		return "false"
+	case ty.IsListType() || ty.IsSetType() || ty.IsTupleType():
+		elems := make([]string, 0, val.LengthInt())
+		for it := val.ElementIterator(); it.Next(); {
+			_, ev := it.Element()
+			elems = append(elems, r.Value(ev))
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	case ty.IsMapType():
+		if val.LengthInt() == 0 {
+			return "{}"
+		}
+		elems := make([]string, 0, val.LengthInt())
+		for it := val.ElementIterator(); it.Next(); {
+			kv, ev := it.Element()
+			elems = append(elems, strconv.Quote(kv.AsString())+" = "+r.Value(ev))
+		}
+		return "{ " + strings.Join(elems, ", ") + " }"
+	case ty.IsObjectType():
+		atys := ty.AttributeTypes()
+		if len(atys) == 0 {
+			return "{}"
+		}
+		names := make([]string, 0, len(atys))
+		for name := range atys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		elems := make([]string, 0, len(names))
+		for _, name := range names {
+			elems = append(elems, name+" = "+r.Value(val.GetAttr(name)))
+		}
+		return "{ " + strings.Join(elems, ", ") + " }"
+	default:
+		return fmt.Sprintf("(%s value)", ty.FriendlyName())
+	}
+}
+
+// Path returns the label for the given relative path. Attributes are
+// labelled by name and map elements by their quoted key, while list
+// elements and set members are unlabelled.
+func (r Renderer) Path(path cty.Path) string {
+	return render.PathLabel(path, strconv.Quote, r.Value)
+}
+
+// Replace implements render.Renderer.
+func (r Renderer) Replace(c ctydiff.ReplaceChange, path, old, new string) string {
+	switch {
+	case render.IsNull(c.OldValue):
+		return "+ " + labelled(path, new)
+	case render.IsNull(c.NewValue):
+		return "- " + labelled(path, old+" -> null")
+	default:
+		return "~ " + labelled(path, old+" -> "+new)
+	}
+}
+
+// Delete implements render.Renderer.
+func (r Renderer) Delete(c ctydiff.DeleteChange, path, old string) string {
+	return "- " + labelled(path, old)
+}
+
+// Insert implements render.Renderer.
+func (r Renderer) Insert(c ctydiff.InsertChange, path, new string) string {
+	return "+ " + labelled(path, new)
+}
+
+// Add implements render.Renderer. Set additions are within the group for
+// the set, and so the new element is presented as a member of it.
+func (r Renderer) Add(c ctydiff.AddChange, path, new string) string {
+	return "+ " + labelled(path, new)
+}
+
+// Remove implements render.Renderer.
+func (r Renderer) Remove(c ctydiff.RemoveChange, path, old string) string {
+	return "- " + labelled(path, old)
+}
+
+// Context implements render.Renderer.
+func (r Renderer) Context(c ctydiff.Context, path, want string) string {
+	return "  " + labelled(path, want)
+}
+
+// ContextRun implements render.ContextRunRenderer, summarizing the run as
+// a count of hidden unchanged elements or attributes.
+func (r Renderer) ContextRun(items []render.Item) string {
+	noun := "element"
+	if len(items[0].RelPath) > 0 {
+		if _, ok := items[0].RelPath[0].(cty.GetAttrStep); ok {
+			noun = "attribute"
+		}
+	}
+	if len(items) != 1 {
+		noun += "s"
+	}
+	return fmt.Sprintf("# (%d unchanged %s hidden)", len(items), noun)
+}
+
+// BeginGroup implements render.Renderer.
+func (r Renderer) BeginGroup(g *render.Group, path string) string {
+	if render.IsSequence(g) {
+		return "~ " + labelled(path, "[")
+	}
+	return "~ " + labelled(path, "{")
+}
+
+// EndGroup implements render.Renderer.
+func (r Renderer) EndGroup(g *render.Group) string {
+	if render.IsSequence(g) {
+		return "  ]"
+	}
+	return "  }"
+}
+
+func labelled(path, text string) string {
+	if path == "" {
+		return text
+	}
+	return path + " = " + text
+}
@@ -0,0 +1,173 @@
+package render
+
+import (
+	"io"
+	"strings"
+
+	"github.com/zclconf/go-cty-diff/ctydiff"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Renderer is implemented by callers to produce the text for the various
+// parts of a diff in the syntax of a particular language.
+//
+// Each method returns text that may span multiple lines. The Printer
+// indents each line according to its nesting depth and terminates it with
+// a newline, so implementations should not include indentation or a
+// trailing newline. A method may return an empty string to omit that
+// element entirely.
+type Renderer interface {
+	// Value returns the presentation of a value.
+	Value(val cty.Value) string
+
+	// Path returns the presentation of a path relative to the group it
+	// appears in. The path may be empty, for changes that apply to the
+	// group's own value.
+	Path(path cty.Path) string
+
+	// The change methods return the presentation of a single change of
+	// the corresponding type, given the change itself along with the
+	// results of the Path and Value methods for its relative path and
+	// its values.
+	Replace(c ctydiff.ReplaceChange, path, old, new string) string
+	Delete(c ctydiff.DeleteChange, path, old string) string
+	Insert(c ctydiff.InsertChange, path, new string) string
+	Add(c ctydiff.AddChange, path, new string) string
+	Remove(c ctydiff.RemoveChange, path, old string) string
+	Context(c ctydiff.Context, path, want string) string
+
+	// BeginGroup and EndGroup return the text before and after the
+	// items of a nested group. The path is the result of the Path method
+	// for the group's RelPath. If BeginGroup returns an empty string then
+	// the group's items are printed at the same indentation as the group
+	// itself, rather than nested inside it.
+	BeginGroup(g *Group, path string) string
+	EndGroup(g *Group) string
+}
+
+// ContextRunRenderer is an optional extension of Renderer for renderers
+// that present consecutive Context changes together, such as to summarize
+// them rather than showing each one.
+//
+// If a Printer's Renderer implements this interface then the Printer calls
+// ContextRun once for each run of consecutive Context items in a group,
+// instead of calling Context for each of them.
+type ContextRunRenderer interface {
+	ContextRun(items []Item) string
+}
+
+// Printer writes diffs using a Renderer.
+type Printer struct {
+	Renderer Renderer
+
+	// Indent is the string used for each level of indentation. If it is
+	// empty then two spaces are used.
+	Indent string
+
+	// Compact causes chains of groups that contain nothing but a single
+	// nested group to be merged, as with Group.Compact.
+	Compact bool
+}
+
+// Print writes the given diff to the given writer. The source value is
+// passed to BuildTree and may be cty.NilVal if it is not available.
+func (p *Printer) Print(w io.Writer, d ctydiff.Diff, source cty.Value) error {
+	tree := BuildTree(d, source)
+	if p.Compact {
+		tree.Compact()
+	}
+	return p.PrintTree(w, tree)
+}
+
+// PrintTree writes the items of the given group, and recursively those of
+// its nested groups, to the given writer. The given group itself is not
+// wrapped by calls to BeginGroup and EndGroup.
+func (p *Printer) PrintTree(w io.Writer, g *Group) error {
+	pw := &printWriter{w: w}
+	p.printItems(pw, g, 0)
+	return pw.err
+}
+
+func (p *Printer) printItems(w *printWriter, g *Group, depth int) {
+	r := p.Renderer
+	runRenderer, summarizeRuns := r.(ContextRunRenderer)
+	for i := 0; i < len(g.Items); i++ {
+		item := g.Items[i]
+		if summarizeRuns && isContext(item) {
+			start := i
+			for i+1 < len(g.Items) && isContext(g.Items[i+1]) {
+				i++
+			}
+			p.write(w, depth, runRenderer.ContextRun(g.Items[start:i+1]))
+			continue
+		}
+		if item.Group != nil {
+			begin := r.BeginGroup(item.Group, r.Path(item.RelPath))
+			if begin == "" {
+				// The group has no presentation of its own, so its items
+				// appear as if they were in the enclosing group.
+				p.printItems(w, item.Group, depth)
+			} else {
+				p.write(w, depth, begin)
+				p.printItems(w, item.Group, depth+1)
+			}
+			p.write(w, depth, r.EndGroup(item.Group))
+			continue
+		}
+
+		path := r.Path(item.RelPath)
+		switch c := item.Change.(type) {
+		case ctydiff.ReplaceChange:
+			p.write(w, depth, r.Replace(c, path, r.Value(c.OldValue), r.Value(c.NewValue)))
+		case ctydiff.DeleteChange:
+			p.write(w, depth, r.Delete(c, path, r.Value(c.OldValue)))
+		case ctydiff.InsertChange:
+			p.write(w, depth, r.Insert(c, path, r.Value(c.NewValue)))
+		case ctydiff.AddChange:
+			p.write(w, depth, r.Add(c, path, r.Value(c.NewValue)))
+		case ctydiff.RemoveChange:
+			p.write(w, depth, r.Remove(c, path, r.Value(c.OldValue)))
+		case ctydiff.Context:
+			p.write(w, depth, r.Context(c, path, r.Value(c.WantValue)))
+		}
+	}
+}
+
+func isContext(item Item) bool {
+	_, ok := item.Change.(ctydiff.Context)
+	return ok
+}
+
+func (p *Printer) write(w *printWriter, depth int, text string) {
+	if text == "" {
+		return
+	}
+	unit := p.Indent
+	if unit == "" {
+		unit = "  "
+	}
+	indent := strings.Repeat(unit, depth)
+	for _, line := range strings.Split(text, "\n") {
+		if line == "" {
+			w.WriteString("\n")
+			continue
+		}
+		w.WriteString(indent)
+		w.WriteString(line)
+		w.WriteString("\n")
+	}
+}
+
+// printWriter wraps an io.Writer to retain the first error it returns, so
+// that a sequence of writes can be checked for errors only once at the end.
+type printWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (w *printWriter) WriteString(s string) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = io.WriteString(w.w, s)
+}
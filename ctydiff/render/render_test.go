@@ -0,0 +1,220 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/zclconf/go-cty-diff/ctydiff"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestPrinter_Print(t *testing.T) {
+	diff := ctydiff.Diff{
+		ctydiff.ReplaceChange{
+			Path:     cty.GetAttrPath("name"),
+			OldValue: cty.StringVal("a"),
+			NewValue: cty.StringVal("b"),
+		},
+		ctydiff.Context{
+			Path:      cty.GetAttrPath("list").Index(cty.NumberIntVal(0)),
+			WantValue: cty.StringVal("x"),
+		},
+		ctydiff.DeleteChange{
+			Path:     cty.GetAttrPath("list").Index(cty.NumberIntVal(1)),
+			OldValue: cty.StringVal("y"),
+		},
+		ctydiff.InsertChange{
+			Path:        cty.GetAttrPath("list").Index(cty.NumberIntVal(1)),
+			NewValue:    cty.StringVal("z"),
+			BeforeValue: cty.NullVal(cty.String),
+			ElementPath: true,
+		},
+		ctydiff.AddChange{
+			Path:     cty.GetAttrPath("obj").GetAttr("inner").GetAttr("set"),
+			NewValue: cty.StringVal("s"),
+		},
+		ctydiff.RemoveChange{
+			Path:     cty.GetAttrPath("obj").GetAttr("inner").GetAttr("set"),
+			OldValue: cty.StringVal("t"),
+		},
+	}
+
+	tests := []struct {
+		name    string
+		printer *Printer
+		want    string
+	}{
+		{
+			"Default",
+			&Printer{Renderer: testRenderer{}},
+			`replace name: "a" => "b"
+begin list
+  context [0]: "x"
+  delete [1]: "y"
+  insert [1]: "z"
+end
+begin obj
+  begin inner
+    begin set
+      add : "s"
+      remove : "t"
+    end
+  end
+end
+`,
+		},
+		{
+			"CompactIndent",
+			&Printer{Renderer: testRenderer{}, Compact: true, Indent: "\t"},
+			`replace name: "a" => "b"
+begin list
+	context [0]: "x"
+	delete [1]: "y"
+	insert [1]: "z"
+end
+begin obj.inner.set
+	add : "s"
+	remove : "t"
+end
+`,
+		},
+		{
+			"ContextRun",
+			&Printer{Renderer: testRunRenderer{}},
+			`replace name: "a" => "b"
+begin list
+  1 unchanged
+  delete [1]: "y"
+  insert [1]: "z"
+end
+begin obj
+  begin inner
+    begin set
+      add : "s"
+      remove : "t"
+    end
+  end
+end
+`,
+		},
+		{
+			"TransparentGroup",
+			&Printer{Renderer: testFlatRenderer{}},
+			`replace name: "a" => "b"
+begin list
+  context [0]: "x"
+  delete [1]: "y"
+  insert [1]: "z"
+end
+begin obj
+  begin set
+    add : "s"
+    remove : "t"
+  end
+end
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.printer.Print(&buf, diff, cty.NilVal); err != nil {
+				t.Fatalf("Print() err = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// testRenderer is a minimal Renderer that labels each line with the type
+// of the element being rendered.
+type testRenderer struct{}
+
+func (testRenderer) Value(val cty.Value) string {
+	if val.Type() == cty.String && val.IsKnown() && !val.IsNull() {
+		return fmt.Sprintf("%q", val.AsString())
+	}
+	return val.GoString()
+}
+
+func (testRenderer) Path(path cty.Path) string {
+	var buf strings.Builder
+	for i, step := range path {
+		switch step := step.(type) {
+		case cty.GetAttrStep:
+			if i > 0 {
+				buf.WriteString(".")
+			}
+			buf.WriteString(step.Name)
+		case cty.IndexStep:
+			buf.WriteString("[" + step.Key.AsBigFloat().String() + "]")
+		}
+	}
+	return buf.String()
+}
+
+func (r testRenderer) Replace(c ctydiff.ReplaceChange, path, old, new string) string {
+	return fmt.Sprintf("replace %s: %s => %s", path, old, new)
+}
+
+func (r testRenderer) Delete(c ctydiff.DeleteChange, path, old string) string {
+	return fmt.Sprintf("delete %s: %s", path, old)
+}
+
+func (r testRenderer) Insert(c ctydiff.InsertChange, path, new string) string {
+	return fmt.Sprintf("insert %s: %s", path, new)
+}
+
+func (r testRenderer) Add(c ctydiff.AddChange, path, new string) string {
+	return fmt.Sprintf("add %s: %s", path, new)
+}
+
+func (r testRenderer) Remove(c ctydiff.RemoveChange, path, old string) string {
+	return fmt.Sprintf("remove %s: %s", path, old)
+}
+
+func (r testRenderer) Context(c ctydiff.Context, path, want string) string {
+	return fmt.Sprintf("context %s: %s", path, want)
+}
+
+func (r testRenderer) BeginGroup(g *Group, path string) string {
+	return "begin " + path
+}
+
+func (r testRenderer) EndGroup(g *Group) string {
+	return "end"
+}
+
+// testRunRenderer is a testRenderer that summarizes runs of Context changes.
+type testRunRenderer struct {
+	testRenderer
+}
+
+func (r testRunRenderer) ContextRun(items []Item) string {
+	return fmt.Sprintf("%d unchanged", len(items))
+}
+
+// testFlatRenderer is a testRenderer that omits the begin and end lines for
+// groups named "inner", so that their items appear in the enclosing group.
+type testFlatRenderer struct {
+	testRenderer
+}
+
+func (r testFlatRenderer) BeginGroup(g *Group, path string) string {
+	if path == "inner" {
+		return ""
+	}
+	return r.testRenderer.BeginGroup(g, path)
+}
+
+func (r testFlatRenderer) EndGroup(g *Group) string {
+	if r.Path(g.RelPath) == "inner" {
+		return ""
+	}
+	return r.testRenderer.EndGroup(g)
+}
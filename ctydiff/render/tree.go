@@ -0,0 +1,242 @@
+package render
+
+import (
+	"github.com/zclconf/go-cty-diff/ctydiff"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Group is a node in the tree produced by BuildTree, representing all of
+// the changes whose paths share a common prefix.
+type Group struct {
+	// Path is the absolute path of the group.
+	Path cty.Path
+
+	// RelPath is the path of the group relative to its parent group. It
+	// is empty for the root group and for groups that have not been
+	// compacted it is always a single step.
+	RelPath cty.Path
+
+	// Value is the value at the group's path in the source value given
+	// to BuildTree, or cty.NilVal if the source value was not given or
+	// has no value at that path. Since changes to lists can alter the
+	// indices of subsequent elements, Value is only a guide to the
+	// structure of the value that the changes apply to.
+	Value cty.Value
+
+	// Items are the changes and nested groups within the group, in the
+	// order they first appear in the diff.
+	Items []Item
+}
+
+// Item is a single element of a Group, which is either a change or a
+// nested group.
+type Item struct {
+	// Change is the change represented by the item, or nil if the item
+	// represents a nested group. The change's own path may be relative to
+	// a NestedDiff, so RelPath and AbsPath should be used instead.
+	Change ctydiff.Change
+
+	// RelPath is the path of the change relative to its group. It is
+	// empty when the change applies to the group's own value, such as when
+	// adding to a set or replacing a value that also has nested changes.
+	RelPath cty.Path
+
+	// AbsPath is the absolute path of the change.
+	AbsPath cty.Path
+
+	// Group is the nested group represented by the item, or nil if the item
+	// represents a change.
+	Group *Group
+}
+
+// BuildTree arranges the changes in the given diff into a tree of groups,
+// where each change belongs to the group for its parent path.
+//
+// The given source value is used only to populate the Value field of each
+// group, and may be cty.NilVal if it is not available.
+//
+// NestedDiff changes are expanded into their nested changes, which are
+// placed in the group for the NestedDiff's path. AddChange and RemoveChange,
+// whose paths are to the set itself, are placed in the group for the set,
+// so that they are presented as members of the set rather than as changes
+// to the value of the set as a whole.
+func BuildTree(d ctydiff.Diff, source cty.Value) *Group {
+	root := &Group{
+		Path:    cty.Path{},
+		RelPath: cty.Path{},
+		Value:   source,
+	}
+
+	d.Walk(func(absPath cty.Path, c ctydiff.Change) error {
+		parentLen := len(absPath) - 1
+		switch c.(type) {
+		case ctydiff.AddChange, ctydiff.RemoveChange:
+			parentLen = len(absPath)
+		}
+		g := root
+		for i := 0; i < parentLen; i++ {
+			g = g.child(absPath[i])
+		}
+		if _, nested := c.(ctydiff.NestedDiff); nested {
+			// The NestedDiff itself is represented only by the group
+			// for its path, which contains its nested changes.
+			if len(absPath) > 0 {
+				g.child(absPath[len(absPath)-1])
+			}
+			return nil
+		}
+		g.Items = append(g.Items, Item{
+			Change:  c,
+			RelPath: absPath[len(g.Path):],
+			AbsPath: absPath,
+		})
+		return nil
+	})
+
+	root.adoptChanges()
+	return root
+}
+
+// child returns the nested group for the given step, creating it if
+// necessary.
+func (g *Group) child(step cty.PathStep) *Group {
+	for _, item := range g.Items {
+		if item.Group != nil && stepsEqual(item.Group.RelPath[0], step) {
+			return item.Group
+		}
+	}
+	path := make(cty.Path, len(g.Path)+1)
+	copy(path, g.Path)
+	path[len(g.Path)] = step
+	child := &Group{
+		Path:    path,
+		RelPath: cty.Path{step},
+		Value:   stepValue(g.Value, step),
+	}
+	g.Items = append(g.Items, Item{
+		RelPath: child.RelPath,
+		AbsPath: child.Path,
+		Group:   child,
+	})
+	return child
+}
+
+// adoptChanges moves any changes whose path is the same as that of a sibling
+// group into that group, so that all of the changes to a particular value
+// are presented together.
+func (g *Group) adoptChanges() {
+	items := g.Items[:0]
+	for _, item := range g.Items {
+		if item.Group == nil && len(item.RelPath) == 1 {
+			if sub := g.findChild(item.RelPath[0]); sub != nil {
+				item.RelPath = cty.Path{}
+				sub.Items = append([]Item{item}, sub.Items...)
+				continue
+			}
+		}
+		items = append(items, item)
+	}
+	g.Items = items
+	for _, item := range g.Items {
+		if item.Group != nil {
+			item.Group.adoptChanges()
+		}
+	}
+}
+
+func (g *Group) findChild(step cty.PathStep) *Group {
+	for _, item := range g.Items {
+		if item.Group != nil && stepsEqual(item.Group.RelPath[0], step) {
+			return item.Group
+		}
+	}
+	return nil
+}
+
+// Compact merges each nested group that contains only a single nested group
+// with that group, so that a chain of steps with no changes of their own is
+// presented as a single group with a multi-step RelPath.
+func (g *Group) Compact() {
+	for i, item := range g.Items {
+		if item.Group == nil {
+			continue
+		}
+		sub := item.Group
+		for len(sub.Items) == 1 && sub.Items[0].Group != nil {
+			only := sub.Items[0].Group
+			rel := make(cty.Path, 0, len(sub.RelPath)+len(only.RelPath))
+			rel = append(rel, sub.RelPath...)
+			rel = append(rel, only.RelPath...)
+			only.RelPath = rel
+			sub = only
+		}
+		sub.Compact()
+		g.Items[i].Group = sub
+		g.Items[i].RelPath = sub.RelPath
+		g.Items[i].AbsPath = sub.Path
+	}
+}
+
+// IsSequence returns true if the given group's value is a list, set or
+// tuple, which Renderers typically present in brackets. The choice is based
+// on the type of the group's source value if available, or on the items in
+// the group otherwise.
+func IsSequence(g *Group) bool {
+	if g.Value != cty.NilVal {
+		ty := g.Value.Type()
+		return ty.IsListType() || ty.IsSetType() || ty.IsTupleType()
+	}
+	for _, item := range g.Items {
+		if len(item.RelPath) == 0 {
+			switch item.Change.(type) {
+			case ctydiff.AddChange, ctydiff.RemoveChange:
+				return true
+			}
+			continue
+		}
+		step, ok := item.RelPath[0].(cty.IndexStep)
+		return ok && IsNumberKey(step.Key)
+	}
+	return false
+}
+
+// stepsEqual returns true if the two given steps select the same element.
+func stepsEqual(a, b cty.PathStep) bool {
+	switch a := a.(type) {
+	case cty.GetAttrStep:
+		b, ok := b.(cty.GetAttrStep)
+		return ok && a.Name == b.Name
+	case cty.IndexStep:
+		b, ok := b.(cty.IndexStep)
+		if !ok || a.Key == cty.NilVal || b.Key == cty.NilVal {
+			return false
+		}
+		return a.Key.RawEquals(b.Key)
+	}
+	return false
+}
+
+// stepValue returns the value selected by the given step from the given
+// value, or cty.NilVal if there is no such value.
+func stepValue(val cty.Value, step cty.PathStep) cty.Value {
+	if val == cty.NilVal || val.IsNull() || !val.IsKnown() {
+		return cty.NilVal
+	}
+	if index, ok := step.(cty.IndexStep); ok {
+		if index.Key == cty.NilVal || index.Key.IsNull() || !index.Key.IsKnown() {
+			return cty.NilVal
+		}
+		if val.Type().IsSetType() {
+			// Set elements are addressed by their own value.
+			if !index.Key.Type().Equals(val.Type().ElementType()) || !val.AsValueSet().Has(index.Key) {
+				return cty.NilVal
+			}
+			return index.Key
+		}
+	}
+	ret, err := step.Apply(val)
+	if err != nil {
+		return cty.NilVal
+	}
+	return ret
+}
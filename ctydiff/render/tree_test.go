@@ -0,0 +1,206 @@
+package render
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zclconf/go-cty-diff/ctydiff"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestBuildTree(t *testing.T) {
+	setElem := cty.ObjectVal(map[string]cty.Value{"n": cty.StringVal("x")})
+	source := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("a"),
+		"obj": cty.ObjectVal(map[string]cty.Value{
+			"a": cty.StringVal("A"),
+			"b": cty.StringVal("B"),
+		}),
+		"set": cty.SetVal([]cty.Value{setElem}),
+	})
+	nameChange := ctydiff.ReplaceChange{
+		Path:     cty.GetAttrPath("name"),
+		OldValue: cty.StringVal("a"),
+		NewValue: cty.StringVal("b"),
+	}
+	aChange := ctydiff.ReplaceChange{
+		Path:     cty.GetAttrPath("obj").GetAttr("a"),
+		OldValue: cty.StringVal("A"),
+		NewValue: cty.StringVal("X"),
+	}
+	bChange := ctydiff.Context{
+		Path:      cty.GetAttrPath("obj").GetAttr("b"),
+		WantValue: cty.StringVal("B"),
+	}
+	setAdd := ctydiff.AddChange{
+		Path:     cty.GetAttrPath("set"),
+		NewValue: setElem,
+	}
+	nChange := ctydiff.ReplaceChange{
+		Path:     cty.GetAttrPath("n"),
+		OldValue: cty.StringVal("x"),
+		NewValue: cty.StringVal("y"),
+	}
+	diff := ctydiff.Diff{
+		nameChange,
+		aChange,
+		setAdd,
+		bChange,
+		ctydiff.NestedDiff{
+			Path:     cty.GetAttrPath("set").Index(setElem),
+			OldValue: setElem,
+			Diff:     ctydiff.Diff{nChange},
+		},
+	}
+
+	got := BuildTree(diff, source)
+
+	setElemGroup := &Group{
+		Path:    cty.GetAttrPath("set").Index(setElem),
+		RelPath: cty.IndexPath(setElem),
+		Value:   setElem,
+		Items: []Item{
+			{
+				Change:  nChange,
+				RelPath: cty.GetAttrPath("n"),
+				AbsPath: cty.GetAttrPath("set").Index(setElem).GetAttr("n"),
+			},
+		},
+	}
+	objGroup := &Group{
+		Path:    cty.GetAttrPath("obj"),
+		RelPath: cty.GetAttrPath("obj"),
+		Value:   source.GetAttr("obj"),
+		Items: []Item{
+			{
+				Change:  aChange,
+				RelPath: cty.GetAttrPath("a"),
+				AbsPath: aChange.Path,
+			},
+			{
+				Change:  bChange,
+				RelPath: cty.GetAttrPath("b"),
+				AbsPath: bChange.Path,
+			},
+		},
+	}
+	setGroup := &Group{
+		Path:    cty.GetAttrPath("set"),
+		RelPath: cty.GetAttrPath("set"),
+		Value:   source.GetAttr("set"),
+		Items: []Item{
+			{
+				Change:  setAdd,
+				RelPath: cty.Path{},
+				AbsPath: setAdd.Path,
+			},
+			{
+				RelPath: setElemGroup.RelPath,
+				AbsPath: setElemGroup.Path,
+				Group:   setElemGroup,
+			},
+		},
+	}
+	want := &Group{
+		Path:    cty.Path{},
+		RelPath: cty.Path{},
+		Value:   source,
+		Items: []Item{
+			{
+				Change:  nameChange,
+				RelPath: nameChange.Path,
+				AbsPath: nameChange.Path,
+			},
+			{
+				RelPath: objGroup.RelPath,
+				AbsPath: objGroup.Path,
+				Group:   objGroup,
+			},
+			{
+				RelPath: setGroup.RelPath,
+				AbsPath: setGroup.Path,
+				Group:   setGroup,
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestGroup_Compact(t *testing.T) {
+	change := ctydiff.ReplaceChange{
+		Path:     cty.GetAttrPath("a").GetAttr("b").GetAttr("c"),
+		OldValue: cty.StringVal("A"),
+		NewValue: cty.StringVal("B"),
+	}
+	tree := BuildTree(ctydiff.Diff{change}, cty.NilVal)
+	tree.Compact()
+
+	if got, want := len(tree.Items), 1; got != want {
+		t.Fatalf("wrong number of items %d; want %d", got, want)
+	}
+	g := tree.Items[0].Group
+	if g == nil {
+		t.Fatalf("item is not a group")
+	}
+	if got, want := g.RelPath, cty.GetAttrPath("a").GetAttr("b"); !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong group RelPath\ngot:  %#v\nwant: %#v", got, want)
+	}
+	if got, want := tree.Items[0].RelPath, g.RelPath; !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong item RelPath\ngot:  %#v\nwant: %#v", got, want)
+	}
+	if got, want := len(g.Items), 1; got != want {
+		t.Fatalf("wrong number of group items %d; want %d", got, want)
+	}
+	if got, want := g.Items[0].RelPath, cty.GetAttrPath("c"); !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong change RelPath\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestIsSequence(t *testing.T) {
+	tests := map[string]struct {
+		group Group
+		want  bool
+	}{
+		"ListValue": {
+			Group{Value: cty.ListValEmpty(cty.String)},
+			true,
+		},
+		"MapValue": {
+			Group{
+				Value: cty.MapValEmpty(cty.String),
+				Items: []Item{{RelPath: cty.IndexPath(cty.NumberIntVal(0))}},
+			},
+			false,
+		},
+		"IndexStep": {
+			Group{Items: []Item{{RelPath: cty.IndexPath(cty.NumberIntVal(0))}}},
+			true,
+		},
+		"KeyStep": {
+			Group{Items: []Item{{RelPath: cty.IndexPath(cty.StringVal("k"))}}},
+			false,
+		},
+		"SetChange": {
+			Group{Items: []Item{
+				{Change: ctydiff.Context{}},
+				{Change: ctydiff.AddChange{NewValue: cty.True}},
+			}},
+			true,
+		},
+		"Empty": {
+			Group{},
+			false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := IsSequence(&test.group); got != test.want {
+				t.Errorf("wrong result %t; want %t", got, test.want)
+			}
+		})
+	}
+}
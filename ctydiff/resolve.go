@@ -0,0 +1,182 @@
+package ctydiff
+
+import (
+	"time"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ConflictResolver is a strategy for automatically resolving the conflicts
+// found by MergeWithOptions.
+//
+// This package provides the resolvers ResolveOurs, ResolveTheirs,
+// ResolveUnion, ResolveLists and ResolveNewer, and callers can implement
+// their own strategies by constructing a ConflictResolver with a custom
+// Resolve function.
+type ConflictResolver struct {
+	// Name identifies the strategy in the MergeResolution for each
+	// conflict that it resolves.
+	Name string
+
+	// Resolve returns the merged value for the given conflict, or false if
+	// the strategy does not apply to it. The root argument has the complete
+	// values that were passed to MergeWithOptions, with an empty path, for
+	// strategies that depend on the values containing the conflicting
+	// ones.
+	//
+	// The returned value must have the same type as either our side or
+	// their side of the conflict, or it may be cty.NilVal to omit a map
+	// element. Any other value is disregarded, as if Resolve had returned
+	// false.
+	Resolve func(conflict, root MergeConflict) (cty.Value, bool)
+}
+
+// ResolveOurs is a ConflictResolver that resolves every conflict with our
+// side's value.
+var ResolveOurs = ConflictResolver{
+	Name: "ours",
+	Resolve: func(conflict, root MergeConflict) (cty.Value, bool) {
+		return conflict.Ours, true
+	},
+}
+
+// ResolveTheirs is a ConflictResolver that resolves every conflict with
+// their side's value.
+var ResolveTheirs = ConflictResolver{
+	Name: "theirs",
+	Resolve: func(conflict, root MergeConflict) (cty.Value, bool) {
+		return conflict.Theirs, true
+	},
+}
+
+// ResolveUnion is a ConflictResolver that resolves conflicts between sets,
+// and between lists where both sides only inserted elements, by keeping
+// the elements from both sides.
+//
+// The union of two sets has every element of either. The union of two
+// lists has the elements that each side inserted at the positions where
+// they were inserted, ordered as by Rebase where both inserted at the same
+// position, and elements that both sides inserted at the same position
+// only once.
+var ResolveUnion = ConflictResolver{
+	Name: "union",
+	Resolve: func(conflict, root MergeConflict) (cty.Value, bool) {
+		ours, theirs := conflict.Ours, conflict.Theirs
+		if !hasTypeOf(ours, theirs) || requireKnown(ours) != nil || requireKnown(theirs) != nil {
+			return cty.NilVal, false
+		}
+		ty := ours.Type()
+		switch {
+		case ty.IsSetType():
+			return cty.SetValFromValueSet(ours.AsValueSet().Union(theirs.AsValueSet())), true
+		case ty.IsListType():
+			return unionLists(conflict.Base, ours, theirs)
+		}
+		return cty.NilVal, false
+	},
+}
+
+// unionLists returns the result of applying the insertions made to the
+// given base list in both ours and theirs, or false if either side made any
+// other change.
+func unionLists(base, ours, theirs cty.Value) (cty.Value, bool) {
+	if !hasTypeOf(base, ours) || requireKnown(base) != nil {
+		return cty.NilVal, false
+	}
+	d := NewDiffWithOptions(base, ours, DiffOptions{NoContext: true})
+	onto := NewDiffWithOptions(base, theirs, DiffOptions{NoContext: true})
+	for _, diff := range []Diff{d, onto} {
+		for _, c := range diff {
+			if _, ok := c.(InsertChange); !ok {
+				return cty.NilVal, false
+			}
+		}
+	}
+	rebased, err := Rebase(d, onto, base)
+	if err != nil {
+		return cty.NilVal, false
+	}
+	ret, err := rebased.Apply(theirs)
+	if err != nil {
+		return cty.NilVal, false
+	}
+	return ret, true
+}
+
+// ResolveLists is a ConflictResolver that resolves conflicts between lists
+// by merging them with MergeLists, if their changes do not overlap.
+var ResolveLists = ConflictResolver{
+	Name: "diff3",
+	Resolve: func(conflict, root MergeConflict) (cty.Value, bool) {
+		if conflict.Base == cty.NilVal || !hasTypeOf(conflict.Base, conflict.Ours) || !hasTypeOf(conflict.Base, conflict.Theirs) {
+			return cty.NilVal, false
+		}
+		ret, err := MergeLists(conflict.Base, conflict.Ours, conflict.Theirs)
+		if err != nil {
+			return cty.NilVal, false
+		}
+		return ret, true
+	},
+}
+
+// ResolveNewer returns a ConflictResolver that resolves conflicts by
+// choosing the side that was modified most recently, according to a
+// timestamp attribute with the given name.
+//
+// The timestamp is taken from the nearest object containing the
+// conflicting values, or the values themselves if they are objects, that
+// has the attribute on both sides. The attribute must be either a number
+// or a string in the RFC 3339 format. If the timestamps are equal, or
+// cannot be compared, then the conflict is not resolved.
+func ResolveNewer(attr string) ConflictResolver {
+	return ConflictResolver{
+		Name: "newer",
+		Resolve: func(conflict, root MergeConflict) (cty.Value, bool) {
+			for i := len(conflict.Path); i >= 0; i-- {
+				ours, oerr := applyPath(root.Ours, conflict.Path[:i])
+				theirs, terr := applyPath(root.Theirs, conflict.Path[:i])
+				if oerr != nil || terr != nil || !hasTimestamp(ours, attr) || !hasTimestamp(theirs, attr) {
+					continue
+				}
+				switch compareTimestamps(ours.GetAttr(attr), theirs.GetAttr(attr)) {
+				case 1:
+					return conflict.Ours, true
+				case -1:
+					return conflict.Theirs, true
+				}
+				return cty.NilVal, false
+			}
+			return cty.NilVal, false
+		},
+	}
+}
+
+// hasTimestamp returns true if the given value is an object with the given
+// attribute.
+func hasTimestamp(val cty.Value, attr string) bool {
+	return requireKnown(val) == nil && val.Type().IsObjectType() && val.Type().HasAttribute(attr)
+}
+
+// compareTimestamps returns 1 if a is later than b, -1 if b is later than
+// a, and 0 if they are equal or cannot be compared.
+func compareTimestamps(a, b cty.Value) int {
+	if !a.IsKnown() || !b.IsKnown() || a.IsNull() || b.IsNull() || !a.Type().Equals(b.Type()) {
+		return 0
+	}
+	switch a.Type() {
+	case cty.Number:
+		return a.AsBigFloat().Cmp(b.AsBigFloat())
+	case cty.String:
+		at, aerr := time.Parse(time.RFC3339, a.AsString())
+		bt, berr := time.Parse(time.RFC3339, b.AsString())
+		switch {
+		case aerr != nil || berr != nil:
+			return 0
+		case at.After(bt):
+			return 1
+		case bt.After(at):
+			return -1
+		}
+	}
+	return 0
+}
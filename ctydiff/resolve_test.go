@@ -0,0 +1,208 @@
+package ctydiff
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestResolveUnion(t *testing.T) {
+	strs := func(ss ...string) cty.Value {
+		vals := make([]cty.Value, len(ss))
+		for i, s := range ss {
+			vals[i] = cty.StringVal(s)
+		}
+		return cty.ListVal(vals)
+	}
+	set := func(ss ...string) cty.Value {
+		vals := make([]cty.Value, len(ss))
+		for i, s := range ss {
+			vals[i] = cty.StringVal(s)
+		}
+		return cty.SetVal(vals)
+	}
+
+	tests := []struct {
+		name   string
+		base   cty.Value
+		ours   cty.Value
+		theirs cty.Value
+		want   cty.Value
+	}{
+		{
+			"Set",
+			cty.NullVal(cty.Set(cty.String)),
+			set("a", "b"),
+			set("b", "c"),
+			set("a", "b", "c"),
+		},
+		{
+			"ListInserts",
+			strs("a", "b"),
+			strs("x", "a", "b", "y"),
+			strs("z", "a", "w", "b", "y"),
+			strs("x", "z", "a", "w", "b", "y"),
+		},
+		{
+			"ListDelete",
+			strs("a", "b"),
+			strs("a"),
+			strs("a", "b", "c"),
+			cty.NilVal,
+		},
+		{
+			"String",
+			cty.StringVal("a"),
+			cty.StringVal("b"),
+			cty.StringVal("c"),
+			cty.NilVal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conflict := MergeConflict{
+				Base:   tt.base,
+				Ours:   tt.ours,
+				Theirs: tt.theirs,
+			}
+			got, ok := ResolveUnion.Resolve(conflict, conflict)
+			if tt.want == cty.NilVal {
+				if ok {
+					t.Fatalf("Resolve() = %#v; want no resolution", got)
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("Resolve() did not resolve")
+			}
+			if !got.RawEquals(tt.want) {
+				t.Errorf("Resolve\nGot\n%#v\nWant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveNewer(t *testing.T) {
+	resource := func(name, updated cty.Value) cty.Value {
+		return cty.ObjectVal(map[string]cty.Value{
+			"config": cty.ObjectVal(map[string]cty.Value{
+				"name": name,
+			}),
+			"updated_at": updated,
+		})
+	}
+	modified := func(updated cty.Value) cty.Value {
+		return cty.ObjectVal(map[string]cty.Value{"modified": updated})
+	}
+	name := cty.GetAttrPath("config").GetAttr("name")
+
+	tests := []struct {
+		name   string
+		path   cty.Path
+		ours   cty.Value
+		theirs cty.Value
+		want   cty.Value
+	}{
+		{
+			"OursNewer",
+			name,
+			resource(cty.StringVal("b"), cty.StringVal("2019-05-02T00:00:00Z")),
+			resource(cty.StringVal("c"), cty.StringVal("2019-05-01T12:00:00+02:00")),
+			cty.StringVal("b"),
+		},
+		{
+			"TheirsNewer",
+			name,
+			resource(cty.StringVal("b"), cty.NumberIntVal(1556755200)),
+			resource(cty.StringVal("c"), cty.NumberIntVal(1556755201)),
+			cty.StringVal("c"),
+		},
+		{
+			"Timestamp",
+			cty.GetAttrPath("updated_at"),
+			resource(cty.StringVal("a"), cty.NumberIntVal(2)),
+			resource(cty.StringVal("a"), cty.NumberIntVal(1)),
+			cty.NumberIntVal(2),
+		},
+		{
+			"Equal",
+			name,
+			resource(cty.StringVal("b"), cty.NumberIntVal(1)),
+			resource(cty.StringVal("c"), cty.NumberIntVal(1)),
+			cty.NilVal,
+		},
+		{
+			"Invalid",
+			name,
+			resource(cty.StringVal("b"), cty.StringVal("yesterday")),
+			resource(cty.StringVal("c"), cty.StringVal("2019-05-01T00:00:00Z")),
+			cty.NilVal,
+		},
+		{
+			"Missing",
+			cty.GetAttrPath("modified"),
+			modified(cty.NumberIntVal(2)),
+			modified(cty.NumberIntVal(1)),
+			cty.NilVal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ours, _ := applyPath(tt.ours, tt.path)
+			theirs, _ := applyPath(tt.theirs, tt.path)
+			conflict := MergeConflict{
+				Path:   tt.path,
+				Ours:   ours,
+				Theirs: theirs,
+			}
+			root := MergeConflict{
+				Ours:   tt.ours,
+				Theirs: tt.theirs,
+			}
+			got, ok := ResolveNewer("updated_at").Resolve(conflict, root)
+			if tt.want == cty.NilVal {
+				if ok {
+					t.Fatalf("Resolve() = %#v; want no resolution", got)
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("Resolve() did not resolve")
+			}
+			if !got.RawEquals(tt.want) {
+				t.Errorf("Resolve\nGot\n%#v\nWant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveLists(t *testing.T) {
+	doc := func(ss ...string) cty.Value {
+		vals := make([]cty.Value, len(ss))
+		for i, s := range ss {
+			vals[i] = cty.StringVal(s)
+		}
+		return cty.ObjectVal(map[string]cty.Value{
+			"list": cty.ListVal(vals),
+		})
+	}
+	opts := MergeOptions{Resolvers: []ConflictResolver{ResolveLists}}
+
+	got, resolutions, err := MergeWithOptions(doc("a", "b", "c"), doc("x", "b", "c"), doc("a", "b", "y"), opts)
+	if err != nil {
+		t.Fatalf("MergeWithOptions() err = %v", err)
+	}
+	if want := doc("x", "b", "y"); !got.RawEquals(want) {
+		t.Errorf("MergeWithOptions\nGot\n%#v\nWant\n%#v", got, want)
+	}
+	if len(resolutions) != 1 || resolutions[0].Strategy != "diff3" {
+		t.Errorf("wrong resolutions %#v", resolutions)
+	}
+
+	_, _, err = MergeWithOptions(doc("a", "b", "c"), doc("x", "b", "c"), doc("y", "b", "c"), opts)
+	if _, ok := err.(*MergeError); !ok {
+		t.Errorf("MergeWithOptions() err = %#v; want *MergeError", err)
+	}
+}
@@ -0,0 +1,72 @@
+package ctydiff
+
+import (
+	"github.com/zclconf/go-cty/cty"
+)
+
+// DiffStats is a summary of the changes in a Diff, as returned by
+// Diff.Stats.
+type DiffStats struct {
+	// The number of changes of each type. Changes inside a NestedDiff are
+	// counted along with the NestedDiff itself.
+	Replace int
+	Delete  int
+	Insert  int
+	Add     int
+	Remove  int
+	Nested  int
+	Context int
+
+	// Attributes is the number of distinct top-level attributes affected by
+	// the changes in the diff, not counting Context changes. Changes that
+	// replace the entire value do not count towards this total.
+	Attributes int
+
+	// MaxDepth is the length of the longest absolute path of any change
+	// in the diff.
+	MaxDepth int
+}
+
+// Changes returns the total number of changes counted in the receiver,
+// excluding Context changes and NestedDiff changes themselves, since those
+// do not directly change anything.
+func (s DiffStats) Changes() int {
+	return s.Replace + s.Delete + s.Insert + s.Add + s.Remove
+}
+
+// Stats returns summary statistics about the receiver, including the
+// changes inside any NestedDiff changes.
+func (d Diff) Stats() DiffStats {
+	var stats DiffStats
+	attrs := make(map[string]struct{})
+	d.Walk(func(absPath cty.Path, c Change) error {
+		if len(absPath) > stats.MaxDepth {
+			stats.MaxDepth = len(absPath)
+		}
+		switch c.(type) {
+		case ReplaceChange:
+			stats.Replace++
+		case DeleteChange:
+			stats.Delete++
+		case InsertChange:
+			stats.Insert++
+		case AddChange:
+			stats.Add++
+		case RemoveChange:
+			stats.Remove++
+		case NestedDiff:
+			stats.Nested++
+		case Context:
+			stats.Context++
+			return nil
+		}
+		if len(absPath) > 0 {
+			if step, ok := absPath[0].(cty.GetAttrStep); ok {
+				attrs[step.Name] = struct{}{}
+			}
+		}
+		return nil
+	})
+	stats.Attributes = len(attrs)
+	return stats
+}
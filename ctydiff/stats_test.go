@@ -0,0 +1,77 @@
+package ctydiff
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestDiff_Stats(t *testing.T) {
+	setElem := cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("A")})
+	diff := Diff{
+		ReplaceChange{
+			Path:     cty.GetAttrPath("name"),
+			OldValue: cty.StringVal("a"),
+			NewValue: cty.StringVal("b"),
+		},
+		Context{
+			Path:      cty.GetAttrPath("list").Index(cty.NumberIntVal(0)),
+			WantValue: cty.StringVal("a"),
+		},
+		DeleteChange{
+			Path:     cty.GetAttrPath("list").Index(cty.NumberIntVal(1)),
+			OldValue: cty.StringVal("b"),
+		},
+		InsertChange{
+			Path:        cty.GetAttrPath("list").Index(cty.NumberIntVal(1)),
+			NewValue:    cty.StringVal("c"),
+			BeforeValue: cty.NullVal(cty.String),
+			ElementPath: true,
+		},
+		Context{
+			Path:      cty.GetAttrPath("other"),
+			WantValue: cty.StringVal("a"),
+		},
+		NestedDiff{
+			Path:     cty.GetAttrPath("set").Index(setElem),
+			OldValue: setElem,
+			Diff: Diff{
+				NestedDiff{
+					Path:     cty.GetAttrPath("a"),
+					OldValue: cty.SetValEmpty(cty.String),
+					Diff: Diff{
+						AddChange{
+							NewValue: cty.StringVal("x"),
+						},
+						RemoveChange{
+							OldValue: cty.StringVal("y"),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	got := diff.Stats()
+	want := DiffStats{
+		Replace:    1,
+		Delete:     1,
+		Insert:     1,
+		Add:        1,
+		Remove:     1,
+		Nested:     2,
+		Context:    2,
+		Attributes: 3,
+		MaxDepth:   3,
+	}
+	if got != want {
+		t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, want)
+	}
+	if got, want := got.Changes(), 5; got != want {
+		t.Errorf("wrong Changes() %d; want %d", got, want)
+	}
+
+	if got := Diff(nil).Stats(); got != (DiffStats{}) {
+		t.Errorf("wrong result for empty diff\ngot: %#v", got)
+	}
+}
@@ -0,0 +1,260 @@
+package ctydiff
+
+import (
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ToValue returns a representation of the receiver as a cty value, for
+// passing diffs through systems that deal only in cty values. DiffFromValue
+// converts the representation back into a Diff.
+//
+// The representation is a tuple with an object for each change. Each object
+// has a "kind" attribute that is the kind of change as a string, and a
+// "path" attribute that is a tuple of steps. Each step is an object with a
+// "type" attribute that is either "attr", along with a "name" attribute for
+// a cty.GetAttrStep, or "index", along with a "key" attribute for a
+// cty.IndexStep. The other attributes of a change, which may have any type,
+// depend on its kind:
+//
+//	kind       attributes             change type
+//	"replace"  old, new               ReplaceChange
+//	"delete"   old                    DeleteChange
+//	"insert"   new, before, element   InsertChange
+//	"add"      new                    AddChange
+//	"remove"   old                    RemoveChange
+//	"nested"   old, diff              NestedDiff, with diff in the same representation
+//	"context"  old                    Context
+//
+// An attribute for a value that a change does not check, represented by
+// cty.NilVal in the change, is omitted. The "element" attribute of an
+// insert is true if its ElementPath is true, and is otherwise omitted. Nil
+// changes are skipped.
+//
+// Since the representation is a tuple of objects whose types depend on the
+// values in the diff, serializations of it must retain the types of the
+// values, such as ctyjson.Marshal with cty.DynamicPseudoType as the type.
+func (d Diff) ToValue() cty.Value {
+	var vals []cty.Value
+	for _, c := range d {
+		if c == nil {
+			continue
+		}
+		vals = append(vals, changeToValue(c))
+	}
+	if len(vals) == 0 {
+		return cty.EmptyTupleVal
+	}
+	return cty.TupleVal(vals)
+}
+
+// DiffFromValue converts the representation of a diff returned by
+// Diff.ToValue back into a Diff. It also accepts lists in place of tuples
+// and maps in place of objects, since a value that has been through a type
+// conversion may have those types instead.
+//
+// The paths in any returned errors are relative to the given value.
+func DiffFromValue(val cty.Value) (Diff, error) {
+	return diffFromValue(val, cty.Path{})
+}
+
+func diffFromValue(val cty.Value, path cty.Path) (Diff, error) {
+	if err := requireSequence(val, path, "diff"); err != nil {
+		return nil, err
+	}
+	var ret Diff
+	for it := val.ElementIterator(); it.Next(); {
+		k, v := it.Element()
+		c, err := changeFromValue(v, path.Index(k))
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, c)
+	}
+	return ret, nil
+}
+
+func changeToValue(c Change) cty.Value {
+	attrs := make(map[string]cty.Value)
+	set := func(name string, v cty.Value) {
+		if v != cty.NilVal {
+			attrs[name] = v
+		}
+	}
+	switch c := c.(type) {
+	case ReplaceChange:
+		set("kind", cty.StringVal("replace"))
+		set("path", pathToValue(c.Path))
+		set("old", c.OldValue)
+		set("new", c.NewValue)
+	case DeleteChange:
+		set("kind", cty.StringVal("delete"))
+		set("path", pathToValue(c.Path))
+		set("old", c.OldValue)
+	case InsertChange:
+		set("kind", cty.StringVal("insert"))
+		set("path", pathToValue(c.Path))
+		set("new", c.NewValue)
+		set("before", c.BeforeValue)
+		if c.ElementPath {
+			set("element", cty.True)
+		}
+	case AddChange:
+		set("kind", cty.StringVal("add"))
+		set("path", pathToValue(c.Path))
+		set("new", c.NewValue)
+	case RemoveChange:
+		set("kind", cty.StringVal("remove"))
+		set("path", pathToValue(c.Path))
+		set("old", c.OldValue)
+	case NestedDiff:
+		set("kind", cty.StringVal("nested"))
+		set("path", pathToValue(c.Path))
+		set("old", c.OldValue)
+		set("diff", c.Diff.ToValue())
+	case Context:
+		set("kind", cty.StringVal("context"))
+		set("path", pathToValue(c.Path))
+		set("old", c.WantValue)
+	default:
+		// Should never happen, since the above covers all change types.
+		panic("unsupported change type")
+	}
+	return cty.ObjectVal(attrs)
+}
+
+func pathToValue(path cty.Path) cty.Value {
+	if len(path) == 0 {
+		return cty.EmptyTupleVal
+	}
+	vals := make([]cty.Value, len(path))
+	for i, step := range path {
+		switch step := step.(type) {
+		case cty.GetAttrStep:
+			vals[i] = cty.ObjectVal(map[string]cty.Value{
+				"type": cty.StringVal("attr"),
+				"name": cty.StringVal(step.Name),
+			})
+		case cty.IndexStep:
+			vals[i] = cty.ObjectVal(map[string]cty.Value{
+				"type": cty.StringVal("index"),
+				"key":  step.Key,
+			})
+		default:
+			// Should never happen, since the above covers all step types.
+			panic("unsupported path step type")
+		}
+	}
+	return cty.TupleVal(vals)
+}
+
+func changeFromValue(val cty.Value, path cty.Path) (Change, error) {
+	if !val.IsKnown() || val.IsNull() || !(val.Type().IsObjectType() || val.Type().IsMapType()) {
+		return nil, path.NewErrorf("change must be an object")
+	}
+	kindVal := valueAttr(val, "kind")
+	if kindVal == cty.NilVal || !kindVal.IsKnown() || kindVal.IsNull() || !kindVal.Type().Equals(cty.String) {
+		return nil, path.GetAttr("kind").NewErrorf("change kind must be a string")
+	}
+	changePath, err := pathFromValue(valueAttr(val, "path"), path.GetAttr("path"))
+	if err != nil {
+		return nil, err
+	}
+	old := valueAttr(val, "old")
+	new := valueAttr(val, "new")
+
+	switch kind := kindVal.AsString(); kind {
+	case "replace":
+		return ReplaceChange{Path: changePath, OldValue: old, NewValue: new}, nil
+	case "delete":
+		return DeleteChange{Path: changePath, OldValue: old}, nil
+	case "insert":
+		before := valueAttr(val, "before")
+		elementPath := valueAttr(val, "element")
+		if elementPath != cty.NilVal && !(elementPath.IsKnown() && !elementPath.IsNull() && elementPath.Type().Equals(cty.Bool)) {
+			return nil, path.GetAttr("element").NewErrorf("insert element flag must be a bool")
+		}
+		return InsertChange{
+			Path:        changePath,
+			NewValue:    new,
+			BeforeValue: before,
+			ElementPath: elementPath != cty.NilVal && elementPath.True(),
+		}, nil
+	case "add":
+		return AddChange{Path: changePath, NewValue: new}, nil
+	case "remove":
+		return RemoveChange{Path: changePath, OldValue: old}, nil
+	case "nested":
+		diffVal := valueAttr(val, "diff")
+		if diffVal == cty.NilVal {
+			return nil, path.NewErrorf("nested change must have a diff")
+		}
+		d, err := diffFromValue(diffVal, path.GetAttr("diff"))
+		if err != nil {
+			return nil, err
+		}
+		return NestedDiff{Path: changePath, OldValue: old, Diff: d}, nil
+	case "context":
+		return Context{Path: changePath, WantValue: old}, nil
+	default:
+		return nil, path.GetAttr("kind").NewErrorf("unsupported change kind %q", kind)
+	}
+}
+
+func pathFromValue(val cty.Value, path cty.Path) (cty.Path, error) {
+	if val == cty.NilVal {
+		return nil, path.NewErrorf("change must have a path")
+	}
+	if err := requireSequence(val, path, "path"); err != nil {
+		return nil, err
+	}
+	ret := make(cty.Path, 0, val.LengthInt())
+	for it := val.ElementIterator(); it.Next(); {
+		k, v := it.Element()
+		stepPath := path.Index(k)
+		if !v.IsKnown() || v.IsNull() || !(v.Type().IsObjectType() || v.Type().IsMapType()) {
+			return nil, stepPath.NewErrorf("path step must be an object")
+		}
+		typeVal := valueAttr(v, "type")
+		if typeVal == cty.NilVal || !typeVal.IsKnown() || typeVal.IsNull() || !typeVal.Type().Equals(cty.String) {
+			return nil, stepPath.GetAttr("type").NewErrorf("path step type must be a string")
+		}
+		switch typ := typeVal.AsString(); typ {
+		case "attr":
+			name := valueAttr(v, "name")
+			if name == cty.NilVal || !name.IsKnown() || name.IsNull() || !name.Type().Equals(cty.String) {
+				return nil, stepPath.GetAttr("name").NewErrorf("attribute name must be a string")
+			}
+			ret = append(ret, cty.GetAttrStep{Name: name.AsString()})
+		case "index":
+			key := valueAttr(v, "key")
+			if key == cty.NilVal || !key.IsKnown() || key.IsNull() {
+				return nil, stepPath.GetAttr("key").NewErrorf("index key must not be null")
+			}
+			ret = append(ret, cty.IndexStep{Key: key})
+		default:
+			return nil, stepPath.GetAttr("type").NewErrorf("unsupported path step type %q", typ)
+		}
+	}
+	return ret, nil
+}
+
+// valueAttr returns the value of the given attribute of an object, or
+// element of a map, or cty.NilVal if there is no such attribute or element.
+func valueAttr(val cty.Value, name string) cty.Value {
+	ty := val.Type()
+	switch {
+	case ty.IsObjectType() && ty.HasAttribute(name):
+		return val.GetAttr(name)
+	case ty.IsMapType() && val.HasIndex(cty.StringVal(name)).True():
+		return val.Index(cty.StringVal(name))
+	}
+	return cty.NilVal
+}
+
+func requireSequence(val cty.Value, path cty.Path, what string) error {
+	ty := val.Type()
+	if !val.IsKnown() || val.IsNull() || !(ty.IsTupleType() || ty.IsListType()) {
+		return path.NewErrorf("%s must be a tuple or list", what)
+	}
+	return nil
+}
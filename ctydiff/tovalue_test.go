@@ -0,0 +1,303 @@
+package ctydiff
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+func TestDiff_ToValue(t *testing.T) {
+	d := Diff{
+		InsertChange{
+			Path:        cty.GetAttrPath("list").Index(cty.NumberIntVal(0)),
+			NewValue:    cty.StringVal("a"),
+			BeforeValue: cty.NilVal,
+			ElementPath: true,
+		},
+		nil,
+		NestedDiff{
+			Path:     cty.IndexPath(cty.StringVal("k")),
+			OldValue: cty.EmptyObjectVal,
+			Diff:     Diff{},
+		},
+	}
+	want := cty.TupleVal([]cty.Value{
+		cty.ObjectVal(map[string]cty.Value{
+			"kind": cty.StringVal("insert"),
+			"path": cty.TupleVal([]cty.Value{
+				cty.ObjectVal(map[string]cty.Value{
+					"type": cty.StringVal("attr"),
+					"name": cty.StringVal("list"),
+				}),
+				cty.ObjectVal(map[string]cty.Value{
+					"type": cty.StringVal("index"),
+					"key":  cty.NumberIntVal(0),
+				}),
+			}),
+			"new":     cty.StringVal("a"),
+			"element": cty.True,
+		}),
+		cty.ObjectVal(map[string]cty.Value{
+			"kind": cty.StringVal("nested"),
+			"path": cty.TupleVal([]cty.Value{
+				cty.ObjectVal(map[string]cty.Value{
+					"type": cty.StringVal("index"),
+					"key":  cty.StringVal("k"),
+				}),
+			}),
+			"old":  cty.EmptyObjectVal,
+			"diff": cty.EmptyTupleVal,
+		}),
+	})
+
+	got := d.ToValue()
+	if !got.RawEquals(want) {
+		t.Errorf("wrong value\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestDiff_ToValue_json(t *testing.T) {
+	d := Diff{
+		ReplaceChange{
+			Path:     cty.GetAttrPath("tags").Index(cty.StringVal("env")),
+			OldValue: cty.NullVal(cty.String),
+			NewValue: cty.StringVal("prod"),
+		},
+		RemoveChange{
+			Path:     cty.GetAttrPath("set"),
+			OldValue: cty.ListVal([]cty.Value{cty.True}),
+		},
+	}
+
+	raw, err := ctyjson.Marshal(d.ToValue(), cty.DynamicPseudoType)
+	if err != nil {
+		t.Fatalf("Marshal() err = %v", err)
+	}
+	val, err := ctyjson.Unmarshal(raw, cty.DynamicPseudoType)
+	if err != nil {
+		t.Fatalf("Unmarshal() err = %v", err)
+	}
+	got, err := DiffFromValue(val)
+	if err != nil {
+		t.Fatalf("DiffFromValue() err = %v", err)
+	}
+	if !reflect.DeepEqual(got, d) {
+		t.Errorf("wrong diff\n%s", prettyDiff.Compare(d, got))
+	}
+}
+
+func TestDiff_ToValue_roundTrip(t *testing.T) {
+	d := Diff{
+		ReplaceChange{
+			Path:     cty.GetAttrPath("a").Index(cty.StringVal("k")),
+			OldValue: cty.NullVal(cty.String),
+			NewValue: cty.StringVal("x"),
+		},
+		ReplaceChange{
+			Path:     cty.GetAttrPath("b"),
+			OldValue: cty.NilVal,
+			NewValue: cty.True,
+		},
+		DeleteChange{
+			Path:     cty.GetAttrPath("c").Index(cty.NumberIntVal(0)),
+			OldValue: cty.StringVal("y"),
+		},
+		InsertChange{
+			Path:        cty.GetAttrPath("c").Index(cty.NumberIntVal(1)),
+			NewValue:    cty.StringVal("z"),
+			BeforeValue: cty.NilVal,
+			ElementPath: true,
+		},
+		InsertChange{
+			Path:        cty.GetAttrPath("c"),
+			NewValue:    cty.StringVal("w"),
+			BeforeValue: cty.StringVal("z"),
+		},
+		AddChange{
+			Path:     cty.GetAttrPath("d"),
+			NewValue: cty.NumberIntVal(1),
+		},
+		RemoveChange{
+			Path:     cty.GetAttrPath("d"),
+			OldValue: cty.NumberIntVal(2),
+		},
+		NestedDiff{
+			Path:     cty.GetAttrPath("e").Index(cty.ObjectVal(map[string]cty.Value{"id": cty.StringVal("a")})),
+			OldValue: cty.ObjectVal(map[string]cty.Value{"id": cty.StringVal("a")}),
+			Diff: Diff{
+				ReplaceChange{
+					Path:     cty.GetAttrPath("id"),
+					OldValue: cty.StringVal("a"),
+					NewValue: cty.StringVal("b"),
+				},
+			},
+		},
+		Context{
+			Path:      cty.GetAttrPath("f"),
+			WantValue: cty.ListValEmpty(cty.String),
+		},
+	}
+
+	got, err := DiffFromValue(d.ToValue())
+	if err != nil {
+		t.Fatalf("DiffFromValue() err = %v", err)
+	}
+	if !reflect.DeepEqual(got, d) {
+		t.Errorf("wrong diff\n%s", prettyDiff.Compare(d, got))
+	}
+}
+
+func TestDiffFromValue_conversions(t *testing.T) {
+	// The path steps here are maps, in a list, as they would be after
+	// conversion to the type list(map(string)).
+	val := cty.TupleVal([]cty.Value{
+		cty.ObjectVal(map[string]cty.Value{
+			"kind": cty.StringVal("delete"),
+			"path": cty.ListVal([]cty.Value{
+				cty.MapVal(map[string]cty.Value{
+					"type": cty.StringVal("attr"),
+					"name": cty.StringVal("tags"),
+				}),
+				cty.MapVal(map[string]cty.Value{
+					"type": cty.StringVal("index"),
+					"key":  cty.StringVal("env"),
+				}),
+			}),
+		}),
+	})
+	want := Diff{
+		DeleteChange{
+			Path: cty.GetAttrPath("tags").Index(cty.StringVal("env")),
+		},
+	}
+
+	got, err := DiffFromValue(val)
+	if err != nil {
+		t.Fatalf("DiffFromValue() err = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong diff\n%s", prettyDiff.Compare(want, got))
+	}
+}
+
+func TestDiffFromValue_errors(t *testing.T) {
+	change := func(attrs map[string]cty.Value) cty.Value {
+		return cty.TupleVal([]cty.Value{cty.ObjectVal(attrs)})
+	}
+	step := func(attrs map[string]cty.Value) cty.Value {
+		return change(map[string]cty.Value{
+			"kind": cty.StringVal("delete"),
+			"path": cty.TupleVal([]cty.Value{cty.ObjectVal(attrs)}),
+		})
+	}
+
+	tests := []struct {
+		name string
+		val  cty.Value
+		want string
+	}{
+		{
+			"NotSequence",
+			cty.NullVal(cty.DynamicPseudoType),
+			"diff must be a tuple or list",
+		},
+		{
+			"NotObject",
+			cty.TupleVal([]cty.Value{cty.StringVal("replace")}),
+			"change must be an object",
+		},
+		{
+			"NoKind",
+			change(map[string]cty.Value{"path": cty.EmptyTupleVal}),
+			"change kind must be a string",
+		},
+		{
+			"BadKind",
+			change(map[string]cty.Value{
+				"kind": cty.StringVal("frob"),
+				"path": cty.EmptyTupleVal,
+			}),
+			`unsupported change kind "frob"`,
+		},
+		{
+			"BadElementFlag",
+			change(map[string]cty.Value{
+				"kind":    cty.StringVal("insert"),
+				"path":    cty.EmptyTupleVal,
+				"new":     cty.StringVal("a"),
+				"element": cty.StringVal("yes"),
+			}),
+			"insert element flag must be a bool",
+		},
+		{
+			"NoPath",
+			change(map[string]cty.Value{"kind": cty.StringVal("delete")}),
+			"change must have a path",
+		},
+		{
+			"BadPath",
+			change(map[string]cty.Value{
+				"kind": cty.StringVal("delete"),
+				"path": cty.StringVal(".a"),
+			}),
+			"path must be a tuple or list",
+		},
+		{
+			"NoDiff",
+			change(map[string]cty.Value{
+				"kind": cty.StringVal("nested"),
+				"path": cty.EmptyTupleVal,
+			}),
+			"nested change must have a diff",
+		},
+		{
+			"BadNestedDiff",
+			change(map[string]cty.Value{
+				"kind": cty.StringVal("nested"),
+				"path": cty.EmptyTupleVal,
+				"diff": cty.TupleVal([]cty.Value{cty.True}),
+			}),
+			"change must be an object",
+		},
+		{
+			"BadStep",
+			change(map[string]cty.Value{
+				"kind": cty.StringVal("delete"),
+				"path": cty.TupleVal([]cty.Value{cty.StringVal("a")}),
+			}),
+			"path step must be an object",
+		},
+		{
+			"BadStepType",
+			step(map[string]cty.Value{"type": cty.StringVal("splat")}),
+			`unsupported path step type "splat"`,
+		},
+		{
+			"NoStepName",
+			step(map[string]cty.Value{"type": cty.StringVal("attr")}),
+			"attribute name must be a string",
+		},
+		{
+			"NullStepKey",
+			step(map[string]cty.Value{
+				"type": cty.StringVal("index"),
+				"key":  cty.NullVal(cty.String),
+			}),
+			"index key must not be null",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DiffFromValue(tt.val)
+			if err == nil {
+				t.Fatal("DiffFromValue() succeeded; want error")
+			}
+			if got := err.Error(); got != tt.want {
+				t.Errorf("wrong error\ngot:  %s\nwant: %s", got, tt.want)
+			}
+		})
+	}
+}
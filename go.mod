@@ -1,6 +1,22 @@
 module github.com/zclconf/go-cty-diff
 
+go 1.27.1
+
 require (
 	github.com/kylelemons/godebug v0.0.0-20170820004349-d65d576e9348
 	github.com/zclconf/go-cty v0.0.0-20190516203816-4fecf87372ec
 )
+
+require (
+	github.com/apparentlymart/go-textseg v1.0.0 // indirect
+	github.com/golang/protobuf v1.1.0 // indirect
+	github.com/kr/pretty v0.1.0 // indirect
+	github.com/kr/pty v1.1.1 // indirect
+	github.com/kr/text v0.1.0 // indirect
+	github.com/vmihailenco/msgpack v3.3.3+incompatible // indirect
+	golang.org/x/net v0.0.0-20180811021610-c39426892332 // indirect
+	golang.org/x/sync v0.0.0-20180314180146-1d60e4601c6f // indirect
+	golang.org/x/text v0.3.0 // indirect
+	google.golang.org/appengine v1.1.0 // indirect
+	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
+)